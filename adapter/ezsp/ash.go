@@ -0,0 +1,191 @@
+package ezsp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+const (
+	ashFlag       = 0x7e
+	ashEscape     = 0x7d
+	ashXon        = 0x11
+	ashXoff       = 0x13
+	ashSubstitute = 0x18
+	ashCancel     = 0x1a
+	ashFlipBit    = 0x20
+)
+
+var ErrAshCrc = errors.New("ash frame crc mismatch")
+
+type AshFrameType uint8
+
+const (
+	AshFrameTypeData AshFrameType = iota
+	AshFrameTypeAck
+	AshFrameTypeNak
+	AshFrameTypeRst
+	AshFrameTypeRstAck
+	AshFrameTypeError
+	AshFrameTypeUnknown
+)
+
+// AshFrame is a single ASH frame. Data holds the de-randomized payload of DATA frames.
+type AshFrame struct {
+	Control uint8
+	Data    []uint8
+}
+
+func (f *AshFrame) Type() AshFrameType {
+	switch {
+	case f.Control&0x80 == 0x00:
+		return AshFrameTypeData
+	case f.Control&0xe0 == 0x80:
+		return AshFrameTypeAck
+	case f.Control&0xe0 == 0xa0:
+		return AshFrameTypeNak
+	case f.Control == 0xc0:
+		return AshFrameTypeRst
+	case f.Control == 0xc1:
+		return AshFrameTypeRstAck
+	case f.Control == 0xc2:
+		return AshFrameTypeError
+	}
+	return AshFrameTypeUnknown
+}
+
+func (f *AshFrame) FrameNumber() uint8 {
+	return (f.Control >> 4) & 0x07
+}
+
+func (f *AshFrame) Retransmitted() bool {
+	return f.Control&0x08 != 0
+}
+
+func (f *AshFrame) AckNumber() uint8 {
+	return f.Control & 0x07
+}
+
+func NewAshDataFrame(frameNumber uint8, ackNumber uint8, data []uint8) *AshFrame {
+	return &AshFrame{Control: (frameNumber&0x07)<<4 | ackNumber&0x07, Data: data}
+}
+
+func EncodeAshFrame(f *AshFrame) []uint8 {
+	raw := make([]uint8, 0, len(f.Data)+3)
+	raw = append(raw, f.Control)
+	if f.Type() == AshFrameTypeData {
+		raw = append(raw, randomize(f.Data)...)
+	} else {
+		raw = append(raw, f.Data...)
+	}
+	crc := crc16(raw)
+	raw = append(raw, uint8(crc>>8), uint8(crc))
+
+	stuffed := make([]uint8, 0, len(raw)*2+1)
+	for _, b := range raw {
+		switch b {
+		case ashFlag, ashEscape, ashXon, ashXoff, ashSubstitute, ashCancel:
+			stuffed = append(stuffed, ashEscape, b^ashFlipBit)
+		default:
+			stuffed = append(stuffed, b)
+		}
+	}
+	return append(stuffed, ashFlag)
+}
+
+type AshReader struct {
+	r *bufio.Reader
+}
+
+func NewAshReader(r io.Reader) *AshReader {
+	return &AshReader{bufio.NewReader(r)}
+}
+
+// ReadFrame returns the next complete frame from the stream. Frames with a bad CRC are reported
+// with ErrAshCrc and reading may continue with the following frame.
+func (a *AshReader) ReadFrame() (*AshFrame, error) {
+	for {
+		raw, err := a.readRaw()
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) < 3 {
+			continue
+		}
+		body := raw[:len(raw)-2]
+		if crc16(body) != uint16(raw[len(raw)-2])<<8|uint16(raw[len(raw)-1]) {
+			return nil, ErrAshCrc
+		}
+		f := &AshFrame{Control: body[0]}
+		if f.Type() == AshFrameTypeData {
+			f.Data = randomize(body[1:])
+		} else {
+			f.Data = append([]uint8{}, body[1:]...)
+		}
+		return f, nil
+	}
+}
+
+func (a *AshReader) readRaw() ([]uint8, error) {
+	var raw []uint8
+	escaped, discard := false, false
+	for {
+		b, err := a.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(raw) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch b {
+		case ashFlag:
+			if discard {
+				raw, escaped, discard = nil, false, false
+				continue
+			}
+			return raw, nil
+		case ashCancel:
+			raw, escaped, discard = nil, false, false
+		case ashSubstitute:
+			discard = true
+		case ashXon, ashXoff:
+		case ashEscape:
+			escaped = true
+		default:
+			if escaped {
+				b ^= ashFlipBit
+				escaped = false
+			}
+			raw = append(raw, b)
+		}
+	}
+}
+
+func randomize(data []uint8) []uint8 {
+	out := make([]uint8, len(data))
+	rand := uint8(0x42)
+	for i, b := range data {
+		out[i] = b ^ rand
+		if rand&0x01 == 0 {
+			rand = rand >> 1
+		} else {
+			rand = (rand >> 1) ^ 0xb8
+		}
+	}
+	return out
+}
+
+func crc16(data []uint8) uint16 {
+	crc := uint16(0xffff)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,315 @@
+package ezsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/dyrkin/bin"
+	"github.com/dyrkin/zcl-go"
+)
+
+const (
+	FrameIdSendUnicast            uint16 = 0x0034
+	FrameIdSendBroadcast          uint16 = 0x0036
+	FrameIdSendMulticast          uint16 = 0x0038
+	FrameIdIncomingMessageHandler uint16 = 0x0045
+)
+
+const frameControlResponse = 0x0080
+
+const (
+	defaultApsOptions = 0x0140 // EMBER_APS_OPTION_RETRY | EMBER_APS_OPTION_ENABLE_ROUTE_DISCOVERY
+	defaultRadius     = 0x1e
+)
+
+type IncomingMessageType uint8
+
+const (
+	IncomingUnicast IncomingMessageType = iota
+	IncomingUnicastReply
+	IncomingMulticast
+	IncomingMulticastLoopback
+	IncomingBroadcast
+	IncomingBroadcastLoopback
+	IncomingManyToOneRouteRequest
+)
+
+type OutgoingMessageType uint8
+
+const (
+	OutgoingDirect OutgoingMessageType = iota
+	OutgoingViaAddressTable
+	OutgoingViaBinding
+	OutgoingMulticast
+	OutgoingBroadcast
+)
+
+// Frame is an EZSP frame. Version 8 and later use two byte frame control and frame id fields.
+type Frame struct {
+	Sequence     uint8
+	FrameControl uint16
+	FrameId      uint16
+	Parameters   []uint8
+}
+
+func (f *Frame) IsResponse() bool {
+	return f.FrameControl&frameControlResponse != 0
+}
+
+func DecodeFrame(data []uint8, extended bool) (*Frame, error) {
+	f := &Frame{}
+	if extended {
+		if len(data) < 5 {
+			return nil, errors.New("ezsp frame too short")
+		}
+		f.Sequence = data[0]
+		f.FrameControl = uint16(data[1]) | uint16(data[2])<<8
+		f.FrameId = uint16(data[3]) | uint16(data[4])<<8
+		f.Parameters = data[5:]
+		return f, nil
+	}
+	if len(data) < 3 {
+		return nil, errors.New("ezsp frame too short")
+	}
+	f.Sequence = data[0]
+	f.FrameControl = uint16(data[1])
+	f.FrameId = uint16(data[2])
+	f.Parameters = data[3:]
+	return f, nil
+}
+
+func EncodeFrame(f *Frame, extended bool) []uint8 {
+	if extended {
+		return append([]uint8{f.Sequence, uint8(f.FrameControl), uint8(f.FrameControl >> 8),
+			uint8(f.FrameId), uint8(f.FrameId >> 8)}, f.Parameters...)
+	}
+	return append([]uint8{f.Sequence, uint8(f.FrameControl), uint8(f.FrameId)}, f.Parameters...)
+}
+
+type ApsFrame struct {
+	ProfileId           uint16
+	ClusterId           uint16
+	SourceEndpoint      uint8
+	DestinationEndpoint uint8
+	Options             uint16
+	GroupId             uint16
+	Sequence            uint8
+}
+
+type IncomingMessageHandler struct {
+	Type            IncomingMessageType
+	ApsFrame        *ApsFrame
+	LastHopLqi      uint8
+	LastHopRssi     uint8
+	Sender          uint16
+	BindingIndex    uint8
+	AddressIndex    uint8
+	MessageContents []uint8 `size:"1"`
+}
+
+type SendUnicast struct {
+	Type               OutgoingMessageType
+	IndexOrDestination uint16
+	ApsFrame           *ApsFrame
+	MessageTag         uint8
+	MessageContents    []uint8 `size:"1"`
+}
+
+type SendMulticast struct {
+	ApsFrame        *ApsFrame
+	Hops            uint8
+	NonmemberRadius uint8
+	MessageTag      uint8
+	MessageContents []uint8 `size:"1"`
+}
+
+type SendBroadcast struct {
+	Destination     uint16
+	ApsFrame        *ApsFrame
+	Radius          uint8
+	MessageTag      uint8
+	MessageContents []uint8 `size:"1"`
+}
+
+type SendResponse struct {
+	Status   uint8
+	Sequence uint8
+}
+
+// ToApplicationMessage maps incomingMessageHandler callbacks and sendUnicast, sendMulticast and
+// sendBroadcast commands to application messages. Other frames yield nil.
+func ToApplicationMessage(f *Frame) *zcl.ApplicationMessage {
+	switch {
+	case f.FrameId == FrameIdIncomingMessageHandler && f.IsResponse():
+		h := &IncomingMessageHandler{}
+		bin.Decode(f.Parameters, h)
+		return incomingToApplicationMessage(h)
+	case f.FrameId == FrameIdSendUnicast && !f.IsResponse():
+		s := &SendUnicast{}
+		bin.Decode(f.Parameters, s)
+		am := outgoingToApplicationMessage(s.ApsFrame, s.MessageContents)
+		am.DstAddrMode = zcl.AddressModeNwk
+		am.DstAddr = nwkAddr(s.IndexOrDestination)
+		return am
+	case f.FrameId == FrameIdSendMulticast && !f.IsResponse():
+		s := &SendMulticast{}
+		bin.Decode(f.Parameters, s)
+		am := outgoingToApplicationMessage(s.ApsFrame, s.MessageContents)
+		am.DstAddrMode = zcl.AddressModeGroup
+		return am
+	case f.FrameId == FrameIdSendBroadcast && !f.IsResponse():
+		s := &SendBroadcast{}
+		bin.Decode(f.Parameters, s)
+		am := outgoingToApplicationMessage(s.ApsFrame, s.MessageContents)
+		am.DstAddrMode = zcl.AddressModeBroadcast
+		am.DstAddr = nwkAddr(s.Destination)
+		return am
+	}
+	return nil
+}
+
+func incomingToApplicationMessage(h *IncomingMessageHandler) *zcl.ApplicationMessage {
+	am := &zcl.ApplicationMessage{}
+	am.SrcAddrMode = zcl.AddressModeNwk
+	am.SrcAddr = nwkAddr(h.Sender)
+	switch h.Type {
+	case IncomingMulticast, IncomingMulticastLoopback:
+		am.DstAddrMode = zcl.AddressModeGroup
+		am.WasBroadcast = true
+	case IncomingBroadcast, IncomingBroadcastLoopback:
+		am.DstAddrMode = zcl.AddressModeBroadcast
+		am.WasBroadcast = true
+	default:
+		am.DstAddrMode = zcl.AddressModeNwk
+	}
+	am.GroupID = h.ApsFrame.GroupId
+	am.SrcEndpoint = h.ApsFrame.SourceEndpoint
+	am.DstEndpoint = h.ApsFrame.DestinationEndpoint
+	am.ClusterID = h.ApsFrame.ClusterId
+	am.ProfileID = h.ApsFrame.ProfileId
+	am.LinkQuality = h.LastHopLqi
+	am.Rssi = int8(h.LastHopRssi)
+	am.TransactionSeqNumber = h.ApsFrame.Sequence
+	am.Data = h.MessageContents
+	return am
+}
+
+func outgoingToApplicationMessage(aps *ApsFrame, contents []uint8) *zcl.ApplicationMessage {
+	am := &zcl.ApplicationMessage{}
+	am.GroupID = aps.GroupId
+	am.SrcEndpoint = aps.SourceEndpoint
+	am.DstEndpoint = aps.DestinationEndpoint
+	am.ClusterID = aps.ClusterId
+	am.ProfileID = aps.ProfileId
+	am.TransactionSeqNumber = aps.Sequence
+	am.Data = contents
+	return am
+}
+
+// FromApplicationMessage builds the sendUnicast, sendMulticast or sendBroadcast command for the message.
+func FromApplicationMessage(m *zcl.ApplicationMessage) (uint16, []uint8, error) {
+	aps := &ApsFrame{
+		ProfileId:           m.ProfileID,
+		ClusterId:           m.ClusterID,
+		SourceEndpoint:      m.SrcEndpoint,
+		DestinationEndpoint: m.DstEndpoint,
+		Options:             defaultApsOptions,
+		GroupId:             m.GroupID,
+		Sequence:            m.TransactionSeqNumber,
+	}
+	switch m.DstAddrMode {
+	case zcl.AddressModeNwk:
+		destination, err := parseNwkAddr(m.DstAddr)
+		if err != nil {
+			return 0, nil, err
+		}
+		return FrameIdSendUnicast, bin.Encode(&SendUnicast{OutgoingDirect, destination, aps, m.TransactionSeqNumber, m.Data}), nil
+	case zcl.AddressModeGroup:
+		return FrameIdSendMulticast, bin.Encode(&SendMulticast{aps, 0, 7, m.TransactionSeqNumber, m.Data}), nil
+	case zcl.AddressModeBroadcast:
+		destination, err := parseNwkAddr(m.DstAddr)
+		if err != nil {
+			return 0, nil, err
+		}
+		return FrameIdSendBroadcast, bin.Encode(&SendBroadcast{destination, aps, defaultRadius, m.TransactionSeqNumber, m.Data}), nil
+	}
+	return 0, nil, fmt.Errorf("unsupported address mode %d", m.DstAddrMode)
+}
+
+// Sender executes an EZSP command on the NCP and returns the parameters of its response.
+type Sender interface {
+	Command(ctx context.Context, frameId uint16, parameters []uint8) ([]uint8, error)
+}
+
+type Transport struct {
+	sender Sender
+}
+
+func NewTransport(sender Sender) *Transport {
+	return &Transport{sender}
+}
+
+func (t *Transport) Send(ctx context.Context, m *zcl.ApplicationMessage) error {
+	frameId, parameters, err := FromApplicationMessage(m)
+	if err != nil {
+		return err
+	}
+	rsp, err := t.sender.Command(ctx, frameId, parameters)
+	if err != nil {
+		return err
+	}
+	if len(rsp) == 0 {
+		return errors.New("empty ezsp response")
+	}
+	if rsp[0] != 0 {
+		return fmt.Errorf("ezsp send failed with status 0x%02x", rsp[0])
+	}
+	return nil
+}
+
+// Reader walks a recorded or live ASH byte stream and yields the application messages it carries.
+type Reader struct {
+	ash      *AshReader
+	extended bool
+}
+
+func NewReader(r io.Reader, extended bool) *Reader {
+	return &Reader{NewAshReader(r), extended}
+}
+
+func (r *Reader) Next() (*zcl.ApplicationMessage, error) {
+	for {
+		f, err := r.ash.ReadFrame()
+		if err == ErrAshCrc {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if f.Type() != AshFrameTypeData {
+			continue
+		}
+		ef, err := DecodeFrame(f.Data, r.extended)
+		if err != nil {
+			continue
+		}
+		if am := ToApplicationMessage(ef); am != nil {
+			return am, nil
+		}
+	}
+}
+
+func nwkAddr(addr uint16) string {
+	return fmt.Sprintf("0x%04x", addr)
+}
+
+func parseNwkAddr(addr string) (uint16, error) {
+	if len(addr) < 3 || addr[:2] != "0x" {
+		return 0, fmt.Errorf("invalid network address %q", addr)
+	}
+	v, err := strconv.ParseUint(addr[2:], 16, 16)
+	return uint16(v), err
+}
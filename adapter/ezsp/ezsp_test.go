@@ -0,0 +1,121 @@
+package ezsp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	. "gopkg.in/check.v1"
+)
+
+func TestEzsp(t *testing.T) { TestingT(t) }
+
+type EzspSuite struct{}
+
+var _ = Suite(&EzspSuite{})
+
+// Host/NCP exchange in EZSP v7 framing: reset handshake, an OnOff attribute report from
+// 0x1234 and a ReadAttributes request sent back to it.
+var recorded = []uint8{
+	0x1a, 0xc0, 0x38, 0xbc, 0x7e, // RST
+	0xc1, 0x02, 0x02, 0x9b, 0x7b, 0x7e, // RSTACK
+	0x21, 0x50, 0xb1, 0xed, 0x54, 0x2e, 0x14, 0xb4, 0x59, 0x95, 0x4b, 0x65, 0xab, 0x55, 0x92, 0x7a, 0x63,
+	0x96, 0x7d, 0x33, 0xb9, 0x12, 0x31, 0x60, 0x93, 0xf8, 0xcc, 0x63, 0x89, 0xec, 0x7f, 0x97, 0x5a, 0x7e, // incomingMessageHandler
+	0x83, 0x40, 0x1b, 0x7e, // ACK
+	0x7d, 0x33, 0x51, 0x21, 0x9c, 0x54, 0x1e, 0x07, 0xb6, 0x58, 0x92, 0x4a, 0x24, 0xab, 0x15, 0x93, 0x49,
+	0x9c, 0x49, 0x20, 0xae, 0xed, 0xc9, 0x67, 0x8b, 0xfd, 0xf5, 0x87, 0x7e, // sendUnicast
+}
+
+func (s *EzspSuite) TestAshSpecVectors(c *C) {
+	c.Assert(EncodeAshFrame(NewAshDataFrame(0, 0, []uint8{0x00, 0x00, 0x00, 0x04})), DeepEquals,
+		[]uint8{0x00, 0x42, 0x21, 0xa8, 0x50, 0xed, 0x2c, 0x7e})
+	c.Assert(EncodeAshFrame(&AshFrame{Control: 0xc0}), DeepEquals, []uint8{0xc0, 0x38, 0xbc, 0x7e})
+
+	r := NewAshReader(bytes.NewReader([]uint8{0x1a, 0xc0, 0x38, 0xbc, 0x7e, 0x00, 0x42, 0x21, 0xa8, 0x50, 0xed, 0x2c, 0x7e}))
+	f, err := r.ReadFrame()
+	c.Assert(err, IsNil)
+	c.Assert(f.Type(), Equals, AshFrameTypeRst)
+	f, err = r.ReadFrame()
+	c.Assert(err, IsNil)
+	c.Assert(f.Type(), Equals, AshFrameTypeData)
+	c.Assert(f.Data, DeepEquals, []uint8{0x00, 0x00, 0x00, 0x04})
+	_, err = r.ReadFrame()
+	c.Assert(err, Equals, io.EOF)
+}
+
+func (s *EzspSuite) TestAshBadCrc(c *C) {
+	r := NewAshReader(bytes.NewReader([]uint8{0x00, 0x42, 0x21, 0xa8, 0x51, 0xed, 0x2c, 0x7e, 0xc0, 0x38, 0xbc, 0x7e}))
+	_, err := r.ReadFrame()
+	c.Assert(err, Equals, ErrAshCrc)
+	f, err := r.ReadFrame()
+	c.Assert(err, IsNil)
+	c.Assert(f.Type(), Equals, AshFrameTypeRst)
+}
+
+func (s *EzspSuite) TestReadRecordedStream(c *C) {
+	z := zcl.New()
+	r := NewReader(bytes.NewReader(recorded), false)
+
+	am, err := r.Next()
+	c.Assert(err, IsNil)
+	c.Assert(am.SrcAddr, Equals, "0x1234")
+	c.Assert(am.ProfileID, Equals, uint16(0x0104))
+	c.Assert(am.LinkQuality, Equals, uint8(0xff))
+	c.Assert(am.Rssi, Equals, int8(-40))
+	c.Assert(am.TransactionSeqNumber, Equals, uint8(0x33))
+	im, err := z.ToZclIncomingMessage(am)
+	c.Assert(err, IsNil)
+	c.Assert(im.Data.CommandName, Equals, "ReportAttributes")
+	c.Assert(im.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports[0].Attribute.Value, Equals, true)
+
+	am, err = r.Next()
+	c.Assert(err, IsNil)
+	c.Assert(am.DstAddrMode, Equals, zcl.AddressModeNwk)
+	c.Assert(am.DstAddr, Equals, "0x1234")
+	im, err = z.ToZclIncomingMessage(am)
+	c.Assert(err, IsNil)
+	c.Assert(im.Data.Command, DeepEquals, &cluster.ReadAttributesCommand{AttributeIDs: []uint16{0x0000}})
+
+	_, err = r.Next()
+	c.Assert(err, Equals, io.EOF)
+}
+
+func (s *EzspSuite) TestFromApplicationMessage(c *C) {
+	m := &zcl.ApplicationMessage{DstAddrMode: zcl.AddressModeGroup, GroupID: 0x0010, DstEndpoint: 0xff, SrcEndpoint: 1,
+		ClusterID: 0x0006, ProfileID: 0x0104, TransactionSeqNumber: 4, Data: []uint8{0x01, 0x04, 0x01}}
+	frameId, parameters, err := FromApplicationMessage(m)
+	c.Assert(err, IsNil)
+	c.Assert(frameId, Equals, FrameIdSendMulticast)
+	am := ToApplicationMessage(&Frame{FrameId: frameId, Parameters: parameters})
+	c.Assert(am, DeepEquals, &zcl.ApplicationMessage{DstAddrMode: zcl.AddressModeGroup, GroupID: 0x0010, DstEndpoint: 0xff,
+		SrcEndpoint: 1, ClusterID: 0x0006, ProfileID: 0x0104, TransactionSeqNumber: 4, Data: []uint8{0x01, 0x04, 0x01}})
+}
+
+type fakeSender struct {
+	frameId    uint16
+	parameters []uint8
+	status     uint8
+}
+
+func (f *fakeSender) Command(ctx context.Context, frameId uint16, parameters []uint8) ([]uint8, error) {
+	f.frameId, f.parameters = frameId, parameters
+	return []uint8{f.status, 0x01}, nil
+}
+
+func (s *EzspSuite) TestTransport(c *C) {
+	sender := &fakeSender{}
+	transport := NewTransport(sender)
+	err := transport.Send(context.Background(), &zcl.ApplicationMessage{DstAddr: "0xfffd", DstAddrMode: zcl.AddressModeBroadcast})
+	c.Assert(err, IsNil)
+	c.Assert(sender.frameId, Equals, FrameIdSendBroadcast)
+	c.Assert(sender.parameters[:2], DeepEquals, []uint8{0xfd, 0xff})
+
+	sender.status = 0x66
+	err = transport.Send(context.Background(), &zcl.ApplicationMessage{DstAddr: "0x1234"})
+	c.Assert(err, NotNil)
+	err = transport.Send(context.Background(), &zcl.ApplicationMessage{DstAddr: "bogus"})
+	c.Assert(err, NotNil)
+}
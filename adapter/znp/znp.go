@@ -0,0 +1,114 @@
+package znp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dyrkin/zcl-go"
+	znpgo "github.com/dyrkin/znp-go"
+)
+
+const defaultRadius = 0x1e
+
+type DataRequester interface {
+	AfDataRequest(dstAddr string, dstEndpoint uint8, srcEndpoint uint8, clusterId uint16,
+		transId uint8, options *znpgo.AfDataRequestOptions, radius uint8, data []uint8) (*znpgo.StatusResponse, error)
+	AfDataRequestExt(dstAddrMode znpgo.AddrMode, dstAddr string, dstEndpoint uint8, dstPanId uint16,
+		srcEndpoint uint8, clusterId uint16, transId uint8, options *znpgo.AfDataRequestOptions, radius uint8,
+		data []uint8) (*znpgo.StatusResponse, error)
+}
+
+type Transport struct {
+	requester DataRequester
+	options   *znpgo.AfDataRequestOptions
+	radius    uint8
+}
+
+func NewTransport(requester DataRequester) *Transport {
+	return &Transport{requester: requester, options: &znpgo.AfDataRequestOptions{}, radius: defaultRadius}
+}
+
+func (t *Transport) Options(options *znpgo.AfDataRequestOptions) *Transport {
+	t.options = options
+	return t
+}
+
+func (t *Transport) Radius(radius uint8) *Transport {
+	t.radius = radius
+	return t
+}
+
+func (t *Transport) Send(ctx context.Context, m *zcl.ApplicationMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var rsp *znpgo.StatusResponse
+	var err error
+	switch m.DstAddrMode {
+	case zcl.AddressModeNwk:
+		rsp, err = t.requester.AfDataRequest(m.DstAddr, m.DstEndpoint, m.SrcEndpoint, m.ClusterID,
+			m.TransactionSeqNumber, t.options, t.radius, m.Data)
+	case zcl.AddressModeGroup:
+		rsp, err = t.requester.AfDataRequestExt(znpgo.AddrModeAddrGroup, fmt.Sprintf("0x%016x", m.GroupID), m.DstEndpoint, 0,
+			m.SrcEndpoint, m.ClusterID, m.TransactionSeqNumber, t.options, t.radius, m.Data)
+	case zcl.AddressModeIeee:
+		rsp, err = t.requester.AfDataRequestExt(znpgo.AddrModeAddr64Bit, m.DstAddr, m.DstEndpoint, 0,
+			m.SrcEndpoint, m.ClusterID, m.TransactionSeqNumber, t.options, t.radius, m.Data)
+	case zcl.AddressModeBroadcast:
+		rsp, err = t.requester.AfDataRequestExt(znpgo.AddrModeAddrBroadcast, m.DstAddr, m.DstEndpoint, 0,
+			m.SrcEndpoint, m.ClusterID, m.TransactionSeqNumber, t.options, t.radius, m.Data)
+	default:
+		return fmt.Errorf("unsupported address mode %d", m.DstAddrMode)
+	}
+	if err != nil {
+		return err
+	}
+	if rsp.Status != znpgo.StatusSuccess {
+		return fmt.Errorf("data request failed with status %d", rsp.Status)
+	}
+	return nil
+}
+
+func ToApplicationMessage(m *znpgo.AfIncomingMessage) *zcl.ApplicationMessage {
+	am := &zcl.ApplicationMessage{}
+	am.SrcAddrMode = zcl.AddressModeNwk
+	am.SrcAddr = m.SrcAddr
+	am.GroupID = m.GroupID
+	if m.GroupID != 0 {
+		am.DstAddrMode = zcl.AddressModeGroup
+	}
+	am.SrcEndpoint = m.SrcEndpoint
+	am.DstEndpoint = m.DstEndpoint
+	am.ClusterID = m.ClusterID
+	am.WasBroadcast = m.WasBroadcast > 0
+	am.LinkQuality = m.LinkQuality
+	am.SecurityUse = m.SecurityUse > 0
+	am.Timestamp = m.Timestamp
+	am.TransactionSeqNumber = m.TransSeqNumber
+	am.Data = m.Data
+	return am
+}
+
+func ExtToApplicationMessage(m *znpgo.AfIncomingMessageExt) *zcl.ApplicationMessage {
+	am := &zcl.ApplicationMessage{}
+	am.SrcAddrMode = zcl.AddressModeIeee
+	am.SrcAddr = m.SrcAddr
+	if m.SrcAddrMode == znpgo.AddrModeAddr16Bit && len(m.SrcAddr) == 18 {
+		am.SrcAddrMode = zcl.AddressModeNwk
+		am.SrcAddr = "0x" + m.SrcAddr[14:]
+	}
+	am.GroupID = m.GroupID
+	if m.GroupID != 0 {
+		am.DstAddrMode = zcl.AddressModeGroup
+	}
+	am.SrcEndpoint = m.SrcEndpoint
+	am.DstEndpoint = m.DstEndpoint
+	am.ClusterID = m.ClusterID
+	am.WasBroadcast = m.WasBroadcast > 0
+	am.LinkQuality = m.LinkQuality
+	am.SecurityUse = m.SecurityUse > 0
+	am.Timestamp = m.Timestamp
+	am.TransactionSeqNumber = m.TransSeqNumber
+	am.Data = m.Data
+	return am
+}
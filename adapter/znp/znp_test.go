@@ -0,0 +1,80 @@
+package znp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	znpgo "github.com/dyrkin/znp-go"
+	. "gopkg.in/check.v1"
+)
+
+func TestZnp(t *testing.T) { TestingT(t) }
+
+type ZnpSuite struct{}
+
+var _ = Suite(&ZnpSuite{})
+
+type dataRequest struct {
+	addrMode znpgo.AddrMode
+	dstAddr  string
+	data     []uint8
+}
+
+type fakeRequester struct {
+	requests []*dataRequest
+	status   znpgo.Status
+}
+
+func (f *fakeRequester) AfDataRequest(dstAddr string, dstEndpoint uint8, srcEndpoint uint8, clusterId uint16,
+	transId uint8, options *znpgo.AfDataRequestOptions, radius uint8, data []uint8) (*znpgo.StatusResponse, error) {
+	f.requests = append(f.requests, &dataRequest{znpgo.AddrModeAddr16Bit, dstAddr, data})
+	return &znpgo.StatusResponse{Status: f.status}, nil
+}
+
+func (f *fakeRequester) AfDataRequestExt(dstAddrMode znpgo.AddrMode, dstAddr string, dstEndpoint uint8, dstPanId uint16,
+	srcEndpoint uint8, clusterId uint16, transId uint8, options *znpgo.AfDataRequestOptions, radius uint8,
+	data []uint8) (*znpgo.StatusResponse, error) {
+	f.requests = append(f.requests, &dataRequest{dstAddrMode, dstAddr, data})
+	return &znpgo.StatusResponse{Status: f.status}, nil
+}
+
+func (s *ZnpSuite) TestToZclIncomingMessage(c *C) {
+	m := &znpgo.AfIncomingMessage{
+		ClusterID:      uint16(cluster.OnOff),
+		SrcAddr:        "0x1234",
+		SrcEndpoint:    1,
+		DstEndpoint:    2,
+		LinkQuality:    120,
+		TransSeqNumber: 9,
+		Data:           []uint8{0x18, 0x05, 0x0a, 0x00, 0x00, byte(cluster.ZclDataTypeBoolean), 0x01},
+	}
+	im, err := zcl.New().ToZclIncomingMessage(ToApplicationMessage(m))
+	c.Assert(err, IsNil)
+	c.Assert(im.SrcAddr, Equals, "0x1234")
+	c.Assert(im.LinkQuality, Equals, uint8(120))
+	c.Assert(im.Data.CommandName, Equals, "ReportAttributes")
+	c.Assert(im.Data.Command, DeepEquals, &cluster.ReportAttributesCommand{
+		AttributeReports: []*cluster.AttributeReport{
+			{AttributeName: "OnOff", AttributeID: 0, Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeBoolean, Value: true}},
+		},
+	})
+}
+
+func (s *ZnpSuite) TestSend(c *C) {
+	requester := &fakeRequester{}
+	transport := NewTransport(requester)
+	err := transport.Send(context.Background(), &zcl.ApplicationMessage{DstAddr: "0x1234", Data: []uint8{1, 2, 3}})
+	c.Assert(err, IsNil)
+	err = transport.Send(context.Background(), &zcl.ApplicationMessage{DstAddrMode: zcl.AddressModeGroup, GroupID: 5})
+	c.Assert(err, IsNil)
+	c.Assert(requester.requests, DeepEquals, []*dataRequest{
+		{znpgo.AddrModeAddr16Bit, "0x1234", []uint8{1, 2, 3}},
+		{znpgo.AddrModeAddrGroup, "0x0000000000000005", nil},
+	})
+
+	requester.status = znpgo.StatusFailure
+	err = transport.Send(context.Background(), &zcl.ApplicationMessage{DstAddr: "0x1234"})
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,81 @@
+package capture
+
+type ApsFrameType uint8
+
+const (
+	ApsFrameTypeData     ApsFrameType = 0
+	ApsFrameTypeCommand  ApsFrameType = 1
+	ApsFrameTypeAck      ApsFrameType = 2
+	ApsFrameTypeInterPan ApsFrameType = 3
+)
+
+type DeliveryMode uint8
+
+const (
+	DeliveryModeUnicast   DeliveryMode = 0
+	DeliveryModeBroadcast DeliveryMode = 2
+	DeliveryModeGroup     DeliveryMode = 3
+)
+
+// ApsFrame is a Zigbee application support layer frame. Endpoints, cluster and profile are set for
+// data frames and for acknowledgements of data frames.
+type ApsFrame struct {
+	FrameType     ApsFrameType
+	DeliveryMode  DeliveryMode
+	AckFormat     bool
+	AckRequest    bool
+	DstEndpoint   uint8
+	GroupAddr     uint16
+	ClusterId     uint16
+	ProfileId     uint16
+	SrcEndpoint   uint8
+	Counter       uint8
+	Fragmentation uint8
+	BlockNumber   uint8
+	Security      *SecurityHeader
+	// Payload is encrypted and followed by the MIC while the frame is secured at this layer.
+	Payload []uint8
+	secured
+}
+
+func DecodeAps(data []uint8) (*ApsFrame, error) {
+	b := &buffer{data: data}
+	fc := b.uint8()
+	f := &ApsFrame{
+		FrameType:    ApsFrameType(fc & 0x03),
+		DeliveryMode: DeliveryMode(fc >> 2 & 0x03),
+		AckFormat:    fc&0x10 != 0,
+		AckRequest:   fc&0x40 != 0,
+	}
+	hasEndpoints := f.FrameType == ApsFrameTypeData || f.FrameType == ApsFrameTypeAck && !f.AckFormat
+	if hasEndpoints {
+		if f.DeliveryMode == DeliveryModeGroup {
+			f.GroupAddr = b.uint16()
+		} else {
+			f.DstEndpoint = b.uint8()
+		}
+		f.ClusterId = b.uint16()
+		f.ProfileId = b.uint16()
+		f.SrcEndpoint = b.uint8()
+	}
+	f.Counter = b.uint8()
+	if fc&0x80 != 0 {
+		f.Fragmentation = b.uint8() & 0x03
+		if f.Fragmentation != 0 {
+			f.BlockNumber = b.uint8()
+		}
+		if f.FrameType == ApsFrameTypeAck && f.Fragmentation != 0 {
+			b.uint8() // ack bitfield
+		}
+	}
+	if fc&0x20 != 0 {
+		f.auxOffset = b.pos
+		f.Security = decodeSecurityHeader(b)
+	}
+	f.header = data[:b.pos]
+	f.Payload = b.rest()
+	if b.err != nil {
+		return nil, b.err
+	}
+	return f, nil
+}
@@ -0,0 +1,216 @@
+// Package capture reads Zigbee traffic captured by 802.15.4 sniffers into pcap and pcapng files and
+// walks the MAC, NWK and APS layers down to the application payload.
+package capture
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dyrkin/zcl-go"
+)
+
+const zdoProfile = 0x0000
+
+// Reader yields the application messages of a capture. Frames which don't carry an unfragmented
+// APS data frame of an application endpoint, or which can't be decrypted with the known keys, are
+// skipped.
+//
+// Keys sent in transport key commands the reader can decrypt are learned, so a capture of a device
+// joining with the well-known trust center link key needs no keys at all. Extended addresses are
+// learned from the frames carrying them, to build the nonces of APS frames whose security header
+// doesn't.
+type Reader struct {
+	packets     *PacketReader
+	networkKeys [][16]uint8
+	linkKeys    [][16]uint8
+	addresses   map[uint16]uint64
+	packet      *Packet
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	packets, err := NewPacketReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		packets:   packets,
+		linkKeys:  [][16]uint8{WellKnownTrustCenterLinkKey},
+		addresses: map[uint16]uint64{},
+	}, nil
+}
+
+// NetworkKey adds a key to decrypt NWK frames with. Add every key a capture spanning a key switch
+// was secured with.
+func (r *Reader) NetworkKey(key [16]uint8) *Reader {
+	r.networkKeys = addKey(r.networkKeys, key)
+	return r
+}
+
+// LinkKey adds a trust center or application link key to decrypt APS frames with, in addition to
+// the well-known trust center link key.
+func (r *Reader) LinkKey(key [16]uint8) *Reader {
+	r.linkKeys = addKey(r.linkKeys, key)
+	return r
+}
+
+// Packet returns the packet the last message was read from.
+func (r *Reader) Packet() *Packet {
+	return r.packet
+}
+
+// Next returns the next application message. Its Timestamp is the capture time in seconds since
+// the Unix epoch.
+func (r *Reader) Next() (*zcl.ApplicationMessage, error) {
+	for {
+		p, err := r.packets.ReadPacket()
+		if err != nil {
+			return nil, err
+		}
+		if am, err := r.ApplicationMessage(p); err == nil && am != nil {
+			r.packet = p
+			return am, nil
+		}
+	}
+}
+
+// NextIncomingMessage decodes the ZCL frame of the next application message.
+func (r *Reader) NextIncomingMessage(d zcl.Decoder) (*zcl.ZclIncomingMessage, error) {
+	am, err := r.Next()
+	if err != nil {
+		return nil, err
+	}
+	return d.ToZclIncomingMessage(am)
+}
+
+// ApplicationMessage decodes the layers of the packet, learning the keys and addresses it carries.
+// It returns nil without an error for frames which don't carry application data.
+func (r *Reader) ApplicationMessage(p *Packet) (*zcl.ApplicationMessage, error) {
+	mac, err := DecodePacket(p)
+	if err != nil || mac.FrameType != MacFrameTypeData {
+		return nil, err
+	}
+	nwk, err := DecodeNwk(mac.Payload)
+	if err != nil {
+		return nil, err
+	}
+	r.learnAddresses(mac, nwk)
+	if nwk.Security != nil {
+		if err := r.decryptNwk(nwk); err != nil {
+			return nil, err
+		}
+	}
+	if nwk.FrameType != NwkFrameTypeData {
+		return nil, nil
+	}
+	aps, err := DecodeAps(nwk.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if aps.Security != nil {
+		if err := r.decryptAps(nwk, aps); err != nil {
+			return nil, err
+		}
+	}
+	if aps.FrameType == ApsFrameTypeCommand {
+		r.learnKey(aps.Payload)
+		return nil, nil
+	}
+	if aps.FrameType != ApsFrameTypeData || aps.ProfileId == zdoProfile || aps.Fragmentation != 0 {
+		return nil, nil
+	}
+	am := &zcl.ApplicationMessage{}
+	am.SrcAddrMode = zcl.AddressModeNwk
+	am.SrcAddr = nwkAddr(nwk.SrcAddr)
+	switch {
+	case aps.DeliveryMode == DeliveryModeGroup:
+		am.DstAddrMode = zcl.AddressModeGroup
+		am.GroupID = aps.GroupAddr
+		am.WasBroadcast = true
+	case aps.DeliveryMode == DeliveryModeBroadcast || nwk.DstAddr >= 0xfff8:
+		am.DstAddrMode = zcl.AddressModeBroadcast
+		am.DstAddr = nwkAddr(nwk.DstAddr)
+		am.WasBroadcast = true
+	default:
+		am.DstAddrMode = zcl.AddressModeNwk
+		am.DstAddr = nwkAddr(nwk.DstAddr)
+	}
+	am.SrcEndpoint = aps.SrcEndpoint
+	am.DstEndpoint = aps.DstEndpoint
+	am.ClusterID = aps.ClusterId
+	am.ProfileID = aps.ProfileId
+	am.LinkQuality = mac.LinkQuality
+	am.Rssi = mac.Rssi
+	am.SecurityUse = nwk.Security != nil || aps.Security != nil
+	am.Timestamp = uint32(p.Timestamp.Unix())
+	am.TransactionSeqNumber = aps.Counter
+	am.Data = aps.Payload
+	return am, nil
+}
+
+func (r *Reader) decryptNwk(nwk *NwkFrame) error {
+	if len(r.networkKeys) == 0 {
+		return fmt.Errorf("nwk frame %d from 0x%04x is encrypted", nwk.SequenceNumber, nwk.SrcAddr)
+	}
+	var err error
+	for _, key := range r.networkKeys {
+		if err = nwk.Decrypt(key); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (r *Reader) decryptAps(nwk *NwkFrame, aps *ApsFrame) error {
+	keys := r.linkKeys
+	if aps.Security.KeyId() == KeyIdNetwork {
+		keys = r.networkKeys
+	}
+	source := nwk.SrcIeee
+	if source == 0 {
+		source = r.addresses[nwk.SrcAddr]
+	}
+	err := fmt.Errorf("aps frame %d from 0x%04x is encrypted", aps.Counter, nwk.SrcAddr)
+	for _, key := range keys {
+		if err = aps.Decrypt(key, source); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// learnAddresses maps the short addresses of the originator and of the last hop to the extended
+// addresses the frame carries for them.
+func (r *Reader) learnAddresses(mac *MacFrame, nwk *NwkFrame) {
+	if nwk.SrcIeee != 0 {
+		r.addresses[nwk.SrcAddr] = nwk.SrcIeee
+	}
+	if nwk.Security != nil && nwk.Security.ExtendedNonce() && mac.SrcAddrMode == MacAddressModeShort {
+		r.addresses[uint16(mac.SrcAddr)] = nwk.Security.Source
+	}
+}
+
+func (r *Reader) learnKey(payload []uint8) {
+	k, err := DecodeTransportKey(payload)
+	if err != nil {
+		return
+	}
+	switch k.KeyType {
+	case KeyTypeNetwork:
+		r.NetworkKey(k.Key)
+	case KeyTypeTrustCenterLink, KeyTypeApplicationLink:
+		r.LinkKey(k.Key)
+	}
+}
+
+func addKey(keys [][16]uint8, key [16]uint8) [][16]uint8 {
+	for _, k := range keys {
+		if k == key {
+			return keys
+		}
+	}
+	return append(keys, key)
+}
+
+func nwkAddr(addr uint16) string {
+	return fmt.Sprintf("0x%04x", addr)
+}
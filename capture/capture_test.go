@@ -0,0 +1,285 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	. "gopkg.in/check.v1"
+)
+
+func TestCapture(t *testing.T) { TestingT(t) }
+
+type CaptureSuite struct{}
+
+var _ = Suite(&CaptureSuite{})
+
+var networkKey = [16]uint8{0x01, 0x03, 0x05, 0x07, 0x09, 0x0b, 0x0d, 0x0f, 0x00, 0x02, 0x04, 0x06, 0x08, 0x0a, 0x0c, 0x0d}
+
+// 0x1234 reports OnOff to the coordinator.
+var (
+	macHeader = "4188" + "07" + "621a" + "0000" + "3412"
+	nwkHeader = "0800" + "0000" + "3412" + "1e" + "05"
+	apsReport = "00" + "01" + "0600" + "0401" + "01" + "2a" + "18070a00001001"
+	// the same frame secured with networkKey by 0xc2a00001deadbeef, frame counter 0x12345678
+	securedNwk = "0802" + "0000" + "3412" + "1e" + "05" + "28" + "78563412" + "efbeadde0100a0c2" + "00" +
+		"682380da540eebf3839315102108b8a936221e"
+	zdoRequest = "00" + "00" + "0500" + "0000" + "00" + "2b" + "01ffff"
+	macAck     = "0200" + "07"
+)
+
+func decodeHex(s string) []uint8 {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func pcapFile(order binary.ByteOrder, linkType LinkType, frames ...string) []uint8 {
+	b := &bytes.Buffer{}
+	binary.Write(b, order, []uint32{pcapMagicMicroseconds, 0x00040002, 0, 0, 0xffff, uint32(linkType)})
+	for i, frame := range frames {
+		data := decodeHex(frame)
+		binary.Write(b, order, []uint32{1600000000 + uint32(i), 250000, uint32(len(data)), uint32(len(data))})
+		b.Write(data)
+	}
+	return b.Bytes()
+}
+
+func pcapngBlock(b *bytes.Buffer, blockType uint32, body []uint8) {
+	body = append(body, make([]uint8, (4-len(body)%4)%4)...)
+	binary.Write(b, binary.LittleEndian, []uint32{blockType, uint32(len(body) + 12)})
+	b.Write(body)
+	binary.Write(b, binary.LittleEndian, uint32(len(body)+12))
+}
+
+// pcapngFile has an interface with nanosecond timestamps capturing TAP frames.
+func pcapngFile(frames ...[]uint8) []uint8 {
+	b := &bytes.Buffer{}
+	pcapngBlock(b, pcapngSectionHeaderBlock, decodeHex("4d3c2b1a"+"0100"+"0000"+"ffffffffffffffff"))
+	pcapngBlock(b, pcapngInterfaceBlock, decodeHex("1b01"+"0000"+"00000000"+"0900010009000000"+"00000000"))
+	for _, frame := range frames {
+		body := &bytes.Buffer{}
+		nanoseconds := uint64(1600000000123456789)
+		binary.Write(body, binary.LittleEndian, []uint32{0, uint32(nanoseconds >> 32), uint32(nanoseconds), uint32(len(frame)), uint32(len(frame))})
+		body.Write(frame)
+		pcapngBlock(b, pcapngEnhancedPacketBlock, body.Bytes())
+	}
+	return b.Bytes()
+}
+
+// tap prepends a TAP header reporting a 16 bit FCS, -60 dBm and a link quality of 200.
+func tap(frame string) []uint8 {
+	b := &bytes.Buffer{}
+	b.Write(decodeHex("0000" + "1c00" + "0000" + "0100" + "01000000" + "0100" + "0400"))
+	binary.Write(b, binary.LittleEndian, math.Float32bits(-60))
+	b.Write(decodeHex("0a00" + "0100" + "c8000000"))
+	b.Write(decodeHex(frame + "ffff"))
+	return b.Bytes()
+}
+
+func (s *CaptureSuite) TestReadUnsecured(c *C) {
+	file := pcapFile(binary.LittleEndian, LinkTypeIeee802154WithFcs,
+		macAck+"ffff",
+		macHeader+nwkHeader+zdoRequest+"ffff",
+		macHeader+nwkHeader+apsReport+"ffff")
+	r, err := NewReader(bytes.NewReader(file))
+	c.Assert(err, IsNil)
+	am, err := r.Next()
+	c.Assert(err, IsNil)
+	c.Assert(am, DeepEquals, &zcl.ApplicationMessage{
+		SrcAddrMode:          zcl.AddressModeNwk,
+		SrcAddr:              "0x1234",
+		DstAddrMode:          zcl.AddressModeNwk,
+		DstAddr:              "0x0000",
+		SrcEndpoint:          1,
+		DstEndpoint:          1,
+		ClusterID:            uint16(cluster.OnOff),
+		ProfileID:            0x0104,
+		Timestamp:            1600000002,
+		TransactionSeqNumber: 0x2a,
+		Data:                 decodeHex("18070a00001001"),
+	})
+	c.Assert(r.Packet().Timestamp, Equals, time.Unix(1600000002, 250000000))
+	_, err = r.Next()
+	c.Assert(err, Equals, io.EOF)
+}
+
+func (s *CaptureSuite) TestReadSecured(c *C) {
+	file := pcapFile(binary.BigEndian, LinkTypeIeee802154NoFcs, macHeader+securedNwk)
+
+	r, err := NewReader(bytes.NewReader(file))
+	c.Assert(err, IsNil)
+	_, err = r.Next()
+	c.Assert(err, Equals, io.EOF)
+
+	r, _ = NewReader(bytes.NewReader(file))
+	r.NetworkKey([16]uint8{}).NetworkKey(networkKey)
+	im, err := r.NextIncomingMessage(zcl.New())
+	c.Assert(err, IsNil)
+	c.Assert(im.SecurityUse, Equals, true)
+	c.Assert(im.Data.CommandName, Equals, "ReportAttributes")
+	report := im.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports[0]
+	c.Assert(report.AttributeName, Equals, "OnOff")
+	c.Assert(report.Attribute.Value, Equals, true)
+
+	mac, _ := DecodeMac(decodeHex(macHeader + securedNwk))
+	nwk, err := DecodeNwk(mac.Payload)
+	c.Assert(err, IsNil)
+	c.Assert(nwk.Security.FrameCounter, Equals, uint32(0x12345678))
+	c.Assert(nwk.Security.Source, Equals, uint64(0xc2a00001deadbeef))
+	c.Assert(nwk.Decrypt([16]uint8{1}), Equals, ErrMic)
+	c.Assert(nwk.Decrypt(networkKey), IsNil)
+	c.Assert(nwk.Payload, DeepEquals, decodeHex(apsReport))
+}
+
+func (s *CaptureSuite) TestReadPcapngTap(c *C) {
+	r, err := NewReader(bytes.NewReader(pcapngFile(tap(macAck), tap(macHeader+nwkHeader+apsReport))))
+	c.Assert(err, IsNil)
+	am, err := r.Next()
+	c.Assert(err, IsNil)
+	c.Assert(am.ClusterID, Equals, uint16(cluster.OnOff))
+	c.Assert(am.LinkQuality, Equals, uint8(200))
+	c.Assert(am.Rssi, Equals, int8(-60))
+	c.Assert(r.Packet().LinkType, Equals, LinkTypeIeee802154Tap)
+	c.Assert(r.Packet().Timestamp, Equals, time.Unix(1600000000, 123456789))
+	_, err = r.Next()
+	c.Assert(err, Equals, io.EOF)
+}
+
+func (s *CaptureSuite) TestDecodeLayers(c *C) {
+	mac, err := DecodeMac(decodeHex("61cc" + "2a" + "621a" + "efbeadde0100a0c2" + "0807060504030201" + "00"))
+	c.Assert(err, IsNil)
+	c.Assert(mac.AckRequest, Equals, true)
+	c.Assert(mac.DstAddrMode, Equals, MacAddressModeExtended)
+	c.Assert(mac.DstAddr, Equals, uint64(0xc2a00001deadbeef))
+	c.Assert(mac.SrcPan, Equals, uint16(0x1a62))
+	c.Assert(mac.SrcAddr, Equals, uint64(0x0102030405060708))
+	c.Assert(mac.Payload, DeepEquals, []uint8{0x00})
+
+	_, err = DecodeMac(decodeHex("4188" + "07" + "621a"))
+	c.Assert(err, ErrorMatches, "frame is truncated")
+
+	nwk, err := DecodeNwk(decodeHex("0814" + "fdff" + "3412" + "1e" + "05" + "0807060504030201" + "02" + "00" + "3300" + "4400" + "aa"))
+	c.Assert(err, IsNil)
+	c.Assert(nwk.SrcIeee, Equals, uint64(0x0102030405060708))
+	c.Assert(nwk.Relays, DeepEquals, []uint16{0x0033, 0x0044})
+	c.Assert(nwk.Payload, DeepEquals, []uint8{0xaa})
+
+	aps, err := DecodeAps(decodeHex("0c" + "0400" + "0600" + "0401" + "01" + "2b" + "01"))
+	c.Assert(err, IsNil)
+	c.Assert(aps.DeliveryMode, Equals, DeliveryModeGroup)
+	c.Assert(aps.GroupAddr, Equals, uint16(4))
+	c.Assert(aps.Payload, DeepEquals, []uint8{0x01})
+}
+
+func (s *CaptureSuite) TestCcmStar(c *C) {
+	// RFC 3610 packet vector #1
+	key := [16]uint8{0xc0, 0xc1, 0xc2, 0xc3, 0xc4, 0xc5, 0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xcb, 0xcc, 0xcd, 0xce, 0xcf}
+	nonce := decodeHex("00000003020100a0a1a2a3a4a5")
+	a := decodeHex("0001020304050607")
+	m := decodeHex("08090a0b0c0d0e0f101112131415161718191a1b1c1d1e")
+	encrypted := decodeHex("588c979a61c663d2f066d0c2c0f989806d5f6b61dac38417e8d12cfdf926e0")
+	c.Assert(ccmStarEncrypt(key, nonce, a, m, 8), DeepEquals, encrypted)
+	decrypted, err := ccmStarDecrypt(key, nonce, a, encrypted, 8)
+	c.Assert(err, IsNil)
+	c.Assert(decrypted, DeepEquals, m)
+	encrypted[0] ^= 1
+	_, err = ccmStarDecrypt(key, nonce, a, encrypted, 8)
+	c.Assert(err, Equals, ErrMic)
+}
+
+// The test vector of Annex C.6.1 of the Zigbee specification, decrypted through the nonce built
+// from the auxiliary header: the source address, the frame counter and the security control,
+// each little endian.
+func (s *CaptureSuite) TestCcmStarZigbeeVector(c *C) {
+	key := [16]uint8{0xc0, 0xc1, 0xc2, 0xc3, 0xc4, 0xc5, 0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xcb, 0xcc, 0xcd, 0xce, 0xcf}
+	h := &SecurityHeader{Control: 0x06, FrameCounter: 0x00010203}
+	sec := &secured{header: decodeHex("0001020304050607"), auxOffset: 6}
+	encrypted := decodeHex("1a55a36abb6c610d066b3375649cef10d4664ecad854a80a895cc1d8ff9469")
+	m, err := sec.decrypt(key, h, 0xa7a6a5a4a3a2a1a0, encrypted)
+	c.Assert(err, IsNil)
+	c.Assert(m, DeepEquals, decodeHex("08090a0b0c0d0e0f101112131415161718191a1b1c1d1e"))
+	c.Assert(ccmStarEncrypt(key, decodeHex("a0a1a2a3a4a5a6a703020100"+"06"), decodeHex("0001020304050607"), m, 8), DeepEquals, encrypted)
+}
+
+func (s *CaptureSuite) TestUnknownFormat(c *C) {
+	_, err := NewReader(bytes.NewReader([]uint8{1, 2, 3, 4, 5}))
+	c.Assert(err, Equals, ErrUnknownFormat)
+	r, err := NewPacketReader(bytes.NewReader(pcapFile(binary.LittleEndian, LinkTypeIeee802154NoFcs, "0200")[:30]))
+	c.Assert(err, IsNil)
+	_, err = r.ReadPacket()
+	c.Assert(err, Equals, io.ErrUnexpectedEOF)
+}
+
+func (s *CaptureSuite) TestOversizedPackets(c *C) {
+	file := pcapFile(binary.LittleEndian, LinkTypeIeee802154NoFcs, "0200")
+	binary.LittleEndian.PutUint32(file[24+8:], 0xffffffff)
+	r, err := NewPacketReader(bytes.NewReader(file))
+	c.Assert(err, IsNil)
+	_, err = r.ReadPacket()
+	c.Assert(err, ErrorMatches, "invalid pcap packet length 4294967295")
+
+	file = pcapngFile(tap("0200"))
+	block := len(file) - len(tap("0200")) - 32
+	binary.LittleEndian.PutUint32(file[block+4:], 0xfffffff0)
+	r, err = NewPacketReader(bytes.NewReader(file))
+	c.Assert(err, IsNil)
+	_, err = r.ReadPacket()
+	c.Assert(err, ErrorMatches, "invalid pcapng block length 4294967280")
+}
+
+func (s *CaptureSuite) TestJoinWithWellKnownKey(c *C) {
+	coordinator := "4188" + "08" + "621a" + "3412" + "0000"
+	file := pcapFile(binary.LittleEndian, LinkTypeIeee802154NoFcs,
+		// the trust center at 0x00124b0001020304 sends networkKey secured with the key-transport key
+		coordinator+"0800"+"3412"+"0000"+"1e"+"06"+
+			"2107300100000004030201004b1200a38edea4a50c8f5b2faa21abbc7aaa4618d5b673ffa4b6258a041a9cf90249aa4b69d69d69a22f",
+		// 0x1234 reports OnOff in an APS frame secured with the trust center link key without
+		// extended nonce, inside a NWK frame secured with networkKey
+		macHeader+"0802000034121e062879563412efbeadde0100a0c2002b278c987771963b8639d6bbb2f85d5a7987950af0510d88757d7df6")
+	r, err := NewReader(bytes.NewReader(file))
+	c.Assert(err, IsNil)
+	im, err := r.NextIncomingMessage(zcl.New())
+	c.Assert(err, IsNil)
+	c.Assert(im.SrcAddr, Equals, "0x1234")
+	c.Assert(im.SecurityUse, Equals, true)
+	report := im.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports[0]
+	c.Assert(report.Attribute.Value, Equals, false)
+	c.Assert(r.networkKeys, DeepEquals, [][16]uint8{networkKey})
+}
+
+func (s *CaptureSuite) TestKeyedHash(c *C) {
+	// vectors of the Matyas-Meyer-Oseas hash and of the keyed hash in annex C of the specification
+	c.Assert(mmoHash([]uint8{0xc0}), DeepEquals, [16]uint8{0xae, 0x3a, 0x10, 0x2a, 0x28, 0xd4, 0x3e, 0xe0, 0xd4, 0xa0, 0x9e, 0x22, 0x78, 0x8b, 0x20, 0x6c})
+	c.Assert(mmoHash(decodeHex("c0c1c2c3c4c5c6c7c8c9cacbcccdcecf")), DeepEquals, [16]uint8{0xa7, 0x97, 0x7e, 0x88, 0xbc, 0x0b, 0x61, 0xe8, 0x21, 0x08, 0x27, 0x10, 0x9a, 0x22, 0x8f, 0x2d})
+	key := [16]uint8{0x40, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49, 0x4a, 0x4b, 0x4c, 0x4d, 0x4e, 0x4f}
+	c.Assert(hmacMmo(key, []uint8{0xc0}), DeepEquals, [16]uint8{0x45, 0x12, 0x80, 0x7b, 0xf9, 0x4c, 0xb3, 0x40, 0x0f, 0x0e, 0x2c, 0x25, 0xfb, 0x76, 0xe9, 0x99})
+	c.Assert(apsKey(WellKnownTrustCenterLinkKey, KeyIdKeyTransport), DeepEquals, [16]uint8{0x4b, 0xab, 0x0f, 0x17, 0x3e, 0x14, 0x34, 0xa2, 0xd5, 0x72, 0xe1, 0xc1, 0xef, 0x47, 0x87, 0x82})
+	c.Assert(apsKey(WellKnownTrustCenterLinkKey, KeyIdData), Equals, WellKnownTrustCenterLinkKey)
+}
+
+func (s *CaptureSuite) TestDecryptAps(c *C) {
+	nwk, _ := DecodeNwk(decodeHex("0802000034121e062879563412efbeadde0100a0c2002b278c987771963b8639d6bbb2f85d5a7987950af0510d88757d7df6"))
+	c.Assert(nwk.Decrypt(networkKey), IsNil)
+	aps, err := DecodeAps(nwk.Payload)
+	c.Assert(err, IsNil)
+	c.Assert(aps.Security.KeyId(), Equals, KeyIdData)
+	c.Assert(aps.Decrypt(WellKnownTrustCenterLinkKey, 0), ErrorMatches, "source address of the nonce is unknown")
+	c.Assert(aps.Decrypt(networkKey, 0xc2a00001deadbeef), Equals, ErrMic)
+	c.Assert(aps.Decrypt(WellKnownTrustCenterLinkKey, 0xc2a00001deadbeef), IsNil)
+	c.Assert(aps.Payload, DeepEquals, decodeHex("18080a00001000"))
+
+	k, err := DecodeTransportKey(decodeHex("05" + "01" + "01030507090b0d0f00020406080a0c0d" + "03" + "efbeadde0100a0c2" + "04030201004b1200"))
+	c.Assert(err, IsNil)
+	c.Assert(k, DeepEquals, &TransportKey{KeyType: KeyTypeNetwork, Key: networkKey, KeySequence: 3})
+	_, err = DecodeTransportKey(decodeHex("0601"))
+	c.Assert(err, ErrorMatches, "unexpected aps command 0x06")
+}
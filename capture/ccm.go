@@ -0,0 +1,100 @@
+package capture
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+var ErrMic = errors.New("message integrity code mismatch")
+
+// ccmStarDecrypt decrypts the ciphertext c, followed by a MIC of micLength bytes, and checks the MIC
+// against the plaintext and the additional data a. Nonces are 13 bytes, so lengths take 2 bytes.
+func ccmStarDecrypt(key [16]uint8, nonce []uint8, a []uint8, c []uint8, micLength int) ([]uint8, error) {
+	if len(c) < micLength {
+		return nil, errTruncated
+	}
+	block, _ := aes.NewCipher(key[:])
+	mic := c[len(c)-micLength:]
+	m := ccmCtr(block, nonce, c[:len(c)-micLength])
+	if micLength > 0 {
+		expected := ccmMic(block, nonce, a, m, micLength)
+		if subtle.ConstantTimeCompare(expected, mic) != 1 {
+			return nil, ErrMic
+		}
+	}
+	return m, nil
+}
+
+// ccmStarEncrypt returns the ciphertext of m followed by its MIC.
+func ccmStarEncrypt(key [16]uint8, nonce []uint8, a []uint8, m []uint8, micLength int) []uint8 {
+	block, _ := aes.NewCipher(key[:])
+	c := ccmCtr(block, nonce, m)
+	if micLength > 0 {
+		c = append(c, ccmMic(block, nonce, a, m, micLength)...)
+	}
+	return c
+}
+
+// ccmMic computes the CBC-MAC of the additional data and the message and encrypts it with the first
+// block of the key stream.
+func ccmMic(block cipher.Block, nonce []uint8, a []uint8, m []uint8, micLength int) []uint8 {
+	b := make([]uint8, aes.BlockSize)
+	b[0] = uint8((micLength-2)/2)<<3 | 0x01
+	if len(a) > 0 {
+		b[0] |= 0x40
+	}
+	copy(b[1:14], nonce)
+	binary.BigEndian.PutUint16(b[14:], uint16(len(m)))
+	var auth []uint8
+	if len(a) > 0 {
+		auth = append(auth, uint8(len(a)>>8), uint8(len(a)))
+		auth = append(auth, a...)
+		auth = pad(auth)
+	}
+	auth = append(auth, pad(m)...)
+	x := make([]uint8, aes.BlockSize)
+	block.Encrypt(x, b)
+	for i := 0; i < len(auth); i += aes.BlockSize {
+		for j := range x {
+			x[j] ^= auth[i+j]
+		}
+		block.Encrypt(x, x)
+	}
+	s := make([]uint8, aes.BlockSize)
+	block.Encrypt(s, counterBlock(nonce, 0))
+	for i := 0; i < micLength; i++ {
+		x[i] ^= s[i]
+	}
+	return x[:micLength]
+}
+
+// ccmCtr xors data with the key stream starting at counter 1.
+func ccmCtr(block cipher.Block, nonce []uint8, data []uint8) []uint8 {
+	out := make([]uint8, len(data))
+	s := make([]uint8, aes.BlockSize)
+	for i := 0; i < len(data); i += aes.BlockSize {
+		block.Encrypt(s, counterBlock(nonce, uint16(i/aes.BlockSize+1)))
+		for j := i; j < len(data) && j < i+aes.BlockSize; j++ {
+			out[j] = data[j] ^ s[j-i]
+		}
+	}
+	return out
+}
+
+func counterBlock(nonce []uint8, counter uint16) []uint8 {
+	a := make([]uint8, aes.BlockSize)
+	a[0] = 0x01
+	copy(a[1:14], nonce)
+	binary.BigEndian.PutUint16(a[14:], counter)
+	return a
+}
+
+// pad returns a copy of b padded with zeros to a multiple of the block size.
+func pad(b []uint8) []uint8 {
+	padded := make([]uint8, (len(b)+aes.BlockSize-1)/aes.BlockSize*aes.BlockSize)
+	copy(padded, b)
+	return padded
+}
@@ -0,0 +1,208 @@
+package capture
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var errTruncated = errors.New("frame is truncated")
+
+type MacFrameType uint8
+
+const (
+	MacFrameTypeBeacon  MacFrameType = 0
+	MacFrameTypeData    MacFrameType = 1
+	MacFrameTypeAck     MacFrameType = 2
+	MacFrameTypeCommand MacFrameType = 3
+)
+
+type MacAddressMode uint8
+
+const (
+	MacAddressModeNone     MacAddressMode = 0
+	MacAddressModeShort    MacAddressMode = 2
+	MacAddressModeExtended MacAddressMode = 3
+)
+
+const (
+	tapFcsType = 0
+	tapRss     = 1
+	tapLqi     = 10
+)
+
+// MacFrame is an IEEE 802.15.4 frame. Addresses are those of the hop, short or extended depending on
+// the address mode.
+type MacFrame struct {
+	FrameType      MacFrameType
+	Security       bool
+	FramePending   bool
+	AckRequest     bool
+	Version        uint8
+	SequenceNumber uint8
+	DstAddrMode    MacAddressMode
+	DstPan         uint16
+	DstAddr        uint64
+	SrcAddrMode    MacAddressMode
+	SrcPan         uint16
+	SrcAddr        uint64
+	Payload        []uint8
+	// LinkQuality and Rssi are reported by TAP captures only.
+	LinkQuality uint8
+	Rssi        int8
+}
+
+// DecodePacket strips the link layer framing of the packet and decodes its 802.15.4 frame.
+func DecodePacket(p *Packet) (*MacFrame, error) {
+	switch p.LinkType {
+	case LinkTypeIeee802154WithFcs:
+		if len(p.Data) < 2 {
+			return nil, errTruncated
+		}
+		return DecodeMac(p.Data[:len(p.Data)-2])
+	case LinkTypeIeee802154NoFcs:
+		return DecodeMac(p.Data)
+	case LinkTypeIeee802154Tap:
+		return decodeTap(p.Data)
+	}
+	return nil, fmt.Errorf("unsupported link type %d", p.LinkType)
+}
+
+// decodeTap reads the TLVs of an IEEE 802.15.4 TAP header preceding the frame.
+func decodeTap(data []uint8) (*MacFrame, error) {
+	if len(data) < 4 {
+		return nil, errTruncated
+	}
+	length := int(binary.LittleEndian.Uint16(data[2:]))
+	if data[0] != 0 || length < 4 || length > len(data) {
+		return nil, errors.New("invalid tap header")
+	}
+	fcsLength := 0
+	var lqi uint8
+	var rssi int8
+	tlvs := data[4:length]
+	for len(tlvs) >= 4 {
+		t, l := binary.LittleEndian.Uint16(tlvs), int(binary.LittleEndian.Uint16(tlvs[2:]))
+		if len(tlvs) < 4+l {
+			return nil, errors.New("invalid tap header")
+		}
+		value := tlvs[4 : 4+l]
+		switch {
+		case t == tapFcsType && l >= 1:
+			fcsLength = 2 * int(value[0])
+		case t == tapRss && l == 4:
+			rssi = int8(math.Round(float64(math.Float32frombits(binary.LittleEndian.Uint32(value)))))
+		case t == tapLqi && l >= 1:
+			lqi = value[0]
+		}
+		tlvs = tlvs[4+(l+3)&^3:]
+	}
+	frame := data[length:]
+	if len(frame) < fcsLength {
+		return nil, errTruncated
+	}
+	f, err := DecodeMac(frame[:len(frame)-fcsLength])
+	if err != nil {
+		return nil, err
+	}
+	f.LinkQuality, f.Rssi = lqi, rssi
+	return f, nil
+}
+
+// DecodeMac decodes a frame without its FCS. Frames secured at the MAC layer, which Zigbee doesn't
+// use, and frames with information elements are not supported.
+func DecodeMac(data []uint8) (*MacFrame, error) {
+	b := &buffer{data: data}
+	fc := b.uint16()
+	f := &MacFrame{
+		FrameType:    MacFrameType(fc & 0x07),
+		Security:     fc&0x0008 != 0,
+		FramePending: fc&0x0010 != 0,
+		AckRequest:   fc&0x0020 != 0,
+		DstAddrMode:  MacAddressMode(fc >> 10 & 0x03),
+		Version:      uint8(fc >> 12 & 0x03),
+		SrcAddrMode:  MacAddressMode(fc >> 14 & 0x03),
+	}
+	panIdCompression := fc&0x0040 != 0
+	if f.Version == 2 && fc&0x0100 != 0 {
+		return nil, errors.New("sequence number suppression is not supported")
+	}
+	if fc&0x0200 != 0 {
+		return nil, errors.New("information elements are not supported")
+	}
+	f.SequenceNumber = b.uint8()
+	if f.DstAddrMode != MacAddressModeNone {
+		f.DstPan = b.uint16()
+		f.DstAddr = b.address(f.DstAddrMode)
+	}
+	if f.SrcAddrMode != MacAddressModeNone {
+		if panIdCompression {
+			f.SrcPan = f.DstPan
+		} else {
+			f.SrcPan = b.uint16()
+		}
+		f.SrcAddr = b.address(f.SrcAddrMode)
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	if f.Security {
+		return nil, errors.New("mac security is not supported")
+	}
+	f.Payload = b.rest()
+	return f, nil
+}
+
+// buffer reads little endian fields, remembering the first read past the end.
+type buffer struct {
+	data []uint8
+	pos  int
+	err  error
+}
+
+func (b *buffer) bytes(n int) []uint8 {
+	if b.err != nil || len(b.data)-b.pos < n {
+		b.err = errTruncated
+		return make([]uint8, n)
+	}
+	v := b.data[b.pos : b.pos+n]
+	b.pos += n
+	return v
+}
+
+func (b *buffer) uint8() uint8 {
+	return b.bytes(1)[0]
+}
+
+func (b *buffer) uint16() uint16 {
+	return binary.LittleEndian.Uint16(b.bytes(2))
+}
+
+func (b *buffer) uint32() uint32 {
+	return binary.LittleEndian.Uint32(b.bytes(4))
+}
+
+func (b *buffer) uint64() uint64 {
+	return binary.LittleEndian.Uint64(b.bytes(8))
+}
+
+func (b *buffer) address(mode MacAddressMode) uint64 {
+	switch mode {
+	case MacAddressModeShort:
+		return uint64(b.uint16())
+	case MacAddressModeExtended:
+		return b.uint64()
+	}
+	b.err = fmt.Errorf("invalid address mode %d", mode)
+	return 0
+}
+
+func (b *buffer) rest() []uint8 {
+	if b.err != nil {
+		return nil
+	}
+	v := b.data[b.pos:]
+	b.pos = len(b.data)
+	return v
+}
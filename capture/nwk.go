@@ -0,0 +1,185 @@
+package capture
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+type NwkFrameType uint8
+
+const (
+	NwkFrameTypeData     NwkFrameType = 0
+	NwkFrameTypeCommand  NwkFrameType = 1
+	NwkFrameTypeInterPan NwkFrameType = 3
+)
+
+type KeyId uint8
+
+const (
+	KeyIdData         KeyId = 0
+	KeyIdNetwork      KeyId = 1
+	KeyIdKeyTransport KeyId = 2
+	KeyIdKeyLoad      KeyId = 3
+)
+
+// defaultSecurityLevel replaces the level of 0 sent over the air, ENC-MIC-32.
+const defaultSecurityLevel = 5
+
+var micLengths = []int{0, 4, 8, 16, 0, 4, 8, 16}
+
+// SecurityHeader is the auxiliary header of NWK and APS frames.
+type SecurityHeader struct {
+	Control      uint8
+	FrameCounter uint32
+	// Source is the extended address of the sender if the header has the extended nonce flag.
+	Source      uint64
+	KeySequence uint8
+}
+
+func (h *SecurityHeader) Level() uint8 {
+	if level := h.Control & 0x07; level != 0 {
+		return level
+	}
+	return defaultSecurityLevel
+}
+
+func (h *SecurityHeader) KeyId() KeyId {
+	return KeyId(h.Control >> 3 & 0x03)
+}
+
+func (h *SecurityHeader) ExtendedNonce() bool {
+	return h.Control&0x20 != 0
+}
+
+func decodeSecurityHeader(b *buffer) *SecurityHeader {
+	h := &SecurityHeader{Control: b.uint8(), FrameCounter: b.uint32()}
+	if h.ExtendedNonce() {
+		h.Source = b.uint64()
+	}
+	if h.KeyId() == KeyIdNetwork {
+		h.KeySequence = b.uint8()
+	}
+	return h
+}
+
+// secured holds what the MIC of a secured frame covers besides the payload.
+type secured struct {
+	header    []uint8
+	auxOffset int
+}
+
+// decrypt returns the plaintext of the payload, which is followed by the MIC. The additional data
+// and the nonce use the actual security level in place of the one sent over the air.
+func (s *secured) decrypt(key [16]uint8, h *SecurityHeader, source uint64, payload []uint8) ([]uint8, error) {
+	control := h.Control&^0x07 | h.Level()
+	a := append([]uint8{}, s.header...)
+	a[s.auxOffset] = control
+	nonce := make([]uint8, 13)
+	binary.LittleEndian.PutUint64(nonce, source)
+	binary.LittleEndian.PutUint32(nonce[8:], h.FrameCounter)
+	nonce[12] = control
+	micLength := micLengths[h.Level()]
+	if h.Level() < 4 {
+		// integrity only, the payload is sent in the clear and authenticated with the header
+		if len(payload) < micLength {
+			return nil, errTruncated
+		}
+		m := payload[:len(payload)-micLength]
+		if _, err := ccmStarDecrypt(key, nonce, append(a, m...), payload[len(m):], micLength); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	return ccmStarDecrypt(key, nonce, a, payload, micLength)
+}
+
+// NwkFrame is a Zigbee network layer frame. IEEE addresses are zero unless present in the header.
+type NwkFrame struct {
+	FrameType        NwkFrameType
+	ProtocolVersion  uint8
+	DiscoverRoute    uint8
+	Multicast        bool
+	EndDevice        bool
+	DstAddr          uint16
+	SrcAddr          uint16
+	Radius           uint8
+	SequenceNumber   uint8
+	DstIeee          uint64
+	SrcIeee          uint64
+	MulticastControl uint8
+	Relays           []uint16
+	Security         *SecurityHeader
+	// Payload is encrypted and followed by the MIC until Decrypt succeeds.
+	Payload []uint8
+	secured
+}
+
+func DecodeNwk(data []uint8) (*NwkFrame, error) {
+	b := &buffer{data: data}
+	fc := b.uint16()
+	f := &NwkFrame{
+		FrameType:       NwkFrameType(fc & 0x03),
+		ProtocolVersion: uint8(fc >> 2 & 0x0f),
+		DiscoverRoute:   uint8(fc >> 6 & 0x03),
+		Multicast:       fc&0x0100 != 0,
+		EndDevice:       fc&0x2000 != 0,
+	}
+	if f.FrameType == NwkFrameTypeInterPan {
+		f.Payload = b.rest()
+		return f, b.err
+	}
+	f.DstAddr = b.uint16()
+	f.SrcAddr = b.uint16()
+	f.Radius = b.uint8()
+	f.SequenceNumber = b.uint8()
+	if fc&0x0800 != 0 {
+		f.DstIeee = b.uint64()
+	}
+	if fc&0x1000 != 0 {
+		f.SrcIeee = b.uint64()
+	}
+	if f.Multicast {
+		f.MulticastControl = b.uint8()
+	}
+	if fc&0x0400 != 0 {
+		count := int(b.uint8())
+		b.uint8() // relay index
+		for i := 0; i < count && b.err == nil; i++ {
+			f.Relays = append(f.Relays, b.uint16())
+		}
+	}
+	if fc&0x0200 != 0 {
+		f.auxOffset = b.pos
+		f.Security = decodeSecurityHeader(b)
+	}
+	f.header = data[:b.pos]
+	f.Payload = b.rest()
+	if b.err != nil {
+		return nil, b.err
+	}
+	return f, nil
+}
+
+// Decrypt replaces the payload of a frame secured with the network key by its plaintext.
+func (f *NwkFrame) Decrypt(key [16]uint8) error {
+	if f.Security == nil {
+		return errors.New("frame is not secured")
+	}
+	if f.Security.KeyId() != KeyIdNetwork {
+		return fmt.Errorf("unexpected key identifier %d", f.Security.KeyId())
+	}
+	source := f.Security.Source
+	if !f.Security.ExtendedNonce() {
+		if f.SrcIeee == 0 {
+			return errors.New("source address of the nonce is unknown")
+		}
+		source = f.SrcIeee
+	}
+	payload, err := f.decrypt(key, f.Security, source, f.Payload)
+	if err != nil {
+		return err
+	}
+	f.Payload = payload
+	return nil
+}
@@ -0,0 +1,271 @@
+package capture
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"time"
+)
+
+type LinkType uint32
+
+const (
+	LinkTypeIeee802154WithFcs LinkType = 195
+	LinkTypeIeee802154NoFcs   LinkType = 230
+	LinkTypeIeee802154Tap     LinkType = 283
+)
+
+const (
+	pcapMagicMicroseconds = 0xa1b2c3d4
+	pcapMagicNanoseconds  = 0xa1b23c4d
+	pcapngByteOrderMagic  = 0x1a2b3c4d
+)
+
+const (
+	pcapngSectionHeaderBlock        = 0x0a0d0d0a
+	pcapngInterfaceBlock            = 0x00000001
+	pcapngPacketBlock               = 0x00000002
+	pcapngSimplePacketBlock         = 0x00000003
+	pcapngEnhancedPacketBlock       = 0x00000006
+	pcapngOptionEnd                 = 0
+	pcapngOptionTimestampResolution = 9
+)
+
+const (
+	// maxPacketLength is the largest snap length of libpcap. Longer packets are taken for a corrupt
+	// file rather than allocated.
+	maxPacketLength = 262144
+	// maxBlockLength leaves room for the header and options of a pcapng packet block.
+	maxBlockLength = maxPacketLength + 1<<16
+)
+
+var ErrUnknownFormat = errors.New("not a pcap or pcapng capture")
+
+// Packet is a captured link layer frame.
+type Packet struct {
+	Timestamp time.Time
+	LinkType  LinkType
+	Data      []uint8
+}
+
+type pcapngInterface struct {
+	linkType LinkType
+	snapLen  uint32
+	// resolution is the duration of a timestamp unit.
+	resolution float64
+}
+
+// PacketReader reads the packets of a pcap or pcapng capture.
+type PacketReader struct {
+	r     io.Reader
+	order binary.ByteOrder
+	ng    bool
+	// pcap
+	linkType   LinkType
+	resolution time.Duration
+	// pcapng
+	interfaces []*pcapngInterface
+}
+
+// NewPacketReader detects the format of the capture from its first bytes.
+func NewPacketReader(r io.Reader) (*PacketReader, error) {
+	pr := &PacketReader{r: r}
+	magic := make([]uint8, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, ErrUnknownFormat
+	}
+	if binary.LittleEndian.Uint32(magic) == pcapngSectionHeaderBlock {
+		pr.ng = true
+		return pr, pr.readSectionHeader()
+	}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(magic) {
+		case pcapMagicMicroseconds:
+			pr.order, pr.resolution = order, time.Microsecond
+		case pcapMagicNanoseconds:
+			pr.order, pr.resolution = order, time.Nanosecond
+		default:
+			continue
+		}
+		header := make([]uint8, 20)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, unexpected(err)
+		}
+		pr.linkType = LinkType(order.Uint32(header[16:]))
+		return pr, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ReadPacket returns the next packet, or io.EOF at the end of the capture.
+func (pr *PacketReader) ReadPacket() (*Packet, error) {
+	if pr.ng {
+		return pr.readBlocks()
+	}
+	header := make([]uint8, 16)
+	if _, err := io.ReadFull(pr.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	seconds := pr.order.Uint32(header)
+	fraction := pr.order.Uint32(header[4:])
+	length := pr.order.Uint32(header[8:])
+	if length > maxPacketLength {
+		return nil, fmt.Errorf("invalid pcap packet length %d", length)
+	}
+	data := make([]uint8, length)
+	if _, err := io.ReadFull(pr.r, data); err != nil {
+		return nil, unexpected(err)
+	}
+	return &Packet{
+		Timestamp: time.Unix(int64(seconds), int64(fraction)*int64(pr.resolution)),
+		LinkType:  pr.linkType,
+		Data:      data,
+	}, nil
+}
+
+// readSectionHeader reads the rest of a section header block, whose type has been read already.
+func (pr *PacketReader) readSectionHeader() error {
+	header := make([]uint8, 8)
+	if _, err := io.ReadFull(pr.r, header); err != nil {
+		return unexpected(err)
+	}
+	switch {
+	case binary.LittleEndian.Uint32(header[4:]) == pcapngByteOrderMagic:
+		pr.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(header[4:]) == pcapngByteOrderMagic:
+		pr.order = binary.BigEndian
+	default:
+		return ErrUnknownFormat
+	}
+	length := pr.order.Uint32(header)
+	if length < 28 || length%4 != 0 {
+		return fmt.Errorf("invalid pcapng block length %d", length)
+	}
+	if _, err := io.CopyN(ioutil.Discard, pr.r, int64(length-12)); err != nil {
+		return unexpected(err)
+	}
+	pr.interfaces = nil
+	return nil
+}
+
+func (pr *PacketReader) readBlocks() (*Packet, error) {
+	for {
+		header := make([]uint8, 4)
+		if _, err := io.ReadFull(pr.r, header); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		// the block type of section headers reads the same in both byte orders
+		if pr.order.Uint32(header) == pcapngSectionHeaderBlock {
+			if err := pr.readSectionHeader(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		blockType := pr.order.Uint32(header)
+		if _, err := io.ReadFull(pr.r, header); err != nil {
+			return nil, unexpected(err)
+		}
+		length := pr.order.Uint32(header)
+		if length < 12 || length%4 != 0 || length > maxBlockLength {
+			return nil, fmt.Errorf("invalid pcapng block length %d", length)
+		}
+		body := make([]uint8, length-8)
+		if _, err := io.ReadFull(pr.r, body); err != nil {
+			return nil, unexpected(err)
+		}
+		body = body[:len(body)-4]
+		switch blockType {
+		case pcapngInterfaceBlock:
+			if err := pr.readInterface(body); err != nil {
+				return nil, err
+			}
+		case pcapngEnhancedPacketBlock, pcapngPacketBlock:
+			if len(body) < 20 {
+				return nil, errors.New("truncated pcapng packet block")
+			}
+			id := pr.order.Uint32(body)
+			if blockType == pcapngPacketBlock {
+				id = uint32(pr.order.Uint16(body))
+			}
+			if int(id) >= len(pr.interfaces) {
+				return nil, fmt.Errorf("pcapng packet of unknown interface %d", id)
+			}
+			i := pr.interfaces[id]
+			timestamp := uint64(pr.order.Uint32(body[4:]))<<32 | uint64(pr.order.Uint32(body[8:]))
+			captured := pr.order.Uint32(body[12:])
+			if int(captured) > len(body)-20 {
+				return nil, errors.New("truncated pcapng packet block")
+			}
+			return &Packet{
+				Timestamp: i.timestamp(timestamp),
+				LinkType:  i.linkType,
+				Data:      body[20 : 20+captured],
+			}, nil
+		case pcapngSimplePacketBlock:
+			if len(pr.interfaces) == 0 {
+				return nil, errors.New("pcapng packet of unknown interface 0")
+			}
+			i := pr.interfaces[0]
+			data := body[4:]
+			if length := pr.order.Uint32(body); int(length) < len(data) {
+				data = data[:length]
+			}
+			if i.snapLen != 0 && int(i.snapLen) < len(data) {
+				data = data[:i.snapLen]
+			}
+			return &Packet{LinkType: i.linkType, Data: data}, nil
+		}
+	}
+}
+
+func (pr *PacketReader) readInterface(body []uint8) error {
+	if len(body) < 8 {
+		return errors.New("truncated pcapng interface block")
+	}
+	i := &pcapngInterface{
+		linkType:   LinkType(pr.order.Uint16(body)),
+		snapLen:    pr.order.Uint32(body[4:]),
+		resolution: 1e-6,
+	}
+	options := body[8:]
+	for len(options) >= 4 {
+		code, length := pr.order.Uint16(options), int(pr.order.Uint16(options[2:]))
+		if code == pcapngOptionEnd || len(options) < 4+length {
+			break
+		}
+		if code == pcapngOptionTimestampResolution && length == 1 {
+			if v := options[4]; v&0x80 == 0 {
+				i.resolution = math.Pow(10, -float64(v))
+			} else {
+				i.resolution = math.Pow(2, -float64(v&0x7f))
+			}
+		}
+		options = options[4+(length+3)&^3:]
+	}
+	pr.interfaces = append(pr.interfaces, i)
+	return nil
+}
+
+func (i *pcapngInterface) timestamp(units uint64) time.Time {
+	perSecond := uint64(math.Round(1 / i.resolution))
+	if perSecond == 0 {
+		return time.Unix(int64(float64(units)*i.resolution), 0)
+	}
+	return time.Unix(int64(units/perSecond), int64(float64(units%perSecond)*i.resolution*1e9))
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
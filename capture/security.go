@@ -0,0 +1,115 @@
+package capture
+
+import (
+	"crypto/aes"
+	"errors"
+	"fmt"
+)
+
+// WellKnownTrustCenterLinkKey is "ZigBeeAlliance09", the default trust center link key of Zigbee 3.0
+// and Home Automation devices.
+var WellKnownTrustCenterLinkKey = [16]uint8{'Z', 'i', 'g', 'B', 'e', 'e', 'A', 'l', 'l', 'i', 'a', 'n', 'c', 'e', '0', '9'}
+
+const (
+	keyTransportHashInput = 0x00
+	keyLoadHashInput      = 0x02
+)
+
+// mmoHash is the Matyas-Meyer-Oseas hash over AES-128 of messages shorter than 8 kB.
+func mmoHash(m []uint8) [16]uint8 {
+	bits := len(m) * 8
+	padded := append(append([]uint8{}, m...), 0x80)
+	for len(padded)%aes.BlockSize != aes.BlockSize-2 {
+		padded = append(padded, 0)
+	}
+	padded = append(padded, uint8(bits>>8), uint8(bits))
+	var hash [16]uint8
+	for i := 0; i < len(padded); i += aes.BlockSize {
+		block, _ := aes.NewCipher(hash[:])
+		block.Encrypt(hash[:], padded[i:i+aes.BlockSize])
+		for j := range hash {
+			hash[j] ^= padded[i+j]
+		}
+	}
+	return hash
+}
+
+// hmacMmo is the keyed hash of the specification, HMAC with mmoHash and a 16 byte key.
+func hmacMmo(key [16]uint8, m []uint8) [16]uint8 {
+	inner := make([]uint8, 0, aes.BlockSize+len(m))
+	outer := make([]uint8, 0, 2*aes.BlockSize)
+	for _, k := range key {
+		inner = append(inner, k^0x36)
+		outer = append(outer, k^0x5c)
+	}
+	innerHash := mmoHash(append(inner, m...))
+	return mmoHash(append(outer, innerHash[:]...))
+}
+
+// apsKey derives the key an APS frame is secured with from a link key.
+func apsKey(linkKey [16]uint8, keyId KeyId) [16]uint8 {
+	switch keyId {
+	case KeyIdKeyTransport:
+		return hmacMmo(linkKey, []uint8{keyTransportHashInput})
+	case KeyIdKeyLoad:
+		return hmacMmo(linkKey, []uint8{keyLoadHashInput})
+	}
+	return linkKey
+}
+
+// Decrypt replaces the payload of a frame secured at the APS layer by its plaintext. The key is the
+// network key for frames secured with it and the link key otherwise, from which the key-transport
+// and key-load keys are derived. Source is the extended address of the sender, needed unless the
+// security header carries it.
+func (f *ApsFrame) Decrypt(key [16]uint8, source uint64) error {
+	if f.Security == nil {
+		return errors.New("frame is not secured")
+	}
+	if f.Security.ExtendedNonce() {
+		source = f.Security.Source
+	}
+	if source == 0 {
+		return errors.New("source address of the nonce is unknown")
+	}
+	payload, err := f.decrypt(apsKey(key, f.Security.KeyId()), f.Security, source, f.Payload)
+	if err != nil {
+		return err
+	}
+	f.Payload = payload
+	return nil
+}
+
+type KeyType uint8
+
+const (
+	KeyTypeNetwork         KeyType = 0x01
+	KeyTypeApplicationLink KeyType = 0x03
+	KeyTypeTrustCenterLink KeyType = 0x04
+)
+
+const apsCommandTransportKey = 0x05
+
+// TransportKey is the key carried by an APS transport key command.
+type TransportKey struct {
+	KeyType KeyType
+	Key     [16]uint8
+	// KeySequence is set for network keys.
+	KeySequence uint8
+}
+
+// DecodeTransportKey decodes the decrypted payload of an APS transport key command.
+func DecodeTransportKey(payload []uint8) (*TransportKey, error) {
+	b := &buffer{data: payload}
+	if id := b.uint8(); b.err == nil && id != apsCommandTransportKey {
+		return nil, fmt.Errorf("unexpected aps command 0x%02x", id)
+	}
+	k := &TransportKey{KeyType: KeyType(b.uint8())}
+	copy(k.Key[:], b.bytes(16))
+	if k.KeyType == KeyTypeNetwork {
+		k.KeySequence = b.uint8()
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return k, nil
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+// Destination is the remote endpoint commands are sent to.
+type Destination struct {
+	Addr        string
+	Endpoint    uint8
+	SrcEndpoint uint8
+	ProfileID   uint16
+}
+
+// requester sends its requests through a transactor, which allocates their sequence numbers
+// when it has an allocator.
+type requester struct {
+	transactor *zcl.Transactor
+}
+
+// global sends a global command to the server side of the cluster and waits for the reply. A reply
+// carrying a failing DefaultResponse is returned together with its status and no error.
+func (r *requester) global(ctx context.Context, dst *Destination, clusterId cluster.ClusterId, commandId cluster.ZclCommand, command interface{}) (*zcl.ZclIncomingMessage, error) {
+	f, err := frame.New().
+		FrameType(frame.FrameTypeGlobal).
+		Direction(frame.DirectionClientServer).
+		CommandId(uint8(commandId)).
+		Command(command).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	im, err := r.transactor.Do(ctx, &zcl.ZclOutgoingMessage{
+		DstAddrMode: zcl.AddressModeNwk,
+		DstAddr:     dst.Addr,
+		DstEndpoint: dst.Endpoint,
+		SrcEndpoint: dst.SrcEndpoint,
+		ClusterID:   uint16(clusterId),
+		ProfileID:   dst.ProfileID,
+		Frame:       f,
+	})
+	if im != nil && defaultResponseStatus(im) != cluster.ZclStatusSuccess {
+		return im, nil
+	}
+	return im, err
+}
+
+func defaultResponseStatus(im *zcl.ZclIncomingMessage) cluster.ZclStatus {
+	if cmd, ok := im.Data.Command.(*cluster.DefaultResponseCommand); ok {
+		return cmd.Status
+	}
+	return cluster.ZclStatusSuccess
+}
@@ -0,0 +1,258 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+const defaultPageSize = 16
+
+type AttributeCapability struct {
+	AttributeId uint16              `json:"attributeId"`
+	Name        string              `json:"name,omitempty"`
+	DataType    cluster.ZclDataType `json:"dataType"`
+	// Access is only known when the device supports DiscoverAttributesExtended.
+	Access cluster.Access `json:"access,omitempty"`
+	// Known is false for attributes missing from the cluster library, which are usually
+	// manufacturer specific.
+	Known bool `json:"known"`
+	// TypeMismatch is set when the device reports another data type than the library describes.
+	TypeMismatch bool `json:"typeMismatch,omitempty"`
+}
+
+type CommandCapability struct {
+	CommandId uint8  `json:"commandId"`
+	Name      string `json:"name,omitempty"`
+	Known     bool   `json:"known"`
+}
+
+// ClusterCapability is what a device reported about one of its server clusters. A status other
+// than success means the device refused the corresponding discovery.
+type ClusterCapability struct {
+	ClusterId               cluster.ClusterId      `json:"clusterId"`
+	Name                    string                 `json:"name,omitempty"`
+	Known                   bool                   `json:"known"`
+	Extended                bool                   `json:"extended"`
+	AttributesStatus        cluster.ZclStatus      `json:"attributesStatus"`
+	Attributes              []*AttributeCapability `json:"attributes"`
+	CommandsReceivedStatus  cluster.ZclStatus      `json:"commandsReceivedStatus"`
+	CommandsReceived        []*CommandCapability   `json:"commandsReceived"`
+	CommandsGeneratedStatus cluster.ZclStatus      `json:"commandsGeneratedStatus"`
+	CommandsGenerated       []*CommandCapability   `json:"commandsGenerated"`
+}
+
+type CapabilityReport struct {
+	Addr     string               `json:"addr"`
+	Endpoint uint8                `json:"endpoint"`
+	Clusters []*ClusterCapability `json:"clusters"`
+}
+
+// UnknownAttributes returns the attributes the library doesn't know about by cluster.
+func (r *CapabilityReport) UnknownAttributes() map[cluster.ClusterId][]*AttributeCapability {
+	unknown := map[cluster.ClusterId][]*AttributeCapability{}
+	for _, c := range r.Clusters {
+		for _, a := range c.Attributes {
+			if !a.Known {
+				unknown[c.ClusterId] = append(unknown[c.ClusterId], a)
+			}
+		}
+	}
+	return unknown
+}
+
+// Crawler discovers the attributes and commands of every cluster on an endpoint, paging until
+// the device reports the discovery as complete.
+type Crawler struct {
+	requester
+	library  *cluster.ClusterLibrary
+	pageSize uint8
+}
+
+func NewCrawler(transactor *zcl.Transactor, library *cluster.ClusterLibrary) *Crawler {
+	return &Crawler{requester: requester{transactor: transactor}, library: library, pageSize: defaultPageSize}
+}
+
+func (cr *Crawler) PageSize(pageSize uint8) *Crawler {
+	cr.pageSize = pageSize
+	return cr
+}
+
+// Crawl discovers the given server clusters of the destination endpoint, usually the input
+// clusters of its simple descriptor.
+func (cr *Crawler) Crawl(ctx context.Context, dst *Destination, clusterIds []cluster.ClusterId) (*CapabilityReport, error) {
+	report := &CapabilityReport{Addr: dst.Addr, Endpoint: dst.Endpoint, Clusters: []*ClusterCapability{}}
+	for _, clusterId := range clusterIds {
+		capability, err := cr.CrawlCluster(ctx, dst, clusterId)
+		if err != nil {
+			return nil, err
+		}
+		report.Clusters = append(report.Clusters, capability)
+	}
+	return report, nil
+}
+
+func (cr *Crawler) CrawlCluster(ctx context.Context, dst *Destination, clusterId cluster.ClusterId) (*ClusterCapability, error) {
+	descriptor, known := cr.library.Clusters()[clusterId]
+	capability := &ClusterCapability{ClusterId: clusterId, Known: known}
+	if known {
+		capability.Name = descriptor.Name
+	}
+	var err error
+	if capability.AttributesStatus, err = cr.discoverAttributes(ctx, dst, capability, descriptor); err != nil {
+		return nil, err
+	}
+	if capability.CommandsReceivedStatus, capability.CommandsReceived, err = cr.discoverCommands(ctx, dst, descriptor, clusterId, true); err != nil {
+		return nil, err
+	}
+	if capability.CommandsGeneratedStatus, capability.CommandsGenerated, err = cr.discoverCommands(ctx, dst, descriptor, clusterId, false); err != nil {
+		return nil, err
+	}
+	return capability, nil
+}
+
+// discoverAttributes prefers DiscoverAttributesExtended and falls back to DiscoverAttributes
+// for devices older than ZCL revision 6.
+func (cr *Crawler) discoverAttributes(ctx context.Context, dst *Destination, capability *ClusterCapability, descriptor *cluster.Cluster) (cluster.ZclStatus, error) {
+	capability.Attributes = []*AttributeCapability{}
+	capability.Extended = true
+	status, err := cr.page(ctx, dst, capability, descriptor, true)
+	if err != nil || status == cluster.ZclStatusSuccess || len(capability.Attributes) > 0 {
+		return status, err
+	}
+	capability.Extended = false
+	return cr.page(ctx, dst, capability, descriptor, false)
+}
+
+func (cr *Crawler) page(ctx context.Context, dst *Destination, capability *ClusterCapability, descriptor *cluster.Cluster, extended bool) (cluster.ZclStatus, error) {
+	start := uint16(0)
+	for {
+		var command interface{} = &cluster.DiscoverAttributesCommand{StartAttributeID: start, MaximumAttributeIdentifiers: cr.pageSize}
+		commandId := cluster.ZclCommandDiscoverAttributes
+		if extended {
+			command = &cluster.DiscoverAttributesExtendedCommand{StartAttributeID: start, MaximumAttributeIdentifiers: cr.pageSize}
+			commandId = cluster.ZclCommandDiscoverAttributesExtended
+		}
+		im, err := cr.global(ctx, dst, capability.ClusterId, commandId, command)
+		if err != nil {
+			return 0, err
+		}
+		if status := defaultResponseStatus(im); status != cluster.ZclStatusSuccess {
+			return status, nil
+		}
+		var complete uint8
+		last := -1
+		switch response := im.Data.Command.(type) {
+		case *cluster.DiscoverAttributesResponse:
+			complete = response.DiscoveryComplete
+			for _, information := range response.AttributeInformations {
+				capability.Attributes = append(capability.Attributes, attributeCapability(descriptor, information.AttributeID, information.AttributeDataType))
+				last = maxId(last, int(information.AttributeID))
+			}
+		case *cluster.DiscoverAttributesExtendedResponse:
+			complete = response.DiscoveryComplete
+			for _, information := range response.ExtendedAttributeInformations {
+				a := attributeCapability(descriptor, information.AttributeID, information.AttributeDataType)
+				a.Access = access(information.AttributeAccessControl)
+				capability.Attributes = append(capability.Attributes, a)
+				last = maxId(last, int(information.AttributeID))
+			}
+		default:
+			return 0, fmt.Errorf("unexpected response %s", im.Data.CommandName)
+		}
+		// A page without identifiers or ending on the last one can't be followed by another.
+		if complete == 1 || last < int(start) || last == 0xffff {
+			return cluster.ZclStatusSuccess, nil
+		}
+		start = uint16(last + 1)
+	}
+}
+
+func (cr *Crawler) discoverCommands(ctx context.Context, dst *Destination, descriptor *cluster.Cluster, clusterId cluster.ClusterId, received bool) (cluster.ZclStatus, []*CommandCapability, error) {
+	commands := []*CommandCapability{}
+	var known map[uint8]*cluster.CommandDescriptor
+	if descriptor != nil && descriptor.CommandDescriptors != nil {
+		known = descriptor.CommandDescriptors.Generated
+		if received {
+			known = descriptor.CommandDescriptors.Received
+		}
+	}
+	start := uint8(0)
+	for {
+		var command interface{} = &cluster.DiscoverCommandsGeneratedCommand{StartCommandID: start, MaximumCommandIdentifiers: cr.pageSize}
+		commandId := cluster.ZclCommandDiscoverCommandsGenerated
+		if received {
+			command = &cluster.DiscoverCommandsReceivedCommand{StartCommandID: start, MaximumCommandIdentifiers: cr.pageSize}
+			commandId = cluster.ZclCommandDiscoverCommandsReceived
+		}
+		im, err := cr.global(ctx, dst, clusterId, commandId, command)
+		if err != nil {
+			return 0, nil, err
+		}
+		if status := defaultResponseStatus(im); status != cluster.ZclStatusSuccess {
+			return status, commands, nil
+		}
+		var complete uint8
+		var ids []uint8
+		switch response := im.Data.Command.(type) {
+		case *cluster.DiscoverCommandsReceivedResponse:
+			complete, ids = response.DiscoveryComplete, response.CommandIdentifiers
+		case *cluster.DiscoverCommandsGeneratedResponse:
+			complete, ids = response.DiscoveryComplete, response.CommandIdentifiers
+		default:
+			return 0, nil, fmt.Errorf("unexpected response %s", im.Data.CommandName)
+		}
+		last := -1
+		for _, id := range ids {
+			c := &CommandCapability{CommandId: id}
+			if cd, ok := known[id]; ok {
+				c.Name, c.Known = cd.Name, true
+			}
+			commands = append(commands, c)
+			last = maxId(last, int(id))
+		}
+		if complete == 1 || last < int(start) || last == 0xff {
+			return cluster.ZclStatusSuccess, commands, nil
+		}
+		start = uint8(last + 1)
+	}
+}
+
+func attributeCapability(descriptor *cluster.Cluster, attributeId uint16, dataType cluster.ZclDataType) *AttributeCapability {
+	a := &AttributeCapability{AttributeId: attributeId, DataType: dataType}
+	if descriptor == nil {
+		return a
+	}
+	if ad, ok := descriptor.AttributeDescriptors[attributeId]; ok {
+		a.Name = ad.Name
+		a.Known = true
+		a.TypeMismatch = ad.Type != dataType
+	}
+	return a
+}
+
+func access(control *cluster.AttributeAccessControl) cluster.Access {
+	var a cluster.Access
+	if control == nil {
+		return a
+	}
+	if control.Readable == 1 {
+		a |= cluster.Read
+	}
+	if control.Writeable == 1 {
+		a |= cluster.Write
+	}
+	if control.Reportable == 1 {
+		a |= cluster.Reportable
+	}
+	return a
+}
+
+func maxId(a, b int) int {
+	if b > a {
+		return b
+	}
+	return a
+}
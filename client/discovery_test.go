@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/server"
+	. "gopkg.in/check.v1"
+)
+
+type responderFunc func(im *zcl.ZclIncomingMessage) *zcl.ZclOutgoingMessage
+
+func (f responderFunc) Respond(im *zcl.ZclIncomingMessage) *zcl.ZclOutgoingMessage {
+	return f(im)
+}
+
+// replyWith answers the request with the given global command.
+func replyWith(im *zcl.ZclIncomingMessage, commandId cluster.ZclCommand, command interface{}) *zcl.ZclOutgoingMessage {
+	f, _ := frame.New().
+		IdGenerator(func() uint8 { return im.Data.TransactionSequenceNumber }).
+		FrameType(frame.FrameTypeGlobal).
+		Direction(frame.DirectionServerClient).
+		DisableDefaultResponse(true).
+		CommandId(uint8(commandId)).
+		Command(command).
+		Build()
+	return &zcl.ZclOutgoingMessage{DstAddr: im.SrcAddr, DstEndpoint: im.SrcEndpoint, SrcEndpoint: im.DstEndpoint, ClusterID: im.ClusterID, Frame: f}
+}
+
+type CrawlerSuite struct {
+	library *cluster.ClusterLibrary
+	store   *server.AttributeStore
+}
+
+var _ = Suite(&CrawlerSuite{})
+
+func (s *CrawlerSuite) SetUpTest(c *C) {
+	s.library = zcl.New().ClusterLibrary()
+	s.store = server.NewAttributeStore(s.library)
+	c.Assert(s.store.AddCluster(1, cluster.Basic), IsNil)
+	c.Assert(s.store.AddCluster(1, cluster.OnOff), IsNil)
+	c.Assert(s.store.Set(1, cluster.Basic, 0x0000, uint64(2)), IsNil)
+	c.Assert(s.store.Set(1, cluster.Basic, 0x0004, "IKEA"), IsNil)
+	c.Assert(s.store.Set(1, cluster.Basic, 0x0005, "TRADFRI"), IsNil)
+	c.Assert(s.store.Set(1, cluster.Basic, 0x0010, "hall"), IsNil)
+	c.Assert(s.store.Set(1, cluster.OnOff, 0x0000, false), IsNil)
+}
+
+func (s *CrawlerSuite) crawler(device responder) (*Crawler, *deviceTransport) {
+	transport := newDeviceTransport(device)
+	return NewCrawler(transport.transactor, s.library), transport
+}
+
+func (s *CrawlerSuite) TestCrawl(c *C) {
+	crawler, transport := s.crawler(s.store)
+	report, err := crawler.PageSize(2).Crawl(context.Background(), destination, []cluster.ClusterId{cluster.Basic, cluster.OnOff})
+	c.Assert(err, IsNil)
+	c.Assert(report.Clusters, HasLen, 2)
+
+	basic := report.Clusters[0]
+	c.Assert(basic.Name, Equals, "Basic")
+	c.Assert(basic.Extended, Equals, true)
+	c.Assert(basic.Attributes, HasLen, 4)
+	c.Assert(basic.Attributes[3], DeepEquals, &AttributeCapability{
+		AttributeId: 0x0010, Name: "LocationDescription", DataType: cluster.ZclDataTypeCharStr, Access: cluster.Read | cluster.Write, Known: true,
+	})
+	c.Assert(basic.CommandsReceived, HasLen, 1)
+	c.Assert(basic.CommandsReceived[0], DeepEquals, &CommandCapability{CommandId: 0x00, Name: "ResetToFactoryDefaults", Known: true})
+
+	onOff := report.Clusters[1]
+	c.Assert(onOff.CommandsReceived, HasLen, 6)
+	c.Assert(onOff.CommandsGeneratedStatus, Equals, cluster.ZclStatusSuccess)
+	c.Assert(onOff.CommandsGenerated, HasLen, 0)
+	c.Assert(report.UnknownAttributes(), HasLen, 0)
+	// Basic: 2 attribute pages, 1 page of received and 1 of generated commands.
+	// OnOff: 1 attribute page, 3 pages of received and 1 of generated commands.
+	c.Assert(transport.sent, Equals, 9)
+}
+
+func (s *CrawlerSuite) TestFallbackToDiscoverAttributes(c *C) {
+	crawler, _ := s.crawler(responderFunc(func(im *zcl.ZclIncomingMessage) *zcl.ZclOutgoingMessage {
+		switch im.Data.Command.(type) {
+		case *cluster.DiscoverAttributesExtendedCommand, *cluster.DiscoverCommandsReceivedCommand, *cluster.DiscoverCommandsGeneratedCommand:
+			return zcl.DefaultResponse(im, cluster.ZclStatusUnsupGeneralCommand, false)
+		}
+		return s.store.Respond(im)
+	}))
+	capability, err := crawler.CrawlCluster(context.Background(), destination, cluster.OnOff)
+	c.Assert(err, IsNil)
+	c.Assert(capability.Extended, Equals, false)
+	c.Assert(capability.AttributesStatus, Equals, cluster.ZclStatusSuccess)
+	c.Assert(capability.Attributes, DeepEquals, []*AttributeCapability{
+		{AttributeId: 0x0000, Name: "OnOff", DataType: cluster.ZclDataTypeBoolean, Known: true},
+	})
+	c.Assert(capability.CommandsReceivedStatus, Equals, cluster.ZclStatusUnsupGeneralCommand)
+}
+
+func (s *CrawlerSuite) TestUnknownAttributes(c *C) {
+	starts := []uint16{}
+	crawler, _ := s.crawler(responderFunc(func(im *zcl.ZclIncomingMessage) *zcl.ZclOutgoingMessage {
+		cmd, ok := im.Data.Command.(*cluster.DiscoverAttributesExtendedCommand)
+		if !ok {
+			return zcl.DefaultResponse(im, cluster.ZclStatusUnsupGeneralCommand, false)
+		}
+		starts = append(starts, cmd.StartAttributeID)
+		if cmd.StartAttributeID == 0 {
+			return replyWith(im, cluster.ZclCommandDiscoverAttributesExtendedResponse, &cluster.DiscoverAttributesExtendedResponse{
+				ExtendedAttributeInformations: []*cluster.ExtendedAttributeInformation{
+					{AttributeID: 0x0000, AttributeDataType: cluster.ZclDataTypeUint16, AttributeAccessControl: &cluster.AttributeAccessControl{Readable: 1}},
+				},
+			})
+		}
+		return replyWith(im, cluster.ZclCommandDiscoverAttributesExtendedResponse, &cluster.DiscoverAttributesExtendedResponse{
+			DiscoveryComplete: 1,
+			ExtendedAttributeInformations: []*cluster.ExtendedAttributeInformation{
+				{AttributeID: 0xff01, AttributeDataType: cluster.ZclDataTypeCharStr, AttributeAccessControl: &cluster.AttributeAccessControl{Readable: 1}},
+			},
+		})
+	}))
+	report, err := crawler.Crawl(context.Background(), destination, []cluster.ClusterId{cluster.Basic})
+	c.Assert(err, IsNil)
+	c.Assert(starts, DeepEquals, []uint16{0x0000, 0x0001})
+	c.Assert(report.Clusters[0].Attributes[0].TypeMismatch, Equals, true)
+	c.Assert(report.UnknownAttributes(), DeepEquals, map[cluster.ClusterId][]*AttributeCapability{
+		cluster.Basic: {{AttributeId: 0xff01, DataType: cluster.ZclDataTypeCharStr, Access: cluster.Read}},
+	})
+}
@@ -0,0 +1,221 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+const (
+	// defaultMaxPayload is what fits an unfragmented APS frame after the ZCL header.
+	defaultMaxPayload  = 79
+	defaultRetries     = 3
+	defaultStepTimeout = 5 * time.Second
+)
+
+var basicAttributes = []uint16{0x0004, 0x0005, 0x4000, 0x0007}
+
+var powerConfigurationAttributes = []uint16{0x0020, 0x0021}
+
+// DeviceProfile is the outcome of an interview. Battery values are in the units of the
+// PowerConfiguration cluster: 100mV and half percent.
+type DeviceProfile struct {
+	Addr                       string               `json:"addr"`
+	Endpoint                   uint8                `json:"endpoint"`
+	ManufacturerName           string               `json:"manufacturerName,omitempty"`
+	ModelIdentifier            string               `json:"modelIdentifier,omitempty"`
+	SWBuildID                  string               `json:"swBuildId,omitempty"`
+	PowerSource                *uint8               `json:"powerSource,omitempty"`
+	BatteryVoltage             *uint8               `json:"batteryVoltage,omitempty"`
+	BatteryPercentageRemaining *uint8               `json:"batteryPercentageRemaining,omitempty"`
+	Clusters                   []*ClusterCapability `json:"clusters"`
+}
+
+type Interviewer struct {
+	requester
+	library     *cluster.ClusterLibrary
+	crawler     *Crawler
+	maxPayload  int
+	retries     int
+	stepTimeout time.Duration
+}
+
+func NewInterviewer(transactor *zcl.Transactor, library *cluster.ClusterLibrary) *Interviewer {
+	return &Interviewer{
+		requester:   requester{transactor: transactor},
+		library:     library,
+		crawler:     NewCrawler(transactor, library),
+		maxPayload:  defaultMaxPayload,
+		retries:     defaultRetries,
+		stepTimeout: defaultStepTimeout,
+	}
+}
+
+// MaxPayload limits the expected size of a ReadAttributes response. Attributes are read in as
+// many requests as needed to stay below it.
+func (i *Interviewer) MaxPayload(maxPayload int) *Interviewer {
+	i.maxPayload = maxPayload
+	return i
+}
+
+// Retries is how many times a step is attempted before the interview fails.
+func (i *Interviewer) Retries(retries int) *Interviewer {
+	i.retries = retries
+	return i
+}
+
+func (i *Interviewer) StepTimeout(stepTimeout time.Duration) *Interviewer {
+	i.stepTimeout = stepTimeout
+	return i
+}
+
+// Interview reads the identity of the device, discovers the given server clusters of the
+// endpoint and reads the battery state if the endpoint has a PowerConfiguration cluster.
+func (i *Interviewer) Interview(ctx context.Context, dst *Destination, clusterIds []cluster.ClusterId) (*DeviceProfile, error) {
+	profile := &DeviceProfile{Addr: dst.Addr, Endpoint: dst.Endpoint, Clusters: []*ClusterCapability{}}
+
+	basic, err := i.ReadAttributes(ctx, dst, cluster.Basic, basicAttributes)
+	if err != nil {
+		return nil, fmt.Errorf("reading Basic attributes: %v", err)
+	}
+	profile.ManufacturerName, _ = basic[0x0004].(string)
+	profile.ModelIdentifier, _ = basic[0x0005].(string)
+	profile.SWBuildID, _ = basic[0x4000].(string)
+	profile.PowerSource = uint8Value(basic[0x0007])
+
+	for _, clusterId := range clusterIds {
+		var capability *ClusterCapability
+		err := i.step(ctx, func(ctx context.Context) (err error) {
+			capability, err = i.crawler.CrawlCluster(ctx, dst, clusterId)
+			return
+		})
+		if err != nil {
+			return nil, fmt.Errorf("discovering cluster %d: %v", clusterId, err)
+		}
+		profile.Clusters = append(profile.Clusters, capability)
+
+		if clusterId == cluster.PowerConfiguration {
+			power, err := i.ReadAttributes(ctx, dst, cluster.PowerConfiguration, powerConfigurationAttributes)
+			if err != nil {
+				return nil, fmt.Errorf("reading PowerConfiguration attributes: %v", err)
+			}
+			profile.BatteryVoltage = uint8Value(power[0x0020])
+			profile.BatteryPercentageRemaining = uint8Value(power[0x0021])
+		}
+	}
+	return profile, nil
+}
+
+// ReadAttributes reads the attributes in chunks fitting the maximum payload and returns the
+// values of those read successfully.
+func (i *Interviewer) ReadAttributes(ctx context.Context, dst *Destination, clusterId cluster.ClusterId, attributeIds []uint16) (map[uint16]interface{}, error) {
+	values := map[uint16]interface{}{}
+	for _, chunk := range i.chunks(clusterId, attributeIds) {
+		var im *zcl.ZclIncomingMessage
+		err := i.step(ctx, func(ctx context.Context) (err error) {
+			im, err = i.global(ctx, dst, clusterId, cluster.ZclCommandReadAttributes, &cluster.ReadAttributesCommand{AttributeIDs: chunk})
+			return
+		})
+		if err != nil {
+			return nil, err
+		}
+		if defaultResponseStatus(im) != cluster.ZclStatusSuccess {
+			continue
+		}
+		response, ok := im.Data.Command.(*cluster.ReadAttributesResponse)
+		if !ok {
+			return nil, fmt.Errorf("unexpected response %s", im.Data.CommandName)
+		}
+		for _, status := range response.ReadAttributeStatuses {
+			if status.Status == cluster.ZclStatusSuccess && status.Attribute != nil {
+				values[status.AttributeID] = status.Attribute.Value
+			}
+		}
+	}
+	return values, nil
+}
+
+// step runs the function with its own timeout, retrying it while it fails.
+func (i *Interviewer) step(ctx context.Context, f func(ctx context.Context) error) error {
+	attempts := i.retries
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		stepCtx, cancel := context.WithTimeout(ctx, i.stepTimeout)
+		err = f(stepCtx)
+		cancel()
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func (i *Interviewer) chunks(clusterId cluster.ClusterId, attributeIds []uint16) [][]uint16 {
+	var descriptors map[uint16]*cluster.AttributeDescriptor
+	if c, ok := i.library.Clusters()[clusterId]; ok {
+		descriptors = c.AttributeDescriptors
+	}
+	chunks := [][]uint16{}
+	chunk := []uint16{}
+	size := 0
+	for _, id := range attributeIds {
+		// attribute id, status and data type precede the value
+		recordSize := 4 + maxValueSize(descriptors[id])
+		if len(chunk) > 0 && size+recordSize > i.maxPayload {
+			chunks = append(chunks, chunk)
+			chunk, size = []uint16{}, 0
+		}
+		chunk = append(chunk, id)
+		size += recordSize
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// maxValueSize estimates the largest encoded value of an attribute. Character strings of the
+// library are at most 32 characters long.
+func maxValueSize(descriptor *cluster.AttributeDescriptor) int {
+	if descriptor == nil {
+		return 33
+	}
+	t := descriptor.Type
+	switch {
+	case t == cluster.ZclDataTypeBoolean:
+		return 1
+	case t >= cluster.ZclDataTypeData8 && t <= cluster.ZclDataTypeData64:
+		return int(t-cluster.ZclDataTypeData8) + 1
+	case t >= cluster.ZclDataTypeBitmap8 && t <= cluster.ZclDataTypeBitmap64:
+		return int(t-cluster.ZclDataTypeBitmap8) + 1
+	case t >= cluster.ZclDataTypeUint8 && t <= cluster.ZclDataTypeUint64:
+		return int(t-cluster.ZclDataTypeUint8) + 1
+	case t >= cluster.ZclDataTypeInt8 && t <= cluster.ZclDataTypeInt64:
+		return int(t-cluster.ZclDataTypeInt8) + 1
+	case t == cluster.ZclDataTypeEnum8:
+		return 1
+	case t == cluster.ZclDataTypeEnum16, t == cluster.ZclDataTypeClusterId, t == cluster.ZclDataTypeAttrId:
+		return 2
+	case t == cluster.ZclDataTypeIeeeAddr:
+		return 8
+	case t == cluster.ZclDataType_128BitSecKey:
+		return 16
+	case t == cluster.ZclDataTypeOctetStr, t == cluster.ZclDataTypeCharStr:
+		return 33
+	}
+	return 4
+}
+
+func uint8Value(value interface{}) *uint8 {
+	if v, ok := value.(uint64); ok {
+		b := uint8(v)
+		return &b
+	}
+	return nil
+}
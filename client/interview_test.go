@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/server"
+	. "gopkg.in/check.v1"
+)
+
+type InterviewerSuite struct {
+	library *cluster.ClusterLibrary
+	store   *server.AttributeStore
+	reads   [][]uint16
+}
+
+var _ = Suite(&InterviewerSuite{})
+
+func (s *InterviewerSuite) SetUpTest(c *C) {
+	s.library = zcl.New().ClusterLibrary()
+	s.store = server.NewAttributeStore(s.library)
+	s.reads = nil
+	c.Assert(s.store.AddCluster(1, cluster.Basic), IsNil)
+	c.Assert(s.store.AddCluster(1, cluster.PowerConfiguration), IsNil)
+	c.Assert(s.store.Set(1, cluster.Basic, 0x0004, "LUMI"), IsNil)
+	c.Assert(s.store.Set(1, cluster.Basic, 0x0005, "lumi.sensor_magnet"), IsNil)
+	c.Assert(s.store.Set(1, cluster.Basic, 0x0007, uint64(3)), IsNil)
+	c.Assert(s.store.Set(1, cluster.PowerConfiguration, 0x0020, uint64(30)), IsNil)
+}
+
+func (s *InterviewerSuite) recording(im *zcl.ZclIncomingMessage) *zcl.ZclOutgoingMessage {
+	if cmd, ok := im.Data.Command.(*cluster.ReadAttributesCommand); ok {
+		s.reads = append(s.reads, cmd.AttributeIDs)
+	}
+	return s.store.Respond(im)
+}
+
+func (s *InterviewerSuite) TestInterview(c *C) {
+	transport := newDeviceTransport(responderFunc(s.recording))
+	profile, err := NewInterviewer(transport.transactor, s.library).
+		Interview(context.Background(), destination, []cluster.ClusterId{cluster.Basic, cluster.PowerConfiguration})
+	c.Assert(err, IsNil)
+	c.Assert(s.reads, DeepEquals, [][]uint16{{0x0004, 0x0005}, {0x4000, 0x0007}, {0x0020, 0x0021}})
+	c.Assert(profile.ManufacturerName, Equals, "LUMI")
+	c.Assert(profile.ModelIdentifier, Equals, "lumi.sensor_magnet")
+	c.Assert(profile.SWBuildID, Equals, "")
+	c.Assert(*profile.PowerSource, Equals, uint8(3))
+	c.Assert(*profile.BatteryVoltage, Equals, uint8(30))
+	c.Assert(profile.BatteryPercentageRemaining, IsNil)
+	c.Assert(profile.Clusters, HasLen, 2)
+	c.Assert(profile.Clusters[1].Attributes, HasLen, 1)
+
+	data, err := json.Marshal(profile)
+	c.Assert(err, IsNil)
+	restored := &DeviceProfile{}
+	c.Assert(json.Unmarshal(data, restored), IsNil)
+	c.Assert(restored, DeepEquals, profile)
+}
+
+func (s *InterviewerSuite) TestRetries(c *C) {
+	dropped := 0
+	transport := newDeviceTransport(responderFunc(func(im *zcl.ZclIncomingMessage) *zcl.ZclOutgoingMessage {
+		if dropped < 2 {
+			dropped++
+			return nil
+		}
+		return s.recording(im)
+	}))
+	interviewer := NewInterviewer(transport.transactor, s.library).StepTimeout(20 * time.Millisecond).MaxPayload(200)
+	values, err := interviewer.ReadAttributes(context.Background(), destination, cluster.Basic, basicAttributes)
+	c.Assert(err, IsNil)
+	c.Assert(values[0x0004], Equals, "LUMI")
+	c.Assert(s.reads, HasLen, 1)
+
+	dropped = 0
+	_, err = interviewer.Retries(2).ReadAttributes(context.Background(), destination, cluster.Basic, basicAttributes)
+	c.Assert(err, Equals, context.DeadlineExceeded)
+}
@@ -0,0 +1,301 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+// ReportingTarget is the desired reporting configuration of an attribute. Change is the
+// reportable change of analog attributes and is ignored for discrete ones.
+type ReportingTarget struct {
+	ClusterId   cluster.ClusterId
+	AttributeId uint16
+	Min         uint16
+	Max         uint16
+	Change      interface{}
+}
+
+// ReportingDiff describes an attribute whose reporting configuration on the device differs from
+// the desired one. Actual is nil when the configuration couldn't be read back, in which case
+// Status tells why.
+type ReportingDiff struct {
+	Desired *ReportingTarget
+	Actual  *ReportingTarget
+	Status  cluster.ZclStatus
+}
+
+type ReportingManager struct {
+	requester
+	library *cluster.ClusterLibrary
+}
+
+func NewReportingManager(transactor *zcl.Transactor, library *cluster.ClusterLibrary) *ReportingManager {
+	return &ReportingManager{requester: requester{transactor: transactor}, library: library}
+}
+
+// Configure sends the targets to the device, reads the configuration back and returns the
+// attributes whose configuration doesn't match. An empty diff means every target is in place.
+func (m *ReportingManager) Configure(ctx context.Context, dst *Destination, targets []*ReportingTarget) ([]*ReportingDiff, error) {
+	clusterIds, byCluster := groupTargets(targets)
+	diffs := []*ReportingDiff{}
+	for _, clusterId := range clusterIds {
+		clusterTargets := byCluster[clusterId]
+		failed, err := m.configure(ctx, dst, clusterId, clusterTargets)
+		if err != nil {
+			return nil, err
+		}
+		verify := []*ReportingTarget{}
+		for _, target := range clusterTargets {
+			if status, ok := failed[target.AttributeId]; ok {
+				diffs = append(diffs, &ReportingDiff{Desired: target, Status: status})
+			} else {
+				verify = append(verify, target)
+			}
+		}
+		if len(verify) == 0 {
+			continue
+		}
+		verified, err := m.Verify(ctx, dst, verify)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, verified...)
+	}
+	return diffs, nil
+}
+
+// Verify reads the reporting configuration of the targets from the device and returns the
+// attributes whose configuration doesn't match.
+func (m *ReportingManager) Verify(ctx context.Context, dst *Destination, targets []*ReportingTarget) ([]*ReportingDiff, error) {
+	clusterIds, byCluster := groupTargets(targets)
+	diffs := []*ReportingDiff{}
+	for _, clusterId := range clusterIds {
+		clusterTargets := byCluster[clusterId]
+		actual, err := m.read(ctx, dst, clusterId, clusterTargets)
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range clusterTargets {
+			record, ok := actual[target.AttributeId]
+			switch {
+			case !ok:
+				diffs = append(diffs, &ReportingDiff{Desired: target, Status: cluster.ZclStatusNotFound})
+			case record.Status != cluster.ZclStatusSuccess:
+				diffs = append(diffs, &ReportingDiff{Desired: target, Status: record.Status})
+			default:
+				current := &ReportingTarget{
+					ClusterId:   clusterId,
+					AttributeId: target.AttributeId,
+					Min:         record.MinimumReportingInterval,
+					Max:         record.MaximumReportingInterval,
+				}
+				if record.ReportableChange != nil {
+					current.Change = record.ReportableChange.Value
+				}
+				if !m.matches(target, current, record.AttributeDataType) {
+					diffs = append(diffs, &ReportingDiff{Desired: target, Actual: current, Status: cluster.ZclStatusSuccess})
+				}
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// configure returns the status of every attribute the device refused to configure.
+func (m *ReportingManager) configure(ctx context.Context, dst *Destination, clusterId cluster.ClusterId, targets []*ReportingTarget) (map[uint16]cluster.ZclStatus, error) {
+	records := []*cluster.AttributeReportingConfigurationRecord{}
+	for _, target := range targets {
+		dataType, err := m.attributeType(clusterId, target.AttributeId)
+		if err != nil {
+			return nil, err
+		}
+		record := &cluster.AttributeReportingConfigurationRecord{
+			Direction:                cluster.ReportDirectionAttributeReported,
+			AttributeID:              target.AttributeId,
+			AttributeDataType:        dataType,
+			MinimumReportingInterval: target.Min,
+			MaximumReportingInterval: target.Max,
+		}
+		if dataType.Analog() {
+			change, err := reportableChange(dataType, target.Change)
+			if err != nil {
+				return nil, err
+			}
+			record.ReportableChange = &cluster.Attribute{DataType: dataType, Value: change}
+		}
+		records = append(records, record)
+	}
+	im, err := m.global(ctx, dst, clusterId, cluster.ZclCommandConfigureReporting, &cluster.ConfigureReportingCommand{AttributeReportingConfigurationRecords: records})
+	if err != nil {
+		return nil, err
+	}
+	failed := map[uint16]cluster.ZclStatus{}
+	if status := defaultResponseStatus(im); status != cluster.ZclStatusSuccess {
+		for _, target := range targets {
+			failed[target.AttributeId] = status
+		}
+		return failed, nil
+	}
+	response, ok := im.Data.Command.(*cluster.ConfigureReportingResponse)
+	if !ok {
+		return nil, fmt.Errorf("unexpected response %s", im.Data.CommandName)
+	}
+	// Only failures are listed. A single Success record stands for all attributes succeeding.
+	for _, record := range response.AttributeStatusRecords {
+		if record.Status != cluster.ZclStatusSuccess && record.Direction == cluster.ReportDirectionAttributeReported {
+			failed[record.AttributeID] = record.Status
+		}
+	}
+	return failed, nil
+}
+
+func (m *ReportingManager) read(ctx context.Context, dst *Destination, clusterId cluster.ClusterId, targets []*ReportingTarget) (map[uint16]*cluster.AttributeReportingConfigurationResponseRecord, error) {
+	records := []*cluster.AttributeRecord{}
+	for _, target := range targets {
+		records = append(records, &cluster.AttributeRecord{Direction: cluster.ReportDirectionAttributeReported, AttributeID: target.AttributeId})
+	}
+	im, err := m.global(ctx, dst, clusterId, cluster.ZclCommandReadReportingConfiguration, &cluster.ReadReportingConfigurationCommand{AttributeRecords: records})
+	if err != nil {
+		return nil, err
+	}
+	actual := map[uint16]*cluster.AttributeReportingConfigurationResponseRecord{}
+	if status := defaultResponseStatus(im); status != cluster.ZclStatusSuccess {
+		for _, target := range targets {
+			actual[target.AttributeId] = &cluster.AttributeReportingConfigurationResponseRecord{AttributeID: target.AttributeId, Status: status}
+		}
+		return actual, nil
+	}
+	response, ok := im.Data.Command.(*cluster.ReadReportingConfigurationResponse)
+	if !ok {
+		return nil, fmt.Errorf("unexpected response %s", im.Data.CommandName)
+	}
+	for _, record := range response.AttributeReportingConfigurationResponseRecords {
+		if record.Direction == cluster.ReportDirectionAttributeReported {
+			actual[record.AttributeID] = record
+		}
+	}
+	return actual, nil
+}
+
+func (m *ReportingManager) matches(desired *ReportingTarget, actual *ReportingTarget, dataType cluster.ZclDataType) bool {
+	if desired.Min != actual.Min || desired.Max != actual.Max {
+		return false
+	}
+	if !dataType.Analog() {
+		return true
+	}
+	change, err := reportableChange(dataType, desired.Change)
+	return err == nil && reflect.DeepEqual(change, actual.Change)
+}
+
+func (m *ReportingManager) attributeType(clusterId cluster.ClusterId, attributeId uint16) (cluster.ZclDataType, error) {
+	c, ok := m.library.Clusters()[clusterId]
+	if !ok {
+		return 0, fmt.Errorf("unknown cluster %d", clusterId)
+	}
+	descriptor, ok := c.AttributeDescriptors[attributeId]
+	if !ok {
+		return 0, fmt.Errorf("cluster %s has no attribute %d", c.Name, attributeId)
+	}
+	return descriptor.Type, nil
+}
+
+// reportableChange converts a Go number to the value representation of the data type. Time of
+// day and date changes are given as *cluster.TimeOfDay and *cluster.Date.
+func reportableChange(dataType cluster.ZclDataType, change interface{}) (interface{}, error) {
+	switch dataType {
+	case cluster.ZclDataTypeTod:
+		if change == nil {
+			return &cluster.TimeOfDay{}, nil
+		}
+		if !cluster.ValidAttributeValue(dataType, change) {
+			return nil, fmt.Errorf("reportable change %v isn't a time of day", change)
+		}
+		return change, nil
+	case cluster.ZclDataTypeDate:
+		if change == nil {
+			return &cluster.Date{}, nil
+		}
+		if !cluster.ValidAttributeValue(dataType, change) {
+			return nil, fmt.Errorf("reportable change %v isn't a date", change)
+		}
+		return change, nil
+	}
+	v := reflect.ValueOf(change)
+	var u uint64
+	var f float64
+	var isFloat bool
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			return nil, fmt.Errorf("reportable change %v is negative", change)
+		}
+		u = uint64(v.Int())
+		f = float64(u)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u = v.Uint()
+		f = float64(u)
+	case reflect.Float32, reflect.Float64:
+		f, isFloat = v.Float(), true
+		if math.IsNaN(f) || f < 0 {
+			return nil, fmt.Errorf("reportable change %v is negative or not a number", change)
+		}
+	case reflect.Invalid:
+	default:
+		return nil, fmt.Errorf("reportable change %v isn't a number", change)
+	}
+	switch dataType {
+	case cluster.ZclDataTypeSemiPrec:
+		if f > 65504 {
+			return nil, fmt.Errorf("reportable change %v doesn't fit data type %d", change, dataType)
+		}
+		return float32(f), nil
+	case cluster.ZclDataTypeSinglePrec:
+		if f > math.MaxFloat32 {
+			return nil, fmt.Errorf("reportable change %v doesn't fit data type %d", change, dataType)
+		}
+		return float32(f), nil
+	case cluster.ZclDataTypeDoublePrec:
+		return f, nil
+	}
+	if isFloat {
+		return nil, fmt.Errorf("reportable change %v isn't an integer", change)
+	}
+	switch {
+	case dataType >= cluster.ZclDataTypeUint8 && dataType <= cluster.ZclDataTypeUint64:
+		if bits := 8 * uint(dataType-cluster.ZclDataTypeUint8+1); bits < 64 && u>>bits != 0 {
+			return nil, fmt.Errorf("reportable change %v doesn't fit data type %d", change, dataType)
+		}
+		return u, nil
+	case dataType >= cluster.ZclDataTypeInt8 && dataType <= cluster.ZclDataTypeInt64:
+		if bits := 8 * uint(dataType-cluster.ZclDataTypeInt8+1); u>>(bits-1) != 0 {
+			return nil, fmt.Errorf("reportable change %v doesn't fit data type %d", change, dataType)
+		}
+		return int64(u), nil
+	case dataType == cluster.ZclDataTypeUtc:
+		if u > math.MaxUint32 {
+			return nil, fmt.Errorf("reportable change %v doesn't fit data type %d", change, dataType)
+		}
+		return uint32(u), nil
+	}
+	return nil, fmt.Errorf("reportable change of data type %d isn't supported", dataType)
+}
+
+func groupTargets(targets []*ReportingTarget) ([]cluster.ClusterId, map[cluster.ClusterId][]*ReportingTarget) {
+	byCluster := map[cluster.ClusterId][]*ReportingTarget{}
+	clusterIds := []cluster.ClusterId{}
+	for _, target := range targets {
+		if _, ok := byCluster[target.ClusterId]; !ok {
+			clusterIds = append(clusterIds, target.ClusterId)
+		}
+		byCluster[target.ClusterId] = append(byCluster[target.ClusterId], target)
+	}
+	sort.Slice(clusterIds, func(i, j int) bool { return clusterIds[i] < clusterIds[j] })
+	return clusterIds, byCluster
+}
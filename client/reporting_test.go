@@ -0,0 +1,199 @@
+package client
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/server"
+	. "gopkg.in/check.v1"
+)
+
+func TestClient(t *testing.T) { TestingT(t) }
+
+type responder interface {
+	Respond(im *zcl.ZclIncomingMessage) *zcl.ZclOutgoingMessage
+}
+
+// deviceTransport answers every request with the reply of an in-process server.
+type deviceTransport struct {
+	zcl        *zcl.Zcl
+	transactor *zcl.Transactor
+	device     responder
+	sent       int
+}
+
+func (d *deviceTransport) Send(ctx context.Context, m *zcl.ApplicationMessage) error {
+	d.sent++
+	im, err := d.zcl.ToZclIncomingMessage(&zcl.ApplicationMessage{
+		SrcAddr: "0x0000", SrcEndpoint: m.SrcEndpoint, DstEndpoint: m.DstEndpoint, ClusterID: m.ClusterID, ProfileID: m.ProfileID, Data: m.Data,
+	})
+	if err != nil {
+		return err
+	}
+	if reply := d.device.Respond(im); reply != nil {
+		am := reply.ToApplicationMessage()
+		am.SrcAddr = m.DstAddr
+		am.SrcEndpoint = m.DstEndpoint
+		rm, err := d.zcl.ToZclIncomingMessage(am)
+		if err != nil {
+			return err
+		}
+		go d.transactor.Dispatch(rm)
+	}
+	return nil
+}
+
+func newDeviceTransport(device responder) *deviceTransport {
+	z := zcl.New()
+	d := &deviceTransport{zcl: z, device: device}
+	d.transactor = zcl.NewTransactor(d, z.ClusterLibrary(), time.Second)
+	return d
+}
+
+var destination = &Destination{Addr: "0x1a2b", Endpoint: 1, SrcEndpoint: 1, ProfileID: 0x0104}
+
+type ReportingManagerSuite struct {
+	store     *server.AttributeStore
+	transport *deviceTransport
+	manager   *ReportingManager
+}
+
+var _ = Suite(&ReportingManagerSuite{})
+
+func (s *ReportingManagerSuite) SetUpTest(c *C) {
+	library := zcl.New().ClusterLibrary()
+	s.store = server.NewAttributeStore(library)
+	c.Assert(s.store.AddCluster(1, cluster.OnOff), IsNil)
+	c.Assert(s.store.AddCluster(1, cluster.LevelControl), IsNil)
+	c.Assert(s.store.Set(1, cluster.OnOff, 0x0000, false), IsNil)
+	c.Assert(s.store.Set(1, cluster.OnOff, 0x4000, false), IsNil)
+	c.Assert(s.store.Set(1, cluster.LevelControl, 0x0000, uint64(0)), IsNil)
+	s.transport = newDeviceTransport(server.NewReporter(s.store))
+	s.manager = NewReportingManager(s.transport.transactor, library)
+}
+
+func (s *ReportingManagerSuite) TestConfigure(c *C) {
+	diffs, err := s.manager.Configure(context.Background(), destination, []*ReportingTarget{
+		{ClusterId: cluster.OnOff, AttributeId: 0x0000, Min: 0, Max: 300},
+		{ClusterId: cluster.LevelControl, AttributeId: 0x0000, Min: 1, Max: 600, Change: 5},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(diffs, HasLen, 0)
+	c.Assert(s.transport.sent, Equals, 4)
+}
+
+func (s *ReportingManagerSuite) TestRefused(c *C) {
+	diffs, err := s.manager.Configure(context.Background(), destination, []*ReportingTarget{
+		{ClusterId: cluster.OnOff, AttributeId: 0x0000, Min: 0, Max: 300},
+		{ClusterId: cluster.OnOff, AttributeId: 0x4000, Min: 0, Max: 300},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(diffs, HasLen, 1)
+	c.Assert(diffs[0].Desired.AttributeId, Equals, uint16(0x4000))
+	c.Assert(diffs[0].Status, Equals, cluster.ZclStatusUnreportableAttribute)
+}
+
+func (s *ReportingManagerSuite) TestVerify(c *C) {
+	_, err := s.manager.Configure(context.Background(), destination, []*ReportingTarget{
+		{ClusterId: cluster.LevelControl, AttributeId: 0x0000, Min: 1, Max: 600, Change: 5},
+	})
+	c.Assert(err, IsNil)
+	diffs, err := s.manager.Verify(context.Background(), destination, []*ReportingTarget{
+		{ClusterId: cluster.LevelControl, AttributeId: 0x0000, Min: 1, Max: 600, Change: uint8(10)},
+		{ClusterId: cluster.OnOff, AttributeId: 0x0000, Min: 0, Max: 300},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(diffs, HasLen, 2)
+	c.Assert(diffs[0].Status, Equals, cluster.ZclStatusNotFound)
+	c.Assert(diffs[0].Actual, IsNil)
+	c.Assert(diffs[1].Actual, DeepEquals, &ReportingTarget{ClusterId: cluster.LevelControl, AttributeId: 0x0000, Min: 1, Max: 600, Change: uint64(5)})
+}
+
+func (s *ReportingManagerSuite) TestUnsupportedCluster(c *C) {
+	diffs, err := s.manager.Configure(context.Background(), destination, []*ReportingTarget{
+		{ClusterId: cluster.ColorControl, AttributeId: 0x0003, Min: 0, Max: 300, Change: 1},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(diffs, HasLen, 1)
+	c.Assert(diffs[0].Status, Equals, cluster.ZclStatusUnsupClusterCommand)
+}
+
+func (s *ReportingManagerSuite) TestInvalidChange(c *C) {
+	for _, change := range []interface{}{"5", 300, 0.5, -1} {
+		_, err := s.manager.Configure(context.Background(), destination, []*ReportingTarget{
+			{ClusterId: cluster.LevelControl, AttributeId: 0x0000, Min: 1, Max: 600, Change: change},
+		})
+		c.Assert(err, NotNil, Commentf("%v", change))
+	}
+	c.Assert(s.transport.sent, Equals, 0)
+}
+
+func (s *ReportingManagerSuite) TestFloatChange(c *C) {
+	c.Assert(s.store.AddCluster(1, cluster.AnalogInputBasic), IsNil)
+	c.Assert(s.store.Set(1, cluster.AnalogInputBasic, 0x0055, float32(20)), IsNil)
+	target := &ReportingTarget{ClusterId: cluster.AnalogInputBasic, AttributeId: 0x0055, Min: 1, Max: 600, Change: 0.5}
+	diffs, err := s.manager.Configure(context.Background(), destination, []*ReportingTarget{target})
+	c.Assert(err, IsNil)
+	c.Assert(diffs, HasLen, 0)
+
+	target.Change = 1
+	diffs, err = s.manager.Verify(context.Background(), destination, []*ReportingTarget{target})
+	c.Assert(err, IsNil)
+	c.Assert(diffs, HasLen, 1)
+	c.Assert(diffs[0].Actual.Change, Equals, float32(0.5))
+}
+
+func (s *ReportingManagerSuite) TestReportableChange(c *C) {
+	for _, t := range []struct {
+		dataType cluster.ZclDataType
+		change   interface{}
+		expected interface{}
+	}{
+		{cluster.ZclDataTypeUint8, 255, uint64(255)},
+		{cluster.ZclDataTypeUint64, uint64(math.MaxUint64), uint64(math.MaxUint64)},
+		{cluster.ZclDataTypeInt16, 32767, int64(32767)},
+		{cluster.ZclDataTypeUtc, nil, uint32(0)},
+		{cluster.ZclDataTypeSemiPrec, 0.5, float32(0.5)},
+		{cluster.ZclDataTypeSinglePrec, float32(1.5), float32(1.5)},
+		{cluster.ZclDataTypeDoublePrec, 2, float64(2)},
+		{cluster.ZclDataTypeTod, &cluster.TimeOfDay{Minutes: 5}, &cluster.TimeOfDay{Minutes: 5}},
+		{cluster.ZclDataTypeDate, nil, &cluster.Date{}},
+	} {
+		change, err := reportableChange(t.dataType, t.change)
+		c.Assert(err, IsNil, Commentf("%d %v", t.dataType, t.change))
+		c.Assert(change, DeepEquals, t.expected)
+	}
+	for _, t := range []struct {
+		dataType cluster.ZclDataType
+		change   interface{}
+	}{
+		{cluster.ZclDataTypeUint8, 256},
+		{cluster.ZclDataTypeInt8, 128},
+		{cluster.ZclDataTypeUint16, 1.5},
+		{cluster.ZclDataTypeSemiPrec, 70000},
+		{cluster.ZclDataTypeSinglePrec, math.NaN()},
+		{cluster.ZclDataTypeDoublePrec, -0.5},
+		{cluster.ZclDataTypeTod, 5},
+		{cluster.ZclDataTypeCharStr, 1},
+	} {
+		_, err := reportableChange(t.dataType, t.change)
+		c.Assert(err, NotNil, Commentf("%d %v", t.dataType, t.change))
+	}
+}
+
+func (s *ReportingManagerSuite) TestReleasesIds(c *C) {
+	allocator := frame.NewTransactionIdAllocator()
+	s.transport.transactor.IdAllocator(allocator)
+	for i := 0; i < 300; i++ {
+		_, err := s.manager.Verify(context.Background(), destination, []*ReportingTarget{
+			{ClusterId: cluster.OnOff, AttributeId: 0x0000, Min: 0, Max: 300},
+		})
+		c.Assert(err, IsNil)
+	}
+	c.Assert(allocator.InFlight(destination.Addr), Equals, 0)
+}
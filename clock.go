@@ -0,0 +1,20 @@
+package zcl
+
+import "time"
+
+// Clock is the time source of reporting, simulation and replay, which tests replace.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the clock of the system.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -7,8 +7,11 @@ type AttributeDescriptor struct {
 }
 
 type CommandDescriptor struct {
-	Name    string
-	Command interface{}
+	Name        string
+	Command     interface{}
+	Direction   CommandDirection
+	Requirement Requirement
+	Response    *uint8
 }
 
 type CommandDescriptors struct {
@@ -36,6 +39,21 @@ const (
 	Scene      Access = 0x08
 )
 
+type CommandDirection uint8
+
+const (
+	ClientToServer  CommandDirection = 0x01
+	ServerToClient  CommandDirection = 0x02
+	EitherDirection                  = ClientToServer | ServerToClient
+)
+
+type Requirement uint8
+
+const (
+	Mandatory Requirement = 0x00
+	Optional  Requirement = 0x01
+)
+
 type ClusterId uint16
 
 const (
@@ -68,32 +86,62 @@ const (
 	IASWarningDevice               ClusterId = 0x0502
 )
 
+var clusterIdNames = map[ClusterId]string{
+	Basic:                          "Basic",
+	PowerConfiguration:             "PowerConfiguration",
+	DeviceTemperatureConfiguration: "DeviceTemperatureConfiguration",
+	Identify:                       "Identify",
+	Groups:                         "Groups",
+	Scenes:                         "Scenes",
+	OnOff:                          "OnOff",
+	LevelControl:                   "LevelControl",
+	Alarms:                         "Alarms",
+	Time:                           "Time",
+	AnalogInputBasic:               "AnalogInputBasic",
+	BinaryOutputBasic:              "BinaryOutputBasic",
+	MultistateInput:                "MultistateInput",
+	OTA:                            "OTA",
+	PollControl:                    "PollControl",
+	ColorControl:                   "ColorControl",
+	IlluminanceMeasurement:         "IlluminanceMeasurement",
+	IlluminanceLevelSensing:        "IlluminanceLevelSensing",
+	TemperatureMeasurement:         "TemperatureMeasurement",
+	PressureMeasurement:            "PressureMeasurement",
+	FlowMeasurement:                "FlowMeasurement",
+	RelativeHumidityMeasurement:    "RelativeHumidityMeasurement",
+	OccupancySensing:               "OccupancySensing",
+	ElectricalMeasurement:          "ElectricalMeasurement",
+	IASZone:                        "IASZone",
+	IASACE:                         "IASAncillaryControlEquipment",
+	IASWarningDevice:               "IASWarningDevice",
+}
+
 func New() *ClusterLibrary {
 	return &ClusterLibrary{
 		global: map[uint8]*CommandDescriptor{
-			0x00: {"ReadAttributes", &ReadAttributesCommand{}},
-			0x01: {"ReadAttributesResponse", &ReadAttributesResponse{}},
-			0x02: {"WriteAttributes", &WriteAttributesCommand{}},
-			0x03: {"WriteAttributesUndivided", &WriteAttributesUndividedCommand{}},
-			0x04: {"WriteAttributesResponse", &WriteAttributesResponse{}},
-			0x05: {"WriteAttributesNoResponse", &WriteAttributesNoResponseCommand{}},
-			0x06: {"ConfigureReporting", &ConfigureReportingCommand{}},
-			0x07: {"ConfigureReportingResponse", &ConfigureReportingResponse{}},
-			0x08: {"ReadReportingConfiguration", &ReadReportingConfigurationCommand{}},
-			0x09: {"ReadReportingConfigurationResponse", &ReadReportingConfigurationResponse{}},
-			0x0a: {"ReportAttributes", &ReportAttributesCommand{}},
-			0x0b: {"DefaultResponse", &DefaultResponseCommand{}},
-			0x0c: {"DiscoverAttributes", &DiscoverAttributesCommand{}},
-			0x0d: {"DiscoverAttributesResponse", &DiscoverAttributesResponse{}},
-			0x0e: {"ReadAttributesStructured", &ReadAttributesStructuredCommand{}},
-			0x0f: {"WriteAttributesStructured", &WriteAttributesStructuredCommand{}},
-			0x10: {"WriteAttributesStructuredResponse", &WriteAttributesStructuredResponse{}},
-			0x11: {"DiscoverCommandsReceived", &DiscoverCommandsReceivedCommand{}},
-			0x12: {"DiscoverCommandsReceivedResponse", &DiscoverCommandsReceivedResponse{}},
-			0x13: {"DiscoverCommandsGenerated", &DiscoverCommandsGeneratedCommand{}},
-			0x14: {"DiscoverCommandsGeneratedResponse", &DiscoverCommandsGeneratedResponse{}},
-			0x15: {"DiscoverAttributesExtended", &DiscoverAttributesExtendedCommand{}},
-			0x16: {"DiscoverAttributesExtendedResponse", &DiscoverAttributesExtendedResponse{}},
+			0x00: {"ReadAttributes", &ReadAttributesCommand{}, EitherDirection, Mandatory, RespondsWith(0x01)},
+			0x01: {"ReadAttributesResponse", &ReadAttributesResponse{}, EitherDirection, Mandatory, nil},
+			0x02: {"WriteAttributes", &WriteAttributesCommand{}, EitherDirection, Mandatory, RespondsWith(0x04)},
+			0x03: {"WriteAttributesUndivided", &WriteAttributesUndividedCommand{}, EitherDirection, Mandatory, RespondsWith(0x04)},
+			0x04: {"WriteAttributesResponse", &WriteAttributesResponse{}, EitherDirection, Mandatory, nil},
+			0x05: {"WriteAttributesNoResponse", &WriteAttributesNoResponseCommand{}, EitherDirection, Mandatory, nil},
+			0x06: {"ConfigureReporting", &ConfigureReportingCommand{}, EitherDirection, Mandatory, RespondsWith(0x07)},
+			0x07: {"ConfigureReportingResponse", &ConfigureReportingResponse{}, EitherDirection, Mandatory, nil},
+			0x08: {"ReadReportingConfiguration", &ReadReportingConfigurationCommand{}, EitherDirection, Mandatory, RespondsWith(0x09)},
+			0x09: {"ReadReportingConfigurationResponse", &ReadReportingConfigurationResponse{}, EitherDirection, Mandatory, nil},
+			0x0a: {"ReportAttributes", &ReportAttributesCommand{}, EitherDirection, Mandatory, nil},
+			0x0b: {"DefaultResponse", &DefaultResponseCommand{}, EitherDirection, Mandatory, nil},
+			0x0c: {"DiscoverAttributes", &DiscoverAttributesCommand{}, EitherDirection, Mandatory, RespondsWith(0x0d)},
+			0x0d: {"DiscoverAttributesResponse", &DiscoverAttributesResponse{}, EitherDirection, Mandatory, nil},
+			0x0e: {"ReadAttributesStructured", &ReadAttributesStructuredCommand{}, EitherDirection, Optional, RespondsWith(0x01)},
+			0x0f: {"WriteAttributesStructured", &WriteAttributesStructuredCommand{}, EitherDirection, Optional, RespondsWith(0x10)},
+			0x10: {"WriteAttributesStructuredResponse", &WriteAttributesStructuredResponse{}, EitherDirection, Optional, nil},
+			0x11: {"DiscoverCommandsReceived", &DiscoverCommandsReceivedCommand{}, EitherDirection, Optional, RespondsWith(0x12)},
+			0x12: {"DiscoverCommandsReceivedResponse", &DiscoverCommandsReceivedResponse{}, EitherDirection, Optional, nil},
+			0x13: {"DiscoverCommandsGenerated", &DiscoverCommandsGeneratedCommand{}, EitherDirection, Optional, RespondsWith(0x14)},
+			0x14: {"DiscoverCommandsGeneratedResponse", &DiscoverCommandsGeneratedResponse{}, EitherDirection, Optional, nil},
+			0x15: {"DiscoverAttributesExtended", &DiscoverAttributesExtendedCommand{}, EitherDirection, Optional, RespondsWith(0x16)},
+			0x16: {"DiscoverAttributesExtendedResponse", &DiscoverAttributesExtendedResponse{}, EitherDirection, Optional, nil},
 		},
 		clusters: map[ClusterId]*Cluster{
 			Basic: {
@@ -116,7 +164,7 @@ func New() *ClusterLibrary {
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"ResetToFactoryDefaults", &ResetToFactoryDefaultsCommand{}},
+						0x00: {"ResetToFactoryDefaults", &ResetToFactoryDefaultsCommand{}, ClientToServer, Optional, nil},
 					},
 				},
 			},
@@ -169,12 +217,12 @@ func New() *ClusterLibrary {
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"Identify", &IdentifyCommand{}},
-						0x01: {"IdentifyQuery", &IdentifyQueryCommand{}},
-						0x40: {"TriggerEffect ", &TriggerEffectCommand{}},
+						0x00: {"Identify", &IdentifyCommand{}, ClientToServer, Mandatory, nil},
+						0x01: {"IdentifyQuery", &IdentifyQueryCommand{}, ClientToServer, Mandatory, RespondsWith(0x00)},
+						0x40: {"TriggerEffect", &TriggerEffectCommand{}, ClientToServer, Optional, nil},
 					},
 					Generated: map[uint8]*CommandDescriptor{
-						0x00: {"IdentifyQueryResponse ", &IdentifyQueryResponse{}},
+						0x00: {"IdentifyQueryResponse", &IdentifyQueryResponse{}, ServerToClient, Mandatory, nil},
 					},
 				},
 			},
@@ -185,18 +233,18 @@ func New() *ClusterLibrary {
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"AddGroup", &AddGroupCommand{}},
-						0x01: {"ViewGroup", &ViewGroupCommand{}},
-						0x02: {"GetGroupMembership", &GetGroupMembershipCommand{}},
-						0x03: {"RemoveGroup", &RemoveGroupCommand{}},
-						0x04: {"RemoveAllGroups", &RemoveAllGroupsCommand{}},
-						0x05: {"AddGroupIfIdentifying", &AddGroupIfIdentifyingCommand{}},
+						0x00: {"AddGroup", &AddGroupCommand{}, ClientToServer, Mandatory, RespondsWith(0x00)},
+						0x01: {"ViewGroup", &ViewGroupCommand{}, ClientToServer, Mandatory, RespondsWith(0x01)},
+						0x02: {"GetGroupMembership", &GetGroupMembershipCommand{}, ClientToServer, Mandatory, RespondsWith(0x02)},
+						0x03: {"RemoveGroup", &RemoveGroupCommand{}, ClientToServer, Mandatory, RespondsWith(0x03)},
+						0x04: {"RemoveAllGroups", &RemoveAllGroupsCommand{}, ClientToServer, Mandatory, nil},
+						0x05: {"AddGroupIfIdentifying", &AddGroupIfIdentifyingCommand{}, ClientToServer, Mandatory, nil},
 					},
 					Generated: map[uint8]*CommandDescriptor{
-						0x00: {"AddGroupResponse", &AddGroupResponse{}},
-						0x01: {"ViewGroupResponse", &ViewGroupResponse{}},
-						0x02: {"GetGroupMembershipResponse", &GetGroupMembershipResponse{}},
-						0x03: {"RemoveGroupResponse", &RemoveGroupResponse{}},
+						0x00: {"AddGroupResponse", &AddGroupResponse{}, ServerToClient, Mandatory, nil},
+						0x01: {"ViewGroupResponse", &ViewGroupResponse{}, ServerToClient, Mandatory, nil},
+						0x02: {"GetGroupMembershipResponse", &GetGroupMembershipResponse{}, ServerToClient, Mandatory, nil},
+						0x03: {"RemoveGroupResponse", &RemoveGroupResponse{}, ServerToClient, Mandatory, nil},
 					},
 				},
 			},
@@ -212,27 +260,27 @@ func New() *ClusterLibrary {
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"AddScene", &AddSceneCommand{}},
-						0x01: {"ViewScene", &ViewSceneCommand{}},
-						0x02: {"RemoveScene", &RemoveSceneCommand{}},
-						0x03: {"RemoveAllScenes", &RemoveAllScenesCommand{}},
-						0x04: {"StoreScene", &StoreSceneCommand{}},
-						0x05: {"RecallScene", &RecallSceneCommand{}},
-						0x06: {"GetSceneMembership", &GetSceneMembership{}},
-						0x40: {"EnhancedAddScene", &EnhancedAddSceneCommand{}},
-						0x41: {"EnhancedViewScene", &EnhancedViewSceneCommand{}},
-						0x42: {"CopyScene", &CopySceneCommand{}},
+						0x00: {"AddScene", &AddSceneCommand{}, ClientToServer, Mandatory, RespondsWith(0x00)},
+						0x01: {"ViewScene", &ViewSceneCommand{}, ClientToServer, Mandatory, RespondsWith(0x01)},
+						0x02: {"RemoveScene", &RemoveSceneCommand{}, ClientToServer, Mandatory, RespondsWith(0x02)},
+						0x03: {"RemoveAllScenes", &RemoveAllScenesCommand{}, ClientToServer, Mandatory, RespondsWith(0x03)},
+						0x04: {"StoreScene", &StoreSceneCommand{}, ClientToServer, Mandatory, RespondsWith(0x04)},
+						0x05: {"RecallScene", &RecallSceneCommand{}, ClientToServer, Mandatory, nil},
+						0x06: {"GetSceneMembership", &GetSceneMembership{}, ClientToServer, Mandatory, RespondsWith(0x06)},
+						0x40: {"EnhancedAddScene", &EnhancedAddSceneCommand{}, ClientToServer, Optional, RespondsWith(0x40)},
+						0x41: {"EnhancedViewScene", &EnhancedViewSceneCommand{}, ClientToServer, Optional, RespondsWith(0x41)},
+						0x42: {"CopyScene", &CopySceneCommand{}, ClientToServer, Optional, RespondsWith(0x42)},
 					},
 					Generated: map[uint8]*CommandDescriptor{
-						0x00: {"AddSceneResponse", &AddSceneResponse{}},
-						0x01: {"ViewSceneResponse", &ViewSceneResponse{}},
-						0x02: {"RemoveSceneResponse", &RemoveSceneResponse{}},
-						0x03: {"RemoveAllScenesResponse", &RemoveAllScenesResponse{}},
-						0x04: {"StoreSceneResponse", &StoreSceneResponse{}},
-						0x06: {"GetSceneMembershipResponse", &GetSceneMembershipResponse{}},
-						0x40: {"EnhancedAddSceneResponse", &EnhancedAddSceneResponse{}},
-						0x41: {"EnhancedViewSceneResponse", &EnhancedViewSceneResponse{}},
-						0x42: {"CopySceneResponse", &CopySceneResponse{}},
+						0x00: {"AddSceneResponse", &AddSceneResponse{}, ServerToClient, Mandatory, nil},
+						0x01: {"ViewSceneResponse", &ViewSceneResponse{}, ServerToClient, Mandatory, nil},
+						0x02: {"RemoveSceneResponse", &RemoveSceneResponse{}, ServerToClient, Mandatory, nil},
+						0x03: {"RemoveAllScenesResponse", &RemoveAllScenesResponse{}, ServerToClient, Mandatory, nil},
+						0x04: {"StoreSceneResponse", &StoreSceneResponse{}, ServerToClient, Mandatory, nil},
+						0x06: {"GetSceneMembershipResponse", &GetSceneMembershipResponse{}, ServerToClient, Mandatory, nil},
+						0x40: {"EnhancedAddSceneResponse", &EnhancedAddSceneResponse{}, ServerToClient, Optional, nil},
+						0x41: {"EnhancedViewSceneResponse", &EnhancedViewSceneResponse{}, ServerToClient, Optional, nil},
+						0x42: {"CopySceneResponse", &CopySceneResponse{}, ServerToClient, Optional, nil},
 					},
 				},
 			},
@@ -246,12 +294,12 @@ func New() *ClusterLibrary {
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"Off", &OffCommand{}},
-						0x01: {"On", &OnCommand{}},
-						0x02: {"Toggle ", &ToggleCommand{}},
-						0x40: {"OffWithEffect ", &OffWithEffectCommand{}},
-						0x41: {"OnWithRecallGlobalScene ", &OnWithRecallGlobalSceneCommand{}},
-						0x42: {"OnWithTimedOff ", &OnWithTimedOffCommand{}},
+						0x00: {"Off", &OffCommand{}, ClientToServer, Mandatory, nil},
+						0x01: {"On", &OnCommand{}, ClientToServer, Mandatory, nil},
+						0x02: {"Toggle", &ToggleCommand{}, ClientToServer, Mandatory, nil},
+						0x40: {"OffWithEffect", &OffWithEffectCommand{}, ClientToServer, Optional, nil},
+						0x41: {"OnWithRecallGlobalScene", &OnWithRecallGlobalSceneCommand{}, ClientToServer, Optional, nil},
+						0x42: {"OnWithTimedOff", &OnWithTimedOffCommand{}, ClientToServer, Optional, nil},
 					},
 				},
 			},
@@ -268,14 +316,14 @@ func New() *ClusterLibrary {
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"MoveToLevel ", &MoveToLevelCommand{}},
-						0x01: {"Move", &MoveCommand{}},
-						0x02: {"Step ", &StepCommand{}},
-						0x03: {"Stop ", &StopCommand{}},
-						0x04: {"MoveToLevel/OnOff", &MoveToLevelOnOffCommand{}},
-						0x05: {"Move/OnOff", &MoveOnOffCommand{}},
-						0x06: {"Step/OnOff", &StepOnOffCommand{}},
-						0x07: {"Stop/OnOff", &StopOnOffCommand{}},
+						0x00: {"MoveToLevel", &MoveToLevelCommand{}, ClientToServer, Mandatory, nil},
+						0x01: {"Move", &MoveCommand{}, ClientToServer, Mandatory, nil},
+						0x02: {"Step", &StepCommand{}, ClientToServer, Mandatory, nil},
+						0x03: {"Stop", &StopCommand{}, ClientToServer, Mandatory, nil},
+						0x04: {"MoveToLevel/OnOff", &MoveToLevelOnOffCommand{}, ClientToServer, Mandatory, nil},
+						0x05: {"Move/OnOff", &MoveOnOffCommand{}, ClientToServer, Mandatory, nil},
+						0x06: {"Step/OnOff", &StepOnOffCommand{}, ClientToServer, Mandatory, nil},
+						0x07: {"Stop/OnOff", &StopOnOffCommand{}, ClientToServer, Mandatory, nil},
 					},
 				},
 			},
@@ -286,14 +334,14 @@ func New() *ClusterLibrary {
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"ResetAlarm", &ResetAlarmCommand{}},
-						0x01: {"ResetAllAlarms", &ResetAllAlarmsCommand{}},
-						0x02: {"GetAlarm", &GetAlarmCommand{}},
-						0x03: {"ResetAlarmLog", &ResetAlarmLogCommand{}},
+						0x00: {"ResetAlarm", &ResetAlarmCommand{}, ClientToServer, Mandatory, nil},
+						0x01: {"ResetAllAlarms", &ResetAllAlarmsCommand{}, ClientToServer, Mandatory, nil},
+						0x02: {"GetAlarm", &GetAlarmCommand{}, ClientToServer, Optional, RespondsWith(0x01)},
+						0x03: {"ResetAlarmLog", &ResetAlarmLogCommand{}, ClientToServer, Optional, nil},
 					},
 					Generated: map[uint8]*CommandDescriptor{
-						0x00: {"Alarm", &AlarmCommand{}},
-						0x01: {"GetAlarmResponse", &GetAlarmResponse{}},
+						0x00: {"Alarm", &AlarmCommand{}, ServerToClient, Mandatory, nil},
+						0x01: {"GetAlarmResponse", &GetAlarmResponse{}, ServerToClient, Optional, nil},
 					},
 				},
 			},
@@ -319,7 +367,7 @@ func New() *ClusterLibrary {
 					0x0041: {"MaxPresentValue", ZclDataTypeSinglePrec, Read | Write},
 					0x0045: {"MinPresentValue", ZclDataTypeSinglePrec, Read | Write},
 					0x0050: {"OutOfService", ZclDataTypeBoolean, Read | Write},
-					0x0055: {"PresentValue", ZclDataTypeSinglePrec, Read | Write},
+					0x0055: {"PresentValue", ZclDataTypeSinglePrec, Read | Write | Reportable},
 					0x0067: {"Reliability", ZclDataTypeBitmap8, Read | Write},
 					0x006a: {"Resolution", ZclDataTypeSinglePrec, Read | Write},
 					0x006f: {"StatusFlags", ZclDataTypeBitmap8, Read},
@@ -368,9 +416,9 @@ func New() *ClusterLibrary {
 					0x0005: {"DownloadedZigBeeStackVersion", ZclDataTypeUint16, Read},
 					0x0006: {"ImageUpgradeStatus", ZclDataTypeEnum8, Read},
 					0x0007: {"ManufacturerID", ZclDataTypeUint16, Read},
-					0x0008: {"ImageTypeID ", ZclDataTypeUint16, Read},
-					0x0009: {"MinimumBlockPeriod ", ZclDataTypeUint16, Read},
-					0x000a: {"ImageStamp ", ZclDataTypeUint32, Read},
+					0x0008: {"ImageTypeID", ZclDataTypeUint16, Read},
+					0x0009: {"MinimumBlockPeriod", ZclDataTypeUint16, Read},
+					0x000a: {"ImageStamp", ZclDataTypeUint32, Read},
 				},
 			},
 			PollControl: {
@@ -386,13 +434,13 @@ func New() *ClusterLibrary {
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"CheckIn", &CheckInCommand{}},
+						0x00: {"CheckInResponse", &CheckInResponse{}, ClientToServer, Mandatory, nil},
+						0x01: {"FastPollStop", &FastPollStopCommand{}, ClientToServer, Mandatory, nil},
+						0x02: {"SetLongPollInterval", &SetLongPollIntervalCommand{}, ClientToServer, Optional, nil},
+						0x03: {"SetShortPollInterval", &SetShortPollIntervalCommand{}, ClientToServer, Optional, nil},
 					},
 					Generated: map[uint8]*CommandDescriptor{
-						0x00: {"CheckInResponse", &CheckInResponse{}},
-						0x01: {"FastPollStop", &FastPollStopCommand{}},
-						0x02: {"SetLongPollInterval", &SetLongPollIntervalCommand{}},
-						0x03: {"SetShortPollInterval", &SetShortPollIntervalCommand{}},
+						0x00: {"CheckIn", &CheckInCommand{}, ServerToClient, Mandatory, RespondsWith(0x00)},
 					},
 				},
 			},
@@ -453,32 +501,32 @@ func New() *ClusterLibrary {
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"MoveToHue", &MoveToHueCommand{}},
-						0x01: {"MoveHue", &MoveHueCommand{}},
-						0x02: {"StepHue", &StepHueCommand{}},
-						0x03: {"MoveToSaturation", &MoveToSaturationCommand{}},
-						0x04: {"MoveSaturation", &MoveSaturationCommand{}},
-						0x05: {"StepSaturation", &StepSaturationCommand{}},
-						0x06: {"MoveToHueAndSaturation", &MoveToHueAndSaturationCommand{}},
-						0x07: {"MoveToColor", &MoveToColorCommand{}},
-						0x08: {"MoveColor", &MoveColorCommand{}},
-						0x09: {"StepColor", &StepColorCommand{}},
-						0x0a: {"MoveToColorTemperature", &MoveToColorTemperatureCommand{}},
-						0x40: {"EnhancedMoveToHue", &EnhancedMoveToHueCommand{}},
-						0x41: {"EnhanceMoveHue", &EnhanceMoveHueCommand{}},
-						0x42: {"EnhancedStepHue", &EnhancedStepHueCommand{}},
-						0x43: {"EnhancedMoveToHueAndSaturation", &EnhancedMoveToHueAndSaturationCommand{}},
-						0x44: {"ColorLoopSet", &ColorLoopSetCommand{}},
-						0x47: {"StopMoveStep", &StopMoveStepCommand{}},
-						0x4b: {"MoveColorTemperature", &MoveColorTemperatureCommand{}},
-						0x4c: {"StepColorTemperature", &StepColorTemperatureCommand{}},
+						0x00: {"MoveToHue", &MoveToHueCommand{}, ClientToServer, Optional, nil},
+						0x01: {"MoveHue", &MoveHueCommand{}, ClientToServer, Optional, nil},
+						0x02: {"StepHue", &StepHueCommand{}, ClientToServer, Optional, nil},
+						0x03: {"MoveToSaturation", &MoveToSaturationCommand{}, ClientToServer, Optional, nil},
+						0x04: {"MoveSaturation", &MoveSaturationCommand{}, ClientToServer, Optional, nil},
+						0x05: {"StepSaturation", &StepSaturationCommand{}, ClientToServer, Optional, nil},
+						0x06: {"MoveToHueAndSaturation", &MoveToHueAndSaturationCommand{}, ClientToServer, Optional, nil},
+						0x07: {"MoveToColor", &MoveToColorCommand{}, ClientToServer, Mandatory, nil},
+						0x08: {"MoveColor", &MoveColorCommand{}, ClientToServer, Mandatory, nil},
+						0x09: {"StepColor", &StepColorCommand{}, ClientToServer, Mandatory, nil},
+						0x0a: {"MoveToColorTemperature", &MoveToColorTemperatureCommand{}, ClientToServer, Optional, nil},
+						0x40: {"EnhancedMoveToHue", &EnhancedMoveToHueCommand{}, ClientToServer, Optional, nil},
+						0x41: {"EnhanceMoveHue", &EnhanceMoveHueCommand{}, ClientToServer, Optional, nil},
+						0x42: {"EnhancedStepHue", &EnhancedStepHueCommand{}, ClientToServer, Optional, nil},
+						0x43: {"EnhancedMoveToHueAndSaturation", &EnhancedMoveToHueAndSaturationCommand{}, ClientToServer, Optional, nil},
+						0x44: {"ColorLoopSet", &ColorLoopSetCommand{}, ClientToServer, Optional, nil},
+						0x47: {"StopMoveStep", &StopMoveStepCommand{}, ClientToServer, Optional, nil},
+						0x4b: {"MoveColorTemperature", &MoveColorTemperatureCommand{}, ClientToServer, Optional, nil},
+						0x4c: {"StepColorTemperature", &StepColorTemperatureCommand{}, ClientToServer, Optional, nil},
 					},
 				},
 			},
 			IlluminanceMeasurement: {
 				Name: "IlluminanceMeasurement",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x0000: {"MeasuredValue", ZclDataTypeUint16, Read},
+					0x0000: {"MeasuredValue", ZclDataTypeUint16, Read | Reportable},
 					0x0001: {"MinMeasuredValue", ZclDataTypeUint16, Read},
 					0x0002: {"MaxMeasuredValue", ZclDataTypeUint16, Read},
 					0x0003: {"Tolerance", ZclDataTypeUint16, Read},
@@ -496,7 +544,7 @@ func New() *ClusterLibrary {
 			TemperatureMeasurement: {
 				Name: "TemperatureMeasurement",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x0000: {"MeasuredValue", ZclDataTypeInt16, Read},
+					0x0000: {"MeasuredValue", ZclDataTypeInt16, Read | Reportable},
 					0x0001: {"MinMeasuredValue", ZclDataTypeInt16, Read},
 					0x0002: {"MaxMeasuredValue", ZclDataTypeInt16, Read},
 					0x0003: {"Tolerance", ZclDataTypeUint16, Read},
@@ -505,7 +553,7 @@ func New() *ClusterLibrary {
 			PressureMeasurement: {
 				Name: "PressureMeasurement",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x0000: {"MeasuredValue", ZclDataTypeInt16, Read},
+					0x0000: {"MeasuredValue", ZclDataTypeInt16, Read | Reportable},
 					0x0001: {"MinMeasuredValue", ZclDataTypeInt16, Read},
 					0x0002: {"MaxMeasuredValue", ZclDataTypeInt16, Read},
 					0x0003: {"Tolerance", ZclDataTypeUint16, Read},
@@ -519,7 +567,7 @@ func New() *ClusterLibrary {
 			FlowMeasurement: {
 				Name: "FlowMeasurement",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x0000: {"MeasuredValue", ZclDataTypeUint16, Read},
+					0x0000: {"MeasuredValue", ZclDataTypeUint16, Read | Reportable},
 					0x0001: {"MinMeasuredValue", ZclDataTypeUint16, Read},
 					0x0002: {"MaxMeasuredValue", ZclDataTypeUint16, Read},
 					0x0003: {"Tolerance", ZclDataTypeUint16, Read},
@@ -528,7 +576,7 @@ func New() *ClusterLibrary {
 			RelativeHumidityMeasurement: {
 				Name: "RelativeHumidityMeasurement",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x0000: {"MeasuredValue", ZclDataTypeUint16, Read},
+					0x0000: {"MeasuredValue", ZclDataTypeUint16, Read | Reportable},
 					0x0001: {"MinMeasuredValue", ZclDataTypeUint16, Read},
 					0x0002: {"MaxMeasuredValue", ZclDataTypeUint16, Read},
 					0x0003: {"Tolerance", ZclDataTypeUint16, Read},
@@ -537,7 +585,7 @@ func New() *ClusterLibrary {
 			OccupancySensing: {
 				Name: "OccupancySensing",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x0000: {"Occupancy", ZclDataTypeBitmap8, Read},
+					0x0000: {"Occupancy", ZclDataTypeBitmap8, Read | Reportable},
 					0x0001: {"OccupancySensorType", ZclDataTypeEnum8, Read},
 					0x0010: {"PIROccupiedToUnoccupiedDelay", ZclDataTypeUint16, Read | Write},
 					0x0011: {"PIRUnoccupiedToOccupiedDelay", ZclDataTypeUint16, Read | Write},
@@ -696,12 +744,12 @@ func New() *ClusterLibrary {
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"GetProfileInfoResponse", &GetProfileInfoResponse{}},
-						0x01: {"GetMeasurementProfileResponse", &GetMeasurementProfileResponse{}},
+						0x00: {"GetProfileInfoCommand", &GetProfileInfoCommand{}, ClientToServer, Optional, RespondsWith(0x00)},
+						0x01: {"GetMeasurementProfileCommand", &GetMeasurementProfileCommand{}, ClientToServer, Optional, RespondsWith(0x01)},
 					},
 					Generated: map[uint8]*CommandDescriptor{
-						0x00: {"GetProfileInfoCommand", &GetProfileInfoCommand{}},
-						0x01: {"GetMeasurementProfileCommand", &GetMeasurementProfileCommand{}},
+						0x00: {"GetProfileInfoResponse", &GetProfileInfoResponse{}, ServerToClient, Optional, nil},
+						0x01: {"GetMeasurementProfileResponse", &GetMeasurementProfileResponse{}, ServerToClient, Optional, nil},
 					},
 				},
 			},
@@ -718,13 +766,13 @@ func New() *ClusterLibrary {
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"ZoneEnrollResponse", &ZoneEnrollResponse{}},
-						0x01: {"InitiateNormalOperationMode", &InitiateNormalOperationModeCommand{}},
-						0x02: {"InitiateTestMode", &InitiateTestModeCommand{}},
+						0x00: {"ZoneEnrollResponse", &ZoneEnrollResponse{}, ClientToServer, Mandatory, nil},
+						0x01: {"InitiateNormalOperationMode", &InitiateNormalOperationModeCommand{}, ClientToServer, Optional, nil},
+						0x02: {"InitiateTestMode", &InitiateTestModeCommand{}, ClientToServer, Optional, nil},
 					},
 					Generated: map[uint8]*CommandDescriptor{
-						0x00: {"ZoneStatusChangeNotification", &ZoneStatusChangeNotificationCommand{}},
-						0x01: {"ZoneEnrollRequest", &ZoneEnrollCommand{}},
+						0x00: {"ZoneStatusChangeNotification", &ZoneStatusChangeNotificationCommand{}, ServerToClient, Mandatory, nil},
+						0x01: {"ZoneEnrollRequest", &ZoneEnrollCommand{}, ServerToClient, Mandatory, RespondsWith(0x00)},
 					},
 				},
 			},
@@ -732,27 +780,27 @@ func New() *ClusterLibrary {
 				Name: "IASAncillaryControlEquipment",
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"Arm", &ArmCommand{}},
-						0x01: {"Bypass", &BypassCommand{}},
-						0x02: {"Emergency", &EmergencyCommand{}},
-						0x03: {"Fire", &FireCommand{}},
-						0x04: {"Panic", &PanicCommand{}},
-						0x05: {"GetZoneIDMap", &GetZoneIDMapCommand{}},
-						0x06: {"GetZoneInformation", &GetZoneInformationCommand{}},
-						0x07: {"GetPanelStatus", &GetPanelStatusCommand{}},
-						0x08: {"GetBypassedZoneList", &GetBypassedZoneListCommand{}},
-						0x09: {"GetZoneStatus", &GetZoneStatus{}},
+						0x00: {"Arm", &ArmCommand{}, ClientToServer, Mandatory, RespondsWith(0x00)},
+						0x01: {"Bypass", &BypassCommand{}, ClientToServer, Mandatory, RespondsWith(0x07)},
+						0x02: {"Emergency", &EmergencyCommand{}, ClientToServer, Mandatory, nil},
+						0x03: {"Fire", &FireCommand{}, ClientToServer, Mandatory, nil},
+						0x04: {"Panic", &PanicCommand{}, ClientToServer, Mandatory, nil},
+						0x05: {"GetZoneIDMap", &GetZoneIDMapCommand{}, ClientToServer, Mandatory, RespondsWith(0x01)},
+						0x06: {"GetZoneInformation", &GetZoneInformationCommand{}, ClientToServer, Mandatory, RespondsWith(0x02)},
+						0x07: {"GetPanelStatus", &GetPanelStatusCommand{}, ClientToServer, Mandatory, RespondsWith(0x05)},
+						0x08: {"GetBypassedZoneList", &GetBypassedZoneListCommand{}, ClientToServer, Mandatory, RespondsWith(0x06)},
+						0x09: {"GetZoneStatus", &GetZoneStatus{}, ClientToServer, Mandatory, RespondsWith(0x08)},
 					},
 					Generated: map[uint8]*CommandDescriptor{
-						0x00: {"ArmResponse", &ArmResponse{}},
-						0x01: {"GetZoneIDMapResponse", &GetZoneIDMapResponse{}},
-						0x02: {"GetZoneInformationResponse", &GetZoneInformationResponse{}},
-						0x03: {"ZoneStatusChanged", &ZoneStatusChanged{}},
-						0x04: {"PanelStatusChanged", &PanelStatusChanged{}},
-						0x05: {"GetPanelStatusResponse", &PanelStatusChanged{}},
-						0x06: {"SetBypassedZoneList", &BypassedZoneList{}},
-						0x07: {"BypassResponse", &BypassedZoneList{}},
-						0x08: {"GetZoneStatusResponse", &GetZoneStatusResponse{}},
+						0x00: {"ArmResponse", &ArmResponse{}, ServerToClient, Mandatory, nil},
+						0x01: {"GetZoneIDMapResponse", &GetZoneIDMapResponse{}, ServerToClient, Mandatory, nil},
+						0x02: {"GetZoneInformationResponse", &GetZoneInformationResponse{}, ServerToClient, Mandatory, nil},
+						0x03: {"ZoneStatusChanged", &ZoneStatusChanged{}, ServerToClient, Mandatory, nil},
+						0x04: {"PanelStatusChanged", &PanelStatusChanged{}, ServerToClient, Mandatory, nil},
+						0x05: {"GetPanelStatusResponse", &PanelStatusChanged{}, ServerToClient, Mandatory, nil},
+						0x06: {"SetBypassedZoneList", &BypassedZoneList{}, ServerToClient, Mandatory, nil},
+						0x07: {"BypassResponse", &BypassedZoneList{}, ServerToClient, Mandatory, nil},
+						0x08: {"GetZoneStatusResponse", &GetZoneStatusResponse{}, ServerToClient, Mandatory, nil},
 					},
 				},
 			},
@@ -763,8 +811,8 @@ func New() *ClusterLibrary {
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"StartWarning", &StartWarning{}},
-						0x01: {"Squawk", &Squark{}},
+						0x00: {"StartWarning", &StartWarning{}, ClientToServer, Mandatory, nil},
+						0x01: {"Squawk", &Squark{}, ClientToServer, Mandatory, nil},
 					},
 				},
 			},
@@ -779,3 +827,43 @@ func (cl *ClusterLibrary) Clusters() map[ClusterId]*Cluster {
 func (cl *ClusterLibrary) Global() map[uint8]*CommandDescriptor {
 	return cl.global
 }
+
+func (cd *CommandDescriptor) ExpectsResponse() bool {
+	return cd.Response != nil
+}
+
+func (c *Cluster) ResponseDescriptor(commandId uint8, direction CommandDirection) (uint8, *CommandDescriptor, bool) {
+	if c.CommandDescriptors == nil {
+		return 0, nil, false
+	}
+	var requests, responses map[uint8]*CommandDescriptor
+	switch direction {
+	case ClientToServer:
+		requests, responses = c.CommandDescriptors.Received, c.CommandDescriptors.Generated
+	case ServerToClient:
+		requests, responses = c.CommandDescriptors.Generated, c.CommandDescriptors.Received
+	default:
+		return 0, nil, false
+	}
+	cd, ok := requests[commandId]
+	if !ok || cd.Response == nil {
+		return 0, nil, false
+	}
+	response, ok := responses[*cd.Response]
+	return *cd.Response, response, ok
+}
+
+func (cl *ClusterLibrary) GlobalResponseDescriptor(commandId uint8) (uint8, *CommandDescriptor, bool) {
+	cd, ok := cl.global[commandId]
+	if !ok || cd.Response == nil {
+		return 0, nil, false
+	}
+	response, ok := cl.global[*cd.Response]
+	return *cd.Response, response, ok
+}
+
+// RespondsWith returns a CommandDescriptor.Response, e.g. for the commands of clusters patched into
+// a library.
+func RespondsWith(commandId uint8) *uint8 {
+	return &commandId
+}
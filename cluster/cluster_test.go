@@ -0,0 +1,87 @@
+package cluster
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type ClusterLibrarySuite struct{}
+
+var _ = Suite(&ClusterLibrarySuite{})
+
+func (s *ClusterLibrarySuite) TestCommandDirections(c *C) {
+	library := New()
+	for _, cd := range library.Global() {
+		c.Assert(cd.Direction, Equals, EitherDirection)
+	}
+	for _, cl := range library.Clusters() {
+		if cl.CommandDescriptors == nil {
+			continue
+		}
+		for _, cd := range cl.CommandDescriptors.Received {
+			c.Assert(cd.Direction, Equals, ClientToServer, Commentf("%s.%s", cl.Name, cd.Name))
+		}
+		for _, cd := range cl.CommandDescriptors.Generated {
+			c.Assert(cd.Direction, Equals, ServerToClient, Commentf("%s.%s", cl.Name, cd.Name))
+		}
+	}
+}
+
+func (s *ClusterLibrarySuite) TestResponsesResolve(c *C) {
+	library := New()
+	for id, cd := range library.Global() {
+		if cd.ExpectsResponse() {
+			_, response, ok := library.GlobalResponseDescriptor(id)
+			c.Assert(ok, Equals, true, Commentf(cd.Name))
+			c.Assert(response.ExpectsResponse(), Equals, false, Commentf(cd.Name))
+		}
+	}
+	for _, cl := range library.Clusters() {
+		if cl.CommandDescriptors == nil {
+			continue
+		}
+		for id, cd := range cl.CommandDescriptors.Received {
+			if cd.ExpectsResponse() {
+				_, _, ok := cl.ResponseDescriptor(id, ClientToServer)
+				c.Assert(ok, Equals, true, Commentf("%s.%s", cl.Name, cd.Name))
+			}
+		}
+		for id, cd := range cl.CommandDescriptors.Generated {
+			if cd.ExpectsResponse() {
+				_, _, ok := cl.ResponseDescriptor(id, ServerToClient)
+				c.Assert(ok, Equals, true, Commentf("%s.%s", cl.Name, cd.Name))
+			}
+		}
+	}
+}
+
+func (s *ClusterLibrarySuite) TestViewGroupResponse(c *C) {
+	groups := New().Clusters()[Groups]
+	responseId, response, ok := groups.ResponseDescriptor(0x01, ClientToServer)
+	c.Assert(ok, Equals, true)
+	c.Assert(responseId, Equals, uint8(0x01))
+	c.Assert(response.Name, Equals, "ViewGroupResponse")
+	c.Assert(response.Command, FitsTypeOf, &ViewGroupResponse{})
+}
+
+// Poll control devices check in with the client, which answers; electrical measurement servers
+// answer profile requests of the client.
+func (s *ClusterLibrarySuite) TestResponsePlacement(c *C) {
+	library := New()
+	_, response, ok := library.Clusters()[PollControl].ResponseDescriptor(0x00, ServerToClient)
+	c.Assert(ok, Equals, true)
+	c.Assert(response.Name, Equals, "CheckInResponse")
+	c.Assert(response.Direction, Equals, ClientToServer)
+	_, response, ok = library.Clusters()[ElectricalMeasurement].ResponseDescriptor(0x00, ClientToServer)
+	c.Assert(ok, Equals, true)
+	c.Assert(response.Name, Equals, "GetProfileInfoResponse")
+	c.Assert(response.Direction, Equals, ServerToClient)
+}
+
+// The specification marks the measured values of the measurement and sensing clusters reportable.
+func (s *ClusterLibrarySuite) TestReportableMeasurements(c *C) {
+	clusters := New().Clusters()
+	for _, clusterId := range []ClusterId{IlluminanceMeasurement, TemperatureMeasurement, PressureMeasurement,
+		FlowMeasurement, RelativeHumidityMeasurement, OccupancySensing} {
+		c.Assert(clusters[clusterId].AttributeDescriptors[0x0000].Access, Equals, Read|Reportable, Commentf("%s", clusterId))
+	}
+}
@@ -3,7 +3,9 @@ package cluster
 import (
 	"encoding/binary"
 	"io"
+	"math"
 	"strconv"
+	"strings"
 
 	"github.com/dyrkin/bin/util"
 	"github.com/dyrkin/composer"
@@ -64,7 +66,7 @@ type WriteAttributesNoResponseCommand struct {
 type WriteAttributeStatus struct {
 	Status        ZclStatus
 	AttributeName string `transient:"true"`
-	AttributeID   uint16
+	AttributeID   uint16 `cond:"uint:Status!=0"`
 }
 
 type WriteAttributesResponse struct {
@@ -78,7 +80,7 @@ type AttributeReportingConfigurationRecord struct {
 	AttributeDataType        ZclDataType `cond:"uint:Direction==0"`
 	MinimumReportingInterval uint16      `cond:"uint:Direction==0"`
 	MaximumReportingInterval uint16      `cond:"uint:Direction==0"`
-	ReportableChange         *Attribute  `cond:"uint:Direction==0"`
+	ReportableChange         *Attribute  `cond:"uint:Direction==0"` // absent for discrete data types
 	TimeoutPeriod            uint16      `cond:"uint:Direction==1"`
 }
 
@@ -88,9 +90,9 @@ type ConfigureReportingCommand struct {
 
 type AttributeStatusRecord struct {
 	Status        ZclStatus
-	Direction     ReportDirection
-	AttributeName string `transient:"true"`
-	AttributeID   uint16
+	Direction     ReportDirection `cond:"uint:Status!=0"`
+	AttributeName string          `transient:"true"`
+	AttributeID   uint16          `cond:"uint:Status!=0"`
 }
 
 type ConfigureReportingResponse struct {
@@ -115,7 +117,7 @@ type AttributeReportingConfigurationResponseRecord struct {
 	AttributeDataType        ZclDataType `cond:"uint:Direction==0;uint:Status==0"`
 	MinimumReportingInterval uint16      `cond:"uint:Direction==0;uint:Status==0"`
 	MaximumReportingInterval uint16      `cond:"uint:Direction==0;uint:Status==0"`
-	ReportableChange         *Attribute  `cond:"uint:Direction==0;uint:Status==0"`
+	ReportableChange         *Attribute  `cond:"uint:Direction==0;uint:Status==0"` // absent for discrete data types
 	TimeoutPeriod            uint16      `cond:"uint:Direction==1;uint:Status==0"`
 }
 
@@ -236,6 +238,10 @@ func (a *Attribute) Serialize(w io.Writer) {
 
 func writeAttribute(c *composer.Composer, dataType ZclDataType, value interface{}) {
 	c.Uint8(uint8(dataType))
+	writeAttributeValue(c, dataType, value)
+}
+
+func writeAttributeValue(c *composer.Composer, dataType ZclDataType, value interface{}) {
 	switch dataType {
 	case ZclDataTypeNoData:
 	case ZclDataTypeData8:
@@ -348,8 +354,14 @@ func writeAttribute(c *composer.Composer, dataType ZclDataType, value interface{
 		b := value.(uint64)
 		c.Uint(binary.LittleEndian, b, 2)
 	case ZclDataTypeSemiPrec:
+		b := value.(float32)
+		c.Uint16le(float32ToHalf(b))
 	case ZclDataTypeSinglePrec:
+		b := value.(float32)
+		c.Uint32le(math.Float32bits(b))
 	case ZclDataTypeDoublePrec:
+		b := value.(float64)
+		c.Uint64le(math.Float64bits(b))
 	case ZclDataTypeOctetStr:
 		b := value.(string)
 		c.Uint8(uint8(len(b)))
@@ -366,13 +378,12 @@ func writeAttribute(c *composer.Composer, dataType ZclDataType, value interface{
 		b := value.(string)
 		c.Uint16le(uint16(len(b)))
 		c.String(b)
-	case ZclDataTypeArray, ZclDataTypeSet, ZclDataTypeBag:
+	case ZclDataTypeArray, ZclDataTypeSet, ZclDataTypeBag, ZclDataTypeStruct:
 		attributes := value.([]*Attribute)
 		c.Uint16le(uint16(len(attributes)))
 		for _, attribute := range attributes {
 			writeAttribute(c, attribute.DataType, attribute.Value)
 		}
-	case ZclDataTypeStruct:
 	case ZclDataTypeTod:
 		b := value.(*TimeOfDay)
 		c.Uint8(b.Hours)
@@ -399,7 +410,7 @@ func writeAttribute(c *composer.Composer, dataType ZclDataType, value interface{
 		c.Uint32le(b)
 	case ZclDataTypeIeeeAddr:
 		b := value.(string)
-		v, _ := strconv.ParseUint(b[2:], 16, 64)
+		v, _ := strconv.ParseUint(strings.TrimPrefix(b, "0x"), 16, 64)
 		c.Uint64le(v)
 	case ZclDataType_128BitSecKey:
 		b := value.([16]byte)
@@ -417,7 +428,70 @@ func (a *Attribute) Deserialize(r io.Reader) {
 func readAttribute(c *composer.Composer) (dataType ZclDataType, value interface{}) {
 	dt, _ := c.ReadByte()
 	dataType = ZclDataType(dt)
+	value = readAttributeValue(c, dataType)
+	return
+}
+
+// readInt sign extends the value from its most significant byte. Composer.ReadInt extends each byte
+// and corrupts values of more than two bytes.
+func readInt(c *composer.Composer, size int) int64 {
+	shift := uint(64 - 8*size)
+	return int64(c.ReadUint(binary.LittleEndian, size)<<shift) >> shift
+}
+
+// halfToFloat32 widens an IEEE 754 half precision value, which has a 5 bit exponent and a 10 bit
+// mantissa.
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exponent := uint32(h>>10) & 0x1f
+	mantissa := uint32(h & 0x3ff)
+	switch exponent {
+	case 0:
+		// zero or subnormal, mantissa * 2^-24
+		return math.Float32frombits(sign | math.Float32bits(float32(mantissa)/(1<<24)))
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mantissa<<13)
+	}
+	return math.Float32frombits(sign | (exponent+127-15)<<23 | mantissa<<13)
+}
+
+// float32ToHalf narrows the value to half precision, rounding to the nearest even value. Values
+// out of range become infinite.
+func float32ToHalf(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exponent := int(b>>23&0xff) - 127 + 15
+	mantissa := b & 0x7fffff
+	switch {
+	case b&0x7f800000 == 0x7f800000:
+		if mantissa != 0 {
+			return sign | 0x7e00 | uint16(mantissa>>13)
+		}
+		return sign | 0x7c00
+	case exponent >= 0x1f:
+		return sign | 0x7c00
+	case exponent <= 0:
+		if exponent < -10 {
+			return sign
+		}
+		// subnormal, the implicit leading bit becomes part of the mantissa
+		return sign | uint16(roundShift(mantissa|0x800000, uint(14-exponent)))
+	}
+	return sign | uint16(roundShift(uint32(exponent)<<23|mantissa, 13))
+}
+
+// roundShift shifts v right, rounding half to even.
+func roundShift(v uint32, shift uint) uint32 {
+	shifted := v >> shift
+	remainder := v & (1<<shift - 1)
+	half := uint32(1) << (shift - 1)
+	if remainder > half || remainder == half && shifted&1 == 1 {
+		shifted++
+	}
+	return shifted
+}
 
+func readAttributeValue(c *composer.Composer, dataType ZclDataType) (value interface{}) {
 	switch dataType {
 	case ZclDataTypeNoData:
 		value = nil
@@ -489,28 +563,34 @@ func readAttribute(c *composer.Composer) (dataType ZclDataType, value interface{
 	case ZclDataTypeUint64:
 		value = c.ReadUint(binary.LittleEndian, 8)
 	case ZclDataTypeInt8:
-		value = c.ReadInt(binary.LittleEndian, 1)
+		value = readInt(c, 1)
 	case ZclDataTypeInt16:
-		value = c.ReadInt(binary.LittleEndian, 2)
+		value = readInt(c, 2)
 	case ZclDataTypeInt24:
-		value = c.ReadInt(binary.LittleEndian, 3)
+		value = readInt(c, 3)
 	case ZclDataTypeInt32:
-		value = c.ReadInt(binary.LittleEndian, 4)
+		value = readInt(c, 4)
 	case ZclDataTypeInt40:
-		value = c.ReadInt(binary.LittleEndian, 5)
+		value = readInt(c, 5)
 	case ZclDataTypeInt48:
-		value = c.ReadInt(binary.LittleEndian, 6)
+		value = readInt(c, 6)
 	case ZclDataTypeInt56:
-		value = c.ReadInt(binary.LittleEndian, 7)
+		value = readInt(c, 7)
 	case ZclDataTypeInt64:
-		value = c.ReadInt(binary.LittleEndian, 8)
+		value = readInt(c, 8)
 	case ZclDataTypeEnum8:
 		value = c.ReadUint(binary.LittleEndian, 1)
 	case ZclDataTypeEnum16:
 		value = c.ReadUint(binary.LittleEndian, 2)
 	case ZclDataTypeSemiPrec:
+		b, _ := c.ReadUint16le()
+		value = halfToFloat32(b)
 	case ZclDataTypeSinglePrec:
+		b, _ := c.ReadUint32le()
+		value = math.Float32frombits(b)
 	case ZclDataTypeDoublePrec:
+		b, _ := c.ReadUint64le()
+		value = math.Float64frombits(b)
 	case ZclDataTypeOctetStr:
 		len, _ := c.ReadByte()
 		value, _ = c.ReadString(int(len))
@@ -523,16 +603,20 @@ func readAttribute(c *composer.Composer) (dataType ZclDataType, value interface{
 	case ZclDataTypeLongCharStr:
 		len, _ := c.ReadUint16le()
 		value, _ = c.ReadString(int(len))
-	case ZclDataTypeArray, ZclDataTypeSet, ZclDataTypeBag:
+	case ZclDataTypeArray, ZclDataTypeSet, ZclDataTypeBag, ZclDataTypeStruct:
 		len, _ := c.ReadUint16le()
-		arr := make([]*Attribute, len)
+		// the number of elements isn't trusted to preallocate them, truncated frames end the array
+		arr := []*Attribute{}
 		for i := 0; i < int(len); i++ {
-			attribute := &Attribute{}
-			attribute.DataType, attribute.Value = readAttribute(c)
-			arr[i] = attribute
+			dt, err := c.ReadByte()
+			if err != nil {
+				break
+			}
+			attribute := &Attribute{DataType: ZclDataType(dt)}
+			attribute.Value = readAttributeValue(c, attribute.DataType)
+			arr = append(arr, attribute)
 		}
 		value = arr
-	case ZclDataTypeStruct:
 	case ZclDataTypeTod:
 		hours, _ := c.ReadUint8()
 		minutes, _ := c.ReadUint8()
@@ -566,6 +650,212 @@ func readAttribute(c *composer.Composer) (dataType ZclDataType, value interface{
 	return
 }
 
+// Serialize writes the reportable change without a type prefix and only for analog data types.
+func (r *AttributeReportingConfigurationRecord) Serialize(w io.Writer) {
+	c := composer.NewWithW(w)
+	c.Uint8(uint8(r.Direction))
+	c.Uint16le(r.AttributeID)
+	switch r.Direction {
+	case ReportDirectionAttributeReported:
+		c.Uint8(uint8(r.AttributeDataType))
+		c.Uint16le(r.MinimumReportingInterval)
+		c.Uint16le(r.MaximumReportingInterval)
+		writeReportableChange(c, r.AttributeDataType, r.ReportableChange)
+	case ReportDirectionAttributeReceived:
+		c.Uint16le(r.TimeoutPeriod)
+	}
+	c.Flush()
+}
+
+func (r *AttributeReportingConfigurationRecord) Deserialize(rd io.Reader) {
+	c := composer.NewWithR(rd)
+	direction, _ := c.ReadByte()
+	r.Direction = ReportDirection(direction)
+	r.AttributeID, _ = c.ReadUint16le()
+	switch r.Direction {
+	case ReportDirectionAttributeReported:
+		dataType, _ := c.ReadByte()
+		r.AttributeDataType = ZclDataType(dataType)
+		r.MinimumReportingInterval, _ = c.ReadUint16le()
+		r.MaximumReportingInterval, _ = c.ReadUint16le()
+		r.ReportableChange = readReportableChange(c, r.AttributeDataType)
+	case ReportDirectionAttributeReceived:
+		r.TimeoutPeriod, _ = c.ReadUint16le()
+	}
+}
+
+func (r *AttributeReportingConfigurationResponseRecord) Serialize(w io.Writer) {
+	c := composer.NewWithW(w)
+	c.Uint8(uint8(r.Status))
+	c.Uint8(uint8(r.Direction))
+	c.Uint16le(r.AttributeID)
+	if r.Status == ZclStatusSuccess {
+		switch r.Direction {
+		case ReportDirectionAttributeReported:
+			c.Uint8(uint8(r.AttributeDataType))
+			c.Uint16le(r.MinimumReportingInterval)
+			c.Uint16le(r.MaximumReportingInterval)
+			writeReportableChange(c, r.AttributeDataType, r.ReportableChange)
+		case ReportDirectionAttributeReceived:
+			c.Uint16le(r.TimeoutPeriod)
+		}
+	}
+	c.Flush()
+}
+
+func (r *AttributeReportingConfigurationResponseRecord) Deserialize(rd io.Reader) {
+	c := composer.NewWithR(rd)
+	status, _ := c.ReadByte()
+	r.Status = ZclStatus(status)
+	direction, _ := c.ReadByte()
+	r.Direction = ReportDirection(direction)
+	r.AttributeID, _ = c.ReadUint16le()
+	if r.Status != ZclStatusSuccess {
+		return
+	}
+	switch r.Direction {
+	case ReportDirectionAttributeReported:
+		dataType, _ := c.ReadByte()
+		r.AttributeDataType = ZclDataType(dataType)
+		r.MinimumReportingInterval, _ = c.ReadUint16le()
+		r.MaximumReportingInterval, _ = c.ReadUint16le()
+		r.ReportableChange = readReportableChange(c, r.AttributeDataType)
+	case ReportDirectionAttributeReceived:
+		r.TimeoutPeriod, _ = c.ReadUint16le()
+	}
+}
+
+// writeReportableChange writes the change as a value of the attribute's own data type. A missing
+// change is written as zero.
+func writeReportableChange(c *composer.Composer, dataType ZclDataType, change *Attribute) {
+	if !dataType.Analog() {
+		return
+	}
+	if change == nil || change.Value == nil {
+		c.Bytes(make([]byte, analogWidth(dataType)))
+		return
+	}
+	writeAttributeValue(c, dataType, change.Value)
+}
+
+func readReportableChange(c *composer.Composer, dataType ZclDataType) *Attribute {
+	if !dataType.Analog() {
+		return nil
+	}
+	return &Attribute{DataType: dataType, Value: readAttributeValue(c, dataType)}
+}
+
+// analogWidth returns the size in bytes of a value of an analog data type.
+func analogWidth(t ZclDataType) int {
+	switch {
+	case t >= ZclDataTypeUint8 && t <= ZclDataTypeUint64:
+		return int(t-ZclDataTypeUint8) + 1
+	case t >= ZclDataTypeInt8 && t <= ZclDataTypeInt64:
+		return int(t-ZclDataTypeInt8) + 1
+	case t == ZclDataTypeSemiPrec:
+		return 2
+	case t == ZclDataTypeDoublePrec:
+		return 8
+	}
+	return 4
+}
+
+// ValidAttributeValue reports whether value has the Go type writeAttribute expects for dataType.
+func ValidAttributeValue(dataType ZclDataType, value interface{}) bool {
+	switch dataType {
+	case ZclDataTypeNoData:
+		return value == nil
+	case ZclDataTypeData8:
+		_, ok := value.([1]byte)
+		return ok
+	case ZclDataTypeData16:
+		_, ok := value.([2]byte)
+		return ok
+	case ZclDataTypeData24:
+		_, ok := value.([3]byte)
+		return ok
+	case ZclDataTypeData32:
+		_, ok := value.([4]byte)
+		return ok
+	case ZclDataTypeData40:
+		_, ok := value.([5]byte)
+		return ok
+	case ZclDataTypeData48:
+		_, ok := value.([6]byte)
+		return ok
+	case ZclDataTypeData56:
+		_, ok := value.([7]byte)
+		return ok
+	case ZclDataTypeData64:
+		_, ok := value.([8]byte)
+		return ok
+	case ZclDataTypeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case ZclDataTypeSemiPrec, ZclDataTypeSinglePrec:
+		_, ok := value.(float32)
+		return ok
+	case ZclDataTypeDoublePrec:
+		_, ok := value.(float64)
+		return ok
+	case ZclDataTypeBitmap8, ZclDataTypeBitmap16, ZclDataTypeBitmap24, ZclDataTypeBitmap32,
+		ZclDataTypeBitmap40, ZclDataTypeBitmap48, ZclDataTypeBitmap56, ZclDataTypeBitmap64,
+		ZclDataTypeUint8, ZclDataTypeUint16, ZclDataTypeUint24, ZclDataTypeUint32,
+		ZclDataTypeUint40, ZclDataTypeUint48, ZclDataTypeUint56, ZclDataTypeUint64,
+		ZclDataTypeEnum8, ZclDataTypeEnum16:
+		_, ok := value.(uint64)
+		return ok
+	case ZclDataTypeInt8, ZclDataTypeInt16, ZclDataTypeInt24, ZclDataTypeInt32,
+		ZclDataTypeInt40, ZclDataTypeInt48, ZclDataTypeInt56, ZclDataTypeInt64:
+		_, ok := value.(int64)
+		return ok
+	case ZclDataTypeOctetStr, ZclDataTypeCharStr, ZclDataTypeLongOctetStr, ZclDataTypeLongCharStr:
+		_, ok := value.(string)
+		return ok
+	case ZclDataTypeArray, ZclDataTypeSet, ZclDataTypeBag, ZclDataTypeStruct:
+		attributes, ok := value.([]*Attribute)
+		if !ok {
+			return false
+		}
+		for _, attribute := range attributes {
+			if attribute == nil || !ValidAttributeValue(attribute.DataType, attribute.Value) {
+				return false
+			}
+		}
+		return true
+	case ZclDataTypeTod:
+		v, ok := value.(*TimeOfDay)
+		return ok && v != nil
+	case ZclDataTypeDate:
+		v, ok := value.(*Date)
+		return ok && v != nil
+	case ZclDataTypeUtc, ZclDataTypeBacOid:
+		_, ok := value.(uint32)
+		return ok
+	case ZclDataTypeClusterId, ZclDataTypeAttrId:
+		_, ok := value.(uint16)
+		return ok
+	case ZclDataTypeIeeeAddr:
+		v, ok := value.(string)
+		return ok && len(v) > 2 && v[:2] == "0x"
+	case ZclDataType_128BitSecKey:
+		_, ok := value.([16]byte)
+		return ok
+	}
+	return false
+}
+
+// Analog reports whether values of the data type are compared against a reportable change threshold.
+func (t ZclDataType) Analog() bool {
+	switch {
+	case t >= ZclDataTypeUint8 && t <= ZclDataTypeInt64,
+		t >= ZclDataTypeSemiPrec && t <= ZclDataTypeDoublePrec,
+		t >= ZclDataTypeTod && t <= ZclDataTypeUtc:
+		return true
+	}
+	return false
+}
+
 func flag(boolean bool) uint8 {
 	if boolean {
 		return 1
@@ -1,5 +1,11 @@
 package cluster
 
+import (
+	"io"
+
+	"github.com/dyrkin/composer"
+)
+
 type ResetToFactoryDefaultsCommand struct {
 }
 
@@ -111,7 +117,7 @@ type ZoneEnrollCommand struct {
 
 type ArmCommand struct {
 	ArmMode       uint8
-	ArmDisarmCode string
+	ArmDisarmCode string `size:"1"`
 	ZoneID        uint8
 }
 
@@ -121,6 +127,24 @@ type BypassCommand struct {
 	ArmDisarmCode string
 }
 
+// Serialize writes the number of zone ids ZoneID holds, whatever NumberOfZones says.
+func (b *BypassCommand) Serialize(w io.Writer) {
+	c := composer.NewWithW(w)
+	c.Uint8(uint8(len(b.ZoneID)))
+	c.Bytes(b.ZoneID)
+	c.Uint8(uint8(len(b.ArmDisarmCode)))
+	c.String(b.ArmDisarmCode)
+	c.Flush()
+}
+
+func (b *BypassCommand) Deserialize(r io.Reader) {
+	c := composer.NewWithR(r)
+	b.NumberOfZones, _ = c.ReadByte()
+	b.ZoneID, _ = c.ReadBytes(int(b.NumberOfZones))
+	length, _ := c.ReadByte()
+	b.ArmDisarmCode, _ = c.ReadString(int(length))
+}
+
 type EmergencyCommand struct{}
 
 type FireCommand struct{}
@@ -143,6 +167,24 @@ type GetZoneStatus struct {
 	ZoneStatusMask     uint16
 }
 
+func (g *GetZoneStatus) Serialize(w io.Writer) {
+	c := composer.NewWithW(w)
+	c.Uint8(g.StartingZoneID)
+	c.Uint8(g.MaxNumberZoneIDs)
+	c.Uint8(flag(g.ZoneStatusMaskFlag))
+	c.Uint16le(g.ZoneStatusMask)
+	c.Flush()
+}
+
+func (g *GetZoneStatus) Deserialize(r io.Reader) {
+	c := composer.NewWithR(r)
+	g.StartingZoneID, _ = c.ReadByte()
+	g.MaxNumberZoneIDs, _ = c.ReadByte()
+	maskFlag, _ := c.ReadByte()
+	g.ZoneStatusMaskFlag = maskFlag != 0
+	g.ZoneStatusMask, _ = c.ReadUint16le()
+}
+
 type ArmResponse struct {
 	ArmNotification uint8
 }
@@ -170,14 +212,14 @@ type GetZoneInformationResponse struct {
 	ZoneId      uint8
 	ZoneType    uint16
 	IEEEAddress [6]byte
-	ZoneLabel   string
+	ZoneLabel   string `size:"1"`
 }
 
 type ZoneStatusChanged struct {
 	ZoneId              uint8
 	ZoneStatus          uint16
 	AudibleNotification uint8
-	ZoneLabel           string
+	ZoneLabel           string `size:"1"`
 }
 
 type PanelStatusChanged struct {
@@ -199,6 +241,40 @@ type GetZoneStatusResponse struct {
 	ZoneStatus         []uint16
 }
 
+// Serialize writes a zone id and status pair for every zone id. The zone ids are 8 bits wide on
+// the wire and missing statuses are written as zero.
+func (g *GetZoneStatusResponse) Serialize(w io.Writer) {
+	c := composer.NewWithW(w)
+	c.Uint8(flag(g.ZoneStatusComplete))
+	c.Uint8(uint8(len(g.ZoneID)))
+	for i, id := range g.ZoneID {
+		var status uint16
+		if i < len(g.ZoneStatus) {
+			status = g.ZoneStatus[i]
+		}
+		c.Uint8(uint8(id))
+		c.Uint16le(status)
+	}
+	c.Flush()
+}
+
+func (g *GetZoneStatusResponse) Deserialize(r io.Reader) {
+	c := composer.NewWithR(r)
+	complete, _ := c.ReadByte()
+	g.ZoneStatusComplete = complete != 0
+	g.NumberOfZones, _ = c.ReadByte()
+	g.ZoneID, g.ZoneStatus = []uint16{}, []uint16{}
+	for i := 0; i < int(g.NumberOfZones); i++ {
+		id, err := c.ReadByte()
+		if err != nil {
+			return
+		}
+		status, _ := c.ReadUint16le()
+		g.ZoneID = append(g.ZoneID, uint16(id))
+		g.ZoneStatus = append(g.ZoneStatus, status)
+	}
+}
+
 type GetProfileInfoResponse struct {
 	ProfileCount          uint8
 	ProfileIntervalPeriod uint8
@@ -249,7 +325,7 @@ type GetAlarmResponse struct {
 
 type AddGroupCommand struct {
 	GroupID   uint16
-	GroupName string
+	GroupName string `size:"1"`
 }
 
 type ViewGroupCommand struct {
@@ -269,7 +345,7 @@ type RemoveAllGroupsCommand struct{}
 
 type AddGroupIfIdentifyingCommand struct {
 	GroupID   uint16
-	GroupName string
+	GroupName string `size:"1"`
 }
 
 type AddGroupResponse struct {
@@ -280,7 +356,7 @@ type AddGroupResponse struct {
 type ViewGroupResponse struct {
 	Status    uint8
 	GroupID   uint16
-	GroupName string
+	GroupName string `size:"1"`
 }
 
 type GetGroupMembershipResponse struct {
@@ -298,7 +374,7 @@ type AddSceneCommand struct {
 	GroupID        uint16
 	SceneID        uint8
 	TransitionTime uint16
-	SceneName      string
+	SceneName      string `size:"1"`
 }
 
 type ViewSceneCommand struct {
@@ -333,7 +409,7 @@ type EnhancedAddSceneCommand struct {
 	GroupID        uint16
 	SceneID        uint8
 	TransitionTime uint16
-	SceneName      string
+	SceneName      string `size:"1"`
 }
 
 type EnhancedViewSceneCommand struct {
@@ -360,7 +436,7 @@ type ViewSceneResponse struct {
 	GroupID        uint16
 	SceneID        uint8
 	TransitionTime uint16
-	SceneName      string
+	SceneName      string `size:"1"`
 }
 
 type RemoveSceneResponse struct {
@@ -399,7 +475,7 @@ type EnhancedViewSceneResponse struct {
 	GroupID        uint16
 	SceneID        uint8
 	TransitionTime uint16
-	SceneName      string
+	SceneName      string `size:"1"`
 }
 
 type CopySceneResponse struct {
@@ -414,6 +490,21 @@ type CheckInResponse struct {
 	StartFastPolling bool
 	FastPollTimeout  uint16
 }
+
+func (r *CheckInResponse) Serialize(w io.Writer) {
+	c := composer.NewWithW(w)
+	c.Uint8(flag(r.StartFastPolling))
+	c.Uint16le(r.FastPollTimeout)
+	c.Flush()
+}
+
+func (r *CheckInResponse) Deserialize(rd io.Reader) {
+	c := composer.NewWithR(rd)
+	startFastPolling, _ := c.ReadByte()
+	r.StartFastPolling = startFastPolling != 0
+	r.FastPollTimeout, _ = c.ReadUint16le()
+}
+
 type FastPollStopCommand struct{}
 
 type SetLongPollIntervalCommand struct {
@@ -474,12 +565,40 @@ type MoveColorCommand struct {
 	RateY int16
 }
 
+func (m *MoveColorCommand) Serialize(w io.Writer) {
+	c := composer.NewWithW(w)
+	c.Int16le(m.RateX)
+	c.Int16le(m.RateY)
+	c.Flush()
+}
+
+func (m *MoveColorCommand) Deserialize(r io.Reader) {
+	c := composer.NewWithR(r)
+	m.RateX, _ = c.ReadInt16le()
+	m.RateY, _ = c.ReadInt16le()
+}
+
 type StepColorCommand struct {
 	RateX          int16
 	RateY          int16
 	TransitionTime uint16
 }
 
+func (s *StepColorCommand) Serialize(w io.Writer) {
+	c := composer.NewWithW(w)
+	c.Int16le(s.RateX)
+	c.Int16le(s.RateY)
+	c.Uint16le(s.TransitionTime)
+	c.Flush()
+}
+
+func (s *StepColorCommand) Deserialize(r io.Reader) {
+	c := composer.NewWithR(r)
+	s.RateX, _ = c.ReadInt16le()
+	s.RateY, _ = c.ReadInt16le()
+	s.TransitionTime, _ = c.ReadUint16le()
+}
+
 type MoveToColorTemperatureCommand struct {
 	ColorTemperatureMireds uint16
 	TransitionTime         uint16
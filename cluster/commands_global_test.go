@@ -1,6 +1,7 @@
 package cluster
 
 import (
+	"math"
 	"testing"
 
 	"github.com/dyrkin/bin"
@@ -66,3 +67,83 @@ func (s *CommandsGlobalSuite) TestEncodeReadAttributesResponse(c *C) {
 	}
 	c.Assert(res, DeepEquals, expected)
 }
+
+func (s *CommandsGlobalSuite) TestDecodeConfigureReportingResponse(c *C) {
+	res := &ConfigureReportingResponse{}
+	bin.Decode([]byte{0x00}, res)
+	c.Assert(res, DeepEquals, &ConfigureReportingResponse{[]*AttributeStatusRecord{{Status: ZclStatusSuccess}}})
+
+	res = &ConfigureReportingResponse{}
+	bin.Decode([]byte{0x8c, 0x00, 0x00, 0x40, 0x86, 0x00, 0x34, 0x12}, res)
+	c.Assert(res, DeepEquals, &ConfigureReportingResponse{[]*AttributeStatusRecord{
+		{ZclStatusUnreportableAttribute, ReportDirectionAttributeReported, "", 0x4000},
+		{ZclStatusUnsupportedAttribute, ReportDirectionAttributeReported, "", 0x1234},
+	}})
+}
+
+func (s *CommandsGlobalSuite) TestConfigureReportingReportableChange(c *C) {
+	cmd := &ConfigureReportingCommand{[]*AttributeReportingConfigurationRecord{
+		{AttributeID: 0x0000, AttributeDataType: ZclDataTypeBoolean, MinimumReportingInterval: 0, MaximumReportingInterval: 300},
+		{AttributeID: 0x0000, AttributeDataType: ZclDataTypeInt16, MinimumReportingInterval: 10, MaximumReportingInterval: 600,
+			ReportableChange: &Attribute{ZclDataTypeInt16, int64(50)}},
+		{AttributeID: 0x0001, AttributeDataType: ZclDataTypeBitmap8, MaximumReportingInterval: 60},
+		{Direction: ReportDirectionAttributeReceived, AttributeID: 0x0002, TimeoutPeriod: 900},
+	}}
+	encoded := []byte{
+		0x00, 0x00, 0x00, byte(ZclDataTypeBoolean), 0x00, 0x00, 0x2c, 0x01,
+		0x00, 0x00, 0x00, byte(ZclDataTypeInt16), 0x0a, 0x00, 0x58, 0x02, 0x32, 0x00,
+		0x00, 0x01, 0x00, byte(ZclDataTypeBitmap8), 0x00, 0x00, 0x3c, 0x00,
+		0x01, 0x02, 0x00, 0x84, 0x03,
+	}
+	c.Assert(bin.Encode(cmd), DeepEquals, encoded)
+	res := &ConfigureReportingCommand{}
+	bin.Decode(encoded, res)
+	c.Assert(res, DeepEquals, cmd)
+}
+
+func (s *CommandsGlobalSuite) TestReadReportingConfigurationResponseReportableChange(c *C) {
+	encoded := []byte{
+		0x00, 0x00, 0x00, 0x00, byte(ZclDataTypeUint16), 0x01, 0x00, 0x10, 0x0e, 0x64, 0x00,
+		0x8b, 0x00, 0x01, 0x00,
+		0x00, 0x00, 0x02, 0x00, byte(ZclDataTypeEnum8), 0x00, 0x00, 0x10, 0x0e,
+		0x00, 0x00, 0x03, 0x00, byte(ZclDataTypeSinglePrec), 0x00, 0x00, 0x10, 0x0e, 0x00, 0x00, 0x80, 0x3f,
+	}
+	res := &ReadReportingConfigurationResponse{}
+	bin.Decode(encoded, res)
+	c.Assert(res, DeepEquals, &ReadReportingConfigurationResponse{[]*AttributeReportingConfigurationResponseRecord{
+		{AttributeID: 0x0000, AttributeDataType: ZclDataTypeUint16, MinimumReportingInterval: 1, MaximumReportingInterval: 3600,
+			ReportableChange: &Attribute{ZclDataTypeUint16, uint64(100)}},
+		{Status: ZclStatusNotFound, AttributeID: 0x0001},
+		{AttributeID: 0x0002, AttributeDataType: ZclDataTypeEnum8, MaximumReportingInterval: 3600},
+		{AttributeID: 0x0003, AttributeDataType: ZclDataTypeSinglePrec, MaximumReportingInterval: 3600,
+			ReportableChange: &Attribute{ZclDataTypeSinglePrec, float32(1)}},
+	}})
+}
+
+func (s *CommandsGlobalSuite) TestHalfPrecision(c *C) {
+	for _, v := range []struct {
+		half  uint16
+		float float32
+	}{
+		{0x3c00, 1},
+		{0xc000, -2},
+		{0x7bff, 65504},
+		{0x0001, 1.0 / (1 << 24)},
+		{0x0400, 1.0 / (1 << 14)},
+		{0x7c00, float32(math.Inf(1))},
+	} {
+		c.Assert(halfToFloat32(v.half), Equals, v.float)
+		c.Assert(float32ToHalf(v.float), Equals, v.half)
+	}
+	// halfway values round to the even mantissa, values beyond the range become infinite
+	c.Assert(float32ToHalf(1+1.0/(1<<11)), Equals, uint16(0x3c00))
+	c.Assert(float32ToHalf(1+3.0/(1<<11)), Equals, uint16(0x3c02))
+	c.Assert(float32ToHalf(65520), Equals, uint16(0x7c00))
+	c.Assert(float32ToHalf(1.0/(1<<26)), Equals, uint16(0))
+
+	a := &Attribute{}
+	bin.Decode([]byte{byte(ZclDataTypeSemiPrec), 0x00, 0x3c}, a)
+	c.Assert(a, DeepEquals, &Attribute{ZclDataTypeSemiPrec, float32(1)})
+	c.Assert(bin.Encode(&Attribute{ZclDataTypeDoublePrec, 1.5}), DeepEquals,
+		[]byte{byte(ZclDataTypeDoublePrec), 0, 0, 0, 0, 0, 0, 0xf8, 0x3f})
+}
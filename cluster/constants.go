@@ -61,6 +61,65 @@ const (
 	ZclDataTypeUnknown       ZclDataType = 0xff
 )
 
+var zclDataTypeNames = map[ZclDataType]string{
+	ZclDataTypeNoData:        "NoData",
+	ZclDataTypeData8:         "Data8",
+	ZclDataTypeData16:        "Data16",
+	ZclDataTypeData24:        "Data24",
+	ZclDataTypeData32:        "Data32",
+	ZclDataTypeData40:        "Data40",
+	ZclDataTypeData48:        "Data48",
+	ZclDataTypeData56:        "Data56",
+	ZclDataTypeData64:        "Data64",
+	ZclDataTypeBoolean:       "Boolean",
+	ZclDataTypeBitmap8:       "Bitmap8",
+	ZclDataTypeBitmap16:      "Bitmap16",
+	ZclDataTypeBitmap24:      "Bitmap24",
+	ZclDataTypeBitmap32:      "Bitmap32",
+	ZclDataTypeBitmap40:      "Bitmap40",
+	ZclDataTypeBitmap48:      "Bitmap48",
+	ZclDataTypeBitmap56:      "Bitmap56",
+	ZclDataTypeBitmap64:      "Bitmap64",
+	ZclDataTypeUint8:         "Uint8",
+	ZclDataTypeUint16:        "Uint16",
+	ZclDataTypeUint24:        "Uint24",
+	ZclDataTypeUint32:        "Uint32",
+	ZclDataTypeUint40:        "Uint40",
+	ZclDataTypeUint48:        "Uint48",
+	ZclDataTypeUint56:        "Uint56",
+	ZclDataTypeUint64:        "Uint64",
+	ZclDataTypeInt8:          "Int8",
+	ZclDataTypeInt16:         "Int16",
+	ZclDataTypeInt24:         "Int24",
+	ZclDataTypeInt32:         "Int32",
+	ZclDataTypeInt40:         "Int40",
+	ZclDataTypeInt48:         "Int48",
+	ZclDataTypeInt56:         "Int56",
+	ZclDataTypeInt64:         "Int64",
+	ZclDataTypeEnum8:         "Enum8",
+	ZclDataTypeEnum16:        "Enum16",
+	ZclDataTypeSemiPrec:      "SemiPrec",
+	ZclDataTypeSinglePrec:    "SinglePrec",
+	ZclDataTypeDoublePrec:    "DoublePrec",
+	ZclDataTypeOctetStr:      "OctetStr",
+	ZclDataTypeCharStr:       "CharStr",
+	ZclDataTypeLongOctetStr:  "LongOctetStr",
+	ZclDataTypeLongCharStr:   "LongCharStr",
+	ZclDataTypeArray:         "Array",
+	ZclDataTypeStruct:        "Struct",
+	ZclDataTypeSet:           "Set",
+	ZclDataTypeBag:           "Bag",
+	ZclDataTypeTod:           "Tod",
+	ZclDataTypeDate:          "Date",
+	ZclDataTypeUtc:           "Utc",
+	ZclDataTypeClusterId:     "ClusterId",
+	ZclDataTypeAttrId:        "AttrId",
+	ZclDataTypeBacOid:        "BacOid",
+	ZclDataTypeIeeeAddr:      "IeeeAddr",
+	ZclDataType_128BitSecKey: "128BitSecKey",
+	ZclDataTypeUnknown:       "Unknown",
+}
+
 type ZclStatus uint8
 
 const (
@@ -103,6 +162,42 @@ const (
 	ZclStatusCmdHasRsp ZclStatus = 0xFF // Non-standard status (used for Default Rsp)
 )
 
+var zclStatusNames = map[ZclStatus]string{
+	ZclStatusSuccess:                  "Success",
+	ZclStatusFailure:                  "Failure",
+	ZclStatusNotAuthorized:            "NotAuthorized",
+	ZclStatusMalformedCommand:         "MalformedCommand",
+	ZclStatusUnsupClusterCommand:      "UnsupClusterCommand",
+	ZclStatusUnsupGeneralCommand:      "UnsupGeneralCommand",
+	ZclStatusUnsupManuClusterCommand:  "UnsupManuClusterCommand",
+	ZclStatusUnsupManuGeneralCommand:  "UnsupManuGeneralCommand",
+	ZclStatusInvalidField:             "InvalidField",
+	ZclStatusUnsupportedAttribute:     "UnsupportedAttribute",
+	ZclStatusInvalidValue:             "InvalidValue",
+	ZclStatusReadOnly:                 "ReadOnly",
+	ZclStatusInsufficientSpace:        "InsufficientSpace",
+	ZclStatusDuplicateExists:          "DuplicateExists",
+	ZclStatusNotFound:                 "NotFound",
+	ZclStatusUnreportableAttribute:    "UnreportableAttribute",
+	ZclStatusInvalidDataType:          "InvalidDataType",
+	ZclStatusInvalidSelector:          "InvalidSelector",
+	ZclStatusWriteOnly:                "WriteOnly",
+	ZclStatusInconsistentStartupState: "InconsistentStartupState",
+	ZclStatusDefinedOutOfBand:         "DefinedOutOfBand",
+	ZclStatusInconsistent:             "Inconsistent",
+	ZclStatusActionDenied:             "ActionDenied",
+	ZclStatusTimeout:                  "Timeout",
+	ZclStatusAbort:                    "Abort",
+	ZclStatusInvalidImage:             "InvalidImage",
+	ZclStatusWaitForData:              "WaitForData",
+	ZclStatusNoImageAvailable:         "NoImageAvailable",
+	ZclStatusRequireMoreImage:         "RequireMoreImage",
+	ZclStatusHardwareFailure:          "HardwareFailure",
+	ZclStatusSoftwareFailure:          "SoftwareFailure",
+	ZclStatusCalibrationError:         "CalibrationError",
+	ZclStatusCmdHasRsp:                "CmdHasRsp",
+}
+
 type ZclCommand uint8
 
 const (
@@ -131,9 +226,40 @@ const (
 	ZclCommandDiscoverAttributesExtendedResponse ZclCommand = 0x16
 )
 
+var zclCommandNames = map[ZclCommand]string{
+	ZclCommandReadAttributes:                     "ReadAttributes",
+	ZclCommandReadAttributesResponse:             "ReadAttributesResponse",
+	ZclCommandWriteAttributes:                    "WriteAttributes",
+	ZclCommandWriteAttributesUndivided:           "WriteAttributesUndivided",
+	ZclCommandWriteAttributesResponse:            "WriteAttributesResponse",
+	ZclCommandWriteAttributesNoResponse:          "WriteAttributesNoResponse",
+	ZclCommandConfigureReporting:                 "ConfigureReporting",
+	ZclCommandConfigureReportingResponse:         "ConfigureReportingResponse",
+	ZclCommandReadReportingConfiguration:         "ReadReportingConfiguration",
+	ZclCommandReadReportingConfigurationResponse: "ReadReportingConfigurationResponse",
+	ZclCommandReportAttributes:                   "ReportAttributes",
+	ZclCommandDefaultResponse:                    "DefaultResponse",
+	ZclCommandDiscoverAttributes:                 "DiscoverAttributes",
+	ZclCommandDiscoverAttributesResponse:         "DiscoverAttributesResponse",
+	ZclCommandReadAttributesStructured:           "ReadAttributesStructured",
+	ZclCommandWriteAttributesStructured:          "WriteAttributesStructured",
+	ZclCommandWriteAttributesStructuredResponse:  "WriteAttributesStructuredResponse",
+	ZclCommandDiscoverCommandsReceived:           "DiscoverCommandsReceived",
+	ZclCommandDiscoverCommandsReceivedResponse:   "DiscoverCommandsReceivedResponse",
+	ZclCommandDiscoverCommandsGenerated:          "DiscoverCommandsGenerated",
+	ZclCommandDiscoverCommandsGeneratedResponse:  "DiscoverCommandsGeneratedResponse",
+	ZclCommandDiscoverAttributesExtended:         "DiscoverAttributesExtended",
+	ZclCommandDiscoverAttributesExtendedResponse: "DiscoverAttributesExtendedResponse",
+}
+
 type ReportDirection uint8
 
 const (
 	ReportDirectionAttributeReported ReportDirection = 0x00
 	ReportDirectionAttributeReceived ReportDirection = 0x01
 )
+
+var reportDirectionNames = map[ReportDirection]string{
+	ReportDirectionAttributeReported: "AttributeReported",
+	ReportDirectionAttributeReceived: "AttributeReceived",
+}
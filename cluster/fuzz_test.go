@@ -0,0 +1,40 @@
+package cluster
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dyrkin/bin"
+	"github.com/dyrkin/composer"
+	"github.com/dyrkin/zcl-go/internal/alloc"
+)
+
+// Decoding may allocate the largest string a frame can announce plus a bounded amount per byte of
+// input, never memory in proportion to lengths or counts read from the frame.
+func allocationLimit(n int) uint64 {
+	return 1<<18 + 512*uint64(n)
+}
+
+func FuzzReadAttribute(f *testing.F) {
+	f.Add([]byte{byte(ZclDataTypeInt24), 0xf7, 0x80, 0xff})
+	f.Add([]byte{byte(ZclDataTypeCharStr), 0x03, 'a', 'b', 'c'})
+	f.Add([]byte{byte(ZclDataTypeLongOctetStr), 0xff, 0xff})
+	f.Add([]byte{byte(ZclDataTypeIeeeAddr), 0x01, 0x02})
+	f.Add([]byte{byte(ZclDataTypeArray), 0xff, 0xff, byte(ZclDataTypeStruct), 0xff, 0xff})
+	f.Add([]byte{byte(ZclDataTypeArray), 0x02, 0x00, byte(ZclDataTypeTod), 1, 2, 3, 4, byte(ZclDataTypeDate), 1, 2, 3, 4})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		a := &Attribute{}
+		allocated := alloc.Bytes(func() {
+			a.DataType, a.Value = readAttribute(composer.NewWithR(bytes.NewReader(data)))
+		})
+		if allocated > allocationLimit(len(data)) {
+			t.Fatalf("decoding %d bytes allocated %d bytes", len(data), allocated)
+		}
+		decoded := &Attribute{}
+		bin.Decode(bin.Encode(a), decoded)
+		if !reflect.DeepEqual(decoded, a) {
+			t.Fatalf("%x decoded to %#v, which decodes to %#v once encoded", data, a, decoded)
+		}
+	})
+}
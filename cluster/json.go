@@ -0,0 +1,225 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+func (t ZclDataType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON accepts whatever ParseZclDataType accepts or a number.
+func (t *ZclDataType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return json.Unmarshal(data, (*uint8)(t))
+	}
+	v, err := ParseZclDataType(s)
+	*t = v
+	return err
+}
+
+func (s ZclStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON accepts whatever ParseZclStatus accepts or a number.
+func (s *ZclStatus) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return json.Unmarshal(data, (*uint8)(s))
+	}
+	v, err := ParseZclStatus(str)
+	*s = v
+	return err
+}
+
+type attributeJSON struct {
+	DataType ZclDataType     `json:"dataType"`
+	Value    json.RawMessage `json:"value,omitempty"`
+	// Hex holds character strings which aren't valid UTF-8.
+	Hex string `json:"hex,omitempty"`
+}
+
+// MarshalJSON writes the data type and a value readable without knowing the Go representation of
+// the type. Bitmaps, data, octet strings, keys and cluster and attribute identifiers are written
+// as hex strings, infinite and NaN floats as strings. Values not matching their data type, e.g. replaced by a quirk, are written as
+// they are.
+func (a *Attribute) MarshalJSON() ([]byte, error) {
+	aj := &attributeJSON{DataType: a.DataType}
+	var value interface{} = a.Value
+	if ValidAttributeValue(a.DataType, a.Value) {
+		switch t := a.DataType; {
+		case t >= ZclDataTypeData8 && t <= ZclDataTypeData64, t == ZclDataType_128BitSecKey:
+			v := reflect.ValueOf(a.Value)
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			value = "0x" + hex.EncodeToString(b)
+		case t >= ZclDataTypeBitmap8 && t <= ZclDataTypeBitmap64:
+			value = fmt.Sprintf("0x%0*x", 2*int(t-ZclDataTypeBitmap8+1), a.Value)
+		case t == ZclDataTypeOctetStr, t == ZclDataTypeLongOctetStr:
+			value = "0x" + hex.EncodeToString([]byte(a.Value.(string)))
+		case t == ZclDataTypeCharStr, t == ZclDataTypeLongCharStr:
+			if s := a.Value.(string); !utf8.ValidString(s) {
+				aj.Hex = "0x" + hex.EncodeToString([]byte(s))
+				value = nil
+			}
+		case t == ZclDataTypeClusterId, t == ZclDataTypeAttrId:
+			value = fmt.Sprintf("0x%04x", a.Value)
+		case t == ZclDataTypeSemiPrec, t == ZclDataTypeSinglePrec:
+			if f := float64(a.Value.(float32)); math.IsInf(f, 0) || math.IsNaN(f) {
+				value = strconv.FormatFloat(f, 'g', -1, 32)
+			}
+		case t == ZclDataTypeDoublePrec:
+			if f := a.Value.(float64); math.IsInf(f, 0) || math.IsNaN(f) {
+				value = strconv.FormatFloat(f, 'g', -1, 64)
+			}
+		}
+	}
+	if value != nil {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		aj.Value = raw
+	}
+	return json.Marshal(aj)
+}
+
+func (a *Attribute) UnmarshalJSON(data []byte) error {
+	aj := &attributeJSON{}
+	if err := json.Unmarshal(data, aj); err != nil {
+		return err
+	}
+	a.DataType = aj.DataType
+	if aj.Hex != "" {
+		b, err := parseHex(aj.Hex)
+		a.Value = string(b)
+		return err
+	}
+	if len(aj.Value) == 0 || string(aj.Value) == "null" {
+		a.Value = nil
+		return nil
+	}
+	value, err := unmarshalAttributeValue(aj.DataType, aj.Value)
+	if err != nil {
+		return fmt.Errorf("invalid %s value: %v", aj.DataType, err)
+	}
+	a.Value = value
+	return nil
+}
+
+func unmarshalAttributeValue(t ZclDataType, raw json.RawMessage) (interface{}, error) {
+	var s string
+	switch {
+	case t >= ZclDataTypeData8 && t <= ZclDataTypeData64, t == ZclDataType_128BitSecKey:
+		size := int(t-ZclDataTypeData8) + 1
+		if t == ZclDataType_128BitSecKey {
+			size = 16
+		}
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		b, err := parseHex(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != size {
+			return nil, fmt.Errorf("expected %d bytes, got %d", size, len(b))
+		}
+		v := reflect.New(reflect.ArrayOf(size, reflect.TypeOf(byte(0)))).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v.Interface(), nil
+	case t == ZclDataTypeBoolean:
+		var b bool
+		err := json.Unmarshal(raw, &b)
+		return b, err
+	case t >= ZclDataTypeBitmap8 && t <= ZclDataTypeBitmap64:
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		return parseHexUint(s, 64)
+	case t >= ZclDataTypeUint8 && t <= ZclDataTypeUint64, t == ZclDataTypeEnum8, t == ZclDataTypeEnum16:
+		var v uint64
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	case t >= ZclDataTypeInt8 && t <= ZclDataTypeInt64:
+		var v int64
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	case t == ZclDataTypeSemiPrec, t == ZclDataTypeSinglePrec:
+		v, err := unmarshalFloat(raw, 32)
+		return float32(v), err
+	case t == ZclDataTypeDoublePrec:
+		return unmarshalFloat(raw, 64)
+	case t == ZclDataTypeOctetStr, t == ZclDataTypeLongOctetStr:
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		b, err := parseHex(s)
+		return string(b), err
+	case t == ZclDataTypeCharStr, t == ZclDataTypeLongCharStr, t == ZclDataTypeIeeeAddr:
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	case t == ZclDataTypeArray, t == ZclDataTypeSet, t == ZclDataTypeBag, t == ZclDataTypeStruct:
+		attributes := []*Attribute{}
+		err := json.Unmarshal(raw, &attributes)
+		return attributes, err
+	case t == ZclDataTypeTod:
+		v := &TimeOfDay{}
+		err := json.Unmarshal(raw, v)
+		return v, err
+	case t == ZclDataTypeDate:
+		v := &Date{}
+		err := json.Unmarshal(raw, v)
+		return v, err
+	case t == ZclDataTypeUtc, t == ZclDataTypeBacOid:
+		var v uint32
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	case t == ZclDataTypeClusterId, t == ZclDataTypeAttrId:
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		v, err := parseHexUint(s, 16)
+		return uint16(v), err
+	}
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	err := d.Decode(&v)
+	return v, err
+}
+
+// unmarshalFloat accepts a number or the strings strconv.ParseFloat accepts, like "NaN" or "+Inf".
+func unmarshalFloat(raw json.RawMessage, bitSize int) (float64, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		var v float64
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	}
+	return strconv.ParseFloat(s, bitSize)
+}
+
+func parseHex(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return nil, fmt.Errorf("%q is not a hex string", s)
+	}
+	return hex.DecodeString(s[2:])
+}
+
+func parseHexUint(s string, bitSize int) (uint64, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return 0, fmt.Errorf("%q is not a hex number", s)
+	}
+	return strconv.ParseUint(s[2:], 16, bitSize)
+}
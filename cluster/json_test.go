@@ -0,0 +1,61 @@
+package cluster
+
+import (
+	"encoding/json"
+	"math"
+
+	. "gopkg.in/check.v1"
+)
+
+type JSONSuite struct{}
+
+var _ = Suite(&JSONSuite{})
+
+func (s *JSONSuite) TestAttributeRoundTrip(c *C) {
+	attributes := []struct {
+		attribute *Attribute
+		json      string
+	}{
+		{&Attribute{ZclDataTypeNoData, nil}, `{"dataType":"NoData"}`},
+		{&Attribute{ZclDataTypeData24, [3]byte{1, 2, 3}}, `{"dataType":"Data24","value":"0x010203"}`},
+		{&Attribute{ZclDataTypeBoolean, false}, `{"dataType":"Boolean","value":false}`},
+		{&Attribute{ZclDataTypeBitmap16, uint64(0x0101)}, `{"dataType":"Bitmap16","value":"0x0101"}`},
+		{&Attribute{ZclDataTypeUint64, uint64(1) << 63}, `{"dataType":"Uint64","value":9223372036854775808}`},
+		{&Attribute{ZclDataTypeInt16, int64(-200)}, `{"dataType":"Int16","value":-200}`},
+		{&Attribute{ZclDataTypeSinglePrec, float32(21.5)}, `{"dataType":"SinglePrec","value":21.5}`},
+		{&Attribute{ZclDataTypeDoublePrec, math.Inf(-1)}, `{"dataType":"DoublePrec","value":"-Inf"}`},
+		{&Attribute{ZclDataTypeEnum8, uint64(3)}, `{"dataType":"Enum8","value":3}`},
+		{&Attribute{ZclDataTypeOctetStr, "\x00\xff"}, `{"dataType":"OctetStr","value":"0x00ff"}`},
+		{&Attribute{ZclDataTypeCharStr, "LUMI"}, `{"dataType":"CharStr","value":"LUMI"}`},
+		{&Attribute{ZclDataTypeCharStr, "\x01\xff"}, `{"dataType":"CharStr","hex":"0x01ff"}`},
+		{&Attribute{ZclDataTypeStruct, []*Attribute{{ZclDataTypeUint8, uint64(1)}}}, `{"dataType":"Struct","value":[{"dataType":"Uint8","value":1}]}`},
+		{&Attribute{ZclDataTypeTod, &TimeOfDay{13, 45, 7, 50}}, `{"dataType":"Tod","value":{"Hours":13,"Minutes":45,"Seconds":7,"Hundredths":50}}`},
+		{&Attribute{ZclDataTypeUtc, uint32(700000000)}, `{"dataType":"Utc","value":700000000}`},
+		{&Attribute{ZclDataTypeClusterId, uint16(0x0402)}, `{"dataType":"ClusterId","value":"0x0402"}`},
+		{&Attribute{ZclDataTypeIeeeAddr, "0x00158d0001a2b3c4"}, `{"dataType":"IeeeAddr","value":"0x00158d0001a2b3c4"}`},
+		{&Attribute{ZclDataType_128BitSecKey, [16]byte{15: 1}}, `{"dataType":"128BitSecKey","value":"0x00000000000000000000000000000001"}`},
+	}
+	for _, a := range attributes {
+		data, err := json.Marshal(a.attribute)
+		c.Assert(err, IsNil)
+		c.Assert(string(data), Equals, a.json)
+		restored := &Attribute{}
+		c.Assert(json.Unmarshal(data, restored), IsNil)
+		c.Assert(restored, DeepEquals, a.attribute)
+	}
+}
+
+func (s *JSONSuite) TestSymbols(c *C) {
+	data, err := json.Marshal([]ZclStatus{ZclStatusSuccess, ZclStatusUnsupportedAttribute, 0x42})
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, `["Success","UnsupportedAttribute","0x42"]`)
+
+	var statuses []ZclStatus
+	c.Assert(json.Unmarshal([]byte(`["Failure","0x86",139]`), &statuses), IsNil)
+	c.Assert(statuses, DeepEquals, []ZclStatus{ZclStatusFailure, ZclStatusUnsupportedAttribute, ZclStatusNotFound})
+
+	var dataType ZclDataType
+	c.Assert(json.Unmarshal([]byte(`"Uint16"`), &dataType), IsNil)
+	c.Assert(dataType, Equals, ZclDataTypeUint16)
+	c.Assert(json.Unmarshal([]byte(`"Uint17"`), &dataType), NotNil)
+}
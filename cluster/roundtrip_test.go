@@ -0,0 +1,308 @@
+package cluster
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dyrkin/bin"
+	. "gopkg.in/check.v1"
+)
+
+type RoundTripSuite struct{}
+
+var _ = Suite(&RoundTripSuite{})
+
+// Every data type must decode to the value it was encoded from.
+func (s *RoundTripSuite) TestAttributes(c *C) {
+	random := rand.New(rand.NewSource(1))
+	for dataType := range zclDataTypeNames {
+		for i := 0; i < 50; i++ {
+			a := randomAttribute(random, dataType, 2)
+			decoded := &Attribute{}
+			bin.Decode(bin.Encode(a), decoded)
+			c.Assert(decoded, DeepEquals, a, Commentf("%v %x", dataType, bin.Encode(a)))
+		}
+	}
+}
+
+// Every command of the library must decode to the value it was encoded from.
+func (s *RoundTripSuite) TestCommands(c *C) {
+	random := rand.New(rand.NewSource(1))
+	check := func(cd *CommandDescriptor) {
+		for i := 0; i < 50; i++ {
+			command := reflect.New(reflect.TypeOf(cd.Command).Elem())
+			randomValue(random, command.Elem(), true)
+			encoded := bin.Encode(command.Interface())
+			decoded := reflect.New(command.Type().Elem()).Interface()
+			bin.Decode(encoded, decoded)
+			c.Assert(decoded, DeepEquals, command.Interface(), Commentf("%s %x", cd.Name, encoded))
+		}
+	}
+	library := New()
+	for _, cd := range library.Global() {
+		check(cd)
+	}
+	for _, cl := range library.Clusters() {
+		if cl.CommandDescriptors == nil {
+			continue
+		}
+		for _, cd := range cl.CommandDescriptors.Received {
+			check(cd)
+		}
+		for _, cd := range cl.CommandDescriptors.Generated {
+			check(cd)
+		}
+	}
+}
+
+func (s *RoundTripSuite) TestShortIeeeAddress(c *C) {
+	c.Assert(bin.Encode(&Attribute{ZclDataTypeIeeeAddr, "1"}), DeepEquals,
+		[]byte{byte(ZclDataTypeIeeeAddr), 1, 0, 0, 0, 0, 0, 0, 0})
+	c.Assert(bin.Encode(&Attribute{ZclDataTypeIeeeAddr, ""}), DeepEquals,
+		[]byte{byte(ZclDataTypeIeeeAddr), 0, 0, 0, 0, 0, 0, 0, 0})
+}
+
+func (s *RoundTripSuite) TestTruncatedArray(c *C) {
+	a := &Attribute{}
+	bin.Decode([]byte{byte(ZclDataTypeArray), 0xff, 0xff, byte(ZclDataTypeUint8), 0x01}, a)
+	c.Assert(a, DeepEquals, &Attribute{ZclDataTypeArray, []*Attribute{{ZclDataTypeUint8, uint64(1)}}})
+}
+
+func (s *RoundTripSuite) TestOwnCodec(c *C) {
+	commands := []struct {
+		command interface{}
+		encoded []byte
+	}{
+		{&MoveColorCommand{RateX: -2, RateY: 300}, []byte{0xfe, 0xff, 0x2c, 0x01}},
+		{&CheckInResponse{StartFastPolling: true, FastPollTimeout: 40}, []byte{0x01, 0x28, 0x00}},
+		{&BypassCommand{NumberOfZones: 2, ZoneID: []uint8{3, 4}, ArmDisarmCode: "12"}, []byte{0x02, 0x03, 0x04, 0x02, '1', '2'}},
+		{&GetZoneStatusResponse{ZoneStatusComplete: true, NumberOfZones: 1, ZoneID: []uint16{5}, ZoneStatus: []uint16{0x0021}},
+			[]byte{0x01, 0x01, 0x05, 0x21, 0x00}},
+	}
+	for _, cmd := range commands {
+		c.Assert(bin.Encode(cmd.command), DeepEquals, cmd.encoded)
+		decoded := reflect.New(reflect.TypeOf(cmd.command).Elem()).Interface()
+		bin.Decode(cmd.encoded, decoded)
+		c.Assert(decoded, DeepEquals, cmd.command)
+	}
+}
+
+// randomAttribute returns a value of the Go type the codec decodes the data type to. Floats are
+// never NaN, which doesn't equal itself.
+func randomAttribute(random *rand.Rand, dataType ZclDataType, depth int) *Attribute {
+	a := &Attribute{DataType: dataType}
+	switch {
+	case dataType >= ZclDataTypeData8 && dataType <= ZclDataTypeData64:
+		value := reflect.New(reflect.ArrayOf(int(dataType-ZclDataTypeData8)+1, reflect.TypeOf(byte(0)))).Elem()
+		random.Read(value.Slice(0, value.Len()).Bytes())
+		a.Value = value.Interface()
+	case dataType == ZclDataTypeBoolean:
+		a.Value = random.Intn(2) == 1
+	case dataType >= ZclDataTypeBitmap8 && dataType <= ZclDataTypeBitmap64:
+		a.Value = randomUint(random, int(dataType-ZclDataTypeBitmap8)+1)
+	case dataType >= ZclDataTypeUint8 && dataType <= ZclDataTypeUint64:
+		a.Value = randomUint(random, int(dataType-ZclDataTypeUint8)+1)
+	case dataType >= ZclDataTypeInt8 && dataType <= ZclDataTypeInt64:
+		shift := uint(64 - 8*(int(dataType-ZclDataTypeInt8)+1))
+		a.Value = int64(random.Uint64()<<shift) >> shift
+	case dataType == ZclDataTypeSemiPrec:
+		v := halfToFloat32(uint16(random.Uint32()))
+		for math.IsNaN(float64(v)) {
+			v = halfToFloat32(uint16(random.Uint32()))
+		}
+		a.Value = v
+	case dataType == ZclDataTypeSinglePrec:
+		v := math.Float32frombits(random.Uint32())
+		for math.IsNaN(float64(v)) {
+			v = math.Float32frombits(random.Uint32())
+		}
+		a.Value = v
+	case dataType == ZclDataTypeDoublePrec:
+		v := math.Float64frombits(random.Uint64())
+		for math.IsNaN(v) {
+			v = math.Float64frombits(random.Uint64())
+		}
+		a.Value = v
+	case dataType == ZclDataTypeEnum8:
+		a.Value = randomUint(random, 1)
+	case dataType == ZclDataTypeEnum16:
+		a.Value = randomUint(random, 2)
+	case dataType == ZclDataTypeOctetStr, dataType == ZclDataTypeCharStr:
+		a.Value = randomString(random, 0xff)
+	case dataType == ZclDataTypeLongOctetStr, dataType == ZclDataTypeLongCharStr:
+		a.Value = randomString(random, 0x3ff)
+	case dataType == ZclDataTypeArray, dataType == ZclDataTypeSet, dataType == ZclDataTypeBag, dataType == ZclDataTypeStruct:
+		elements := []*Attribute{}
+		if depth > 0 {
+			for i := random.Intn(4); i > 0; i-- {
+				elements = append(elements, randomAttribute(random, randomDataType(random), depth-1))
+			}
+		}
+		a.Value = elements
+	case dataType == ZclDataTypeTod:
+		a.Value = &TimeOfDay{uint8(random.Intn(24)), uint8(random.Intn(60)), uint8(random.Intn(60)), uint8(random.Intn(100))}
+	case dataType == ZclDataTypeDate:
+		a.Value = &Date{uint8(random.Intn(256)), uint8(random.Intn(12) + 1), uint8(random.Intn(31) + 1), uint8(random.Intn(7) + 1)}
+	case dataType == ZclDataTypeUtc, dataType == ZclDataTypeBacOid:
+		a.Value = random.Uint32()
+	case dataType == ZclDataTypeClusterId, dataType == ZclDataTypeAttrId:
+		a.Value = uint16(random.Uint32())
+	case dataType == ZclDataTypeIeeeAddr:
+		a.Value = fmt.Sprintf("0x%016x", random.Uint64())
+	case dataType == ZclDataType_128BitSecKey:
+		var key [16]byte
+		random.Read(key[:])
+		a.Value = key
+	}
+	return a
+}
+
+func randomDataType(random *rand.Rand) ZclDataType {
+	dataTypes := make([]ZclDataType, 0, len(zclDataTypeNames))
+	for dataType := range zclDataTypeNames {
+		dataTypes = append(dataTypes, dataType)
+	}
+	return dataTypes[random.Intn(len(dataTypes))]
+}
+
+func randomUint(random *rand.Rand, size int) uint64 {
+	return random.Uint64() >> uint(64-8*size)
+}
+
+func randomString(random *rand.Rand, max int) string {
+	b := make([]byte, random.Intn(max+1))
+	random.Read(b)
+	return string(b)
+}
+
+// randomValue fills the value with what its codec tags allow: transient fields and fields whose
+// conditions don't hold stay zero, and fields without a size, which take the rest of the frame,
+// are only filled at its end.
+func randomValue(random *rand.Rand, v reflect.Value, last bool) {
+	if v.Type() == reflect.TypeOf(ZclDataType(0)) {
+		v.Set(reflect.ValueOf(randomDataType(random)))
+		return
+	}
+	switch v.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(randomUint(random, int(v.Type().Size())))
+	case reflect.String:
+		if last {
+			v.SetString(randomString(random, 0xff))
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			randomValue(random, v.Index(i), false)
+		}
+	case reflect.Slice:
+		n := 0
+		if last {
+			n = random.Intn(4)
+		}
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+		for i := 0; i < n; i++ {
+			randomValue(random, v.Index(i), false)
+		}
+	case reflect.Ptr:
+		if v.Type() == reflect.TypeOf(&Attribute{}) {
+			v.Set(reflect.ValueOf(randomAttribute(random, randomDataType(random), 2)))
+			return
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		randomValue(random, v.Elem(), last)
+	case reflect.Struct:
+		randomStruct(random, v, last)
+	default:
+		panic(fmt.Sprintf("the codec skips %s values", v.Type()))
+	}
+}
+
+func randomStruct(random *rand.Rand, v reflect.Value, last bool) {
+	_, custom := v.Addr().Interface().(bin.Serializable)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		tag := v.Type().Field(i).Tag
+		if tag.Get("transient") == "true" || !conditionsHold(tag.Get("cond"), v) {
+			continue
+		}
+		switch {
+		case tag.Get("bits") != "":
+			bits, _ := strconv.ParseUint(strings.TrimPrefix(tag.Get("bits"), "0b"), 2, 64)
+			for bits&1 == 0 {
+				bits >>= 1
+			}
+			field.SetUint(random.Uint64() & bits)
+		case field.Kind() == reflect.Bool, field.Kind() >= reflect.Int && field.Kind() <= reflect.Int64:
+			// only the types with their own codec write booleans and signed integers
+			if !custom {
+				panic(fmt.Sprintf("the codec skips %s values", field.Type()))
+			}
+			if field.Kind() == reflect.Bool {
+				field.SetBool(random.Intn(2) == 1)
+			} else {
+				field.SetInt(int64(random.Uint64()))
+			}
+		case tag.Get("size") != "":
+			// sized fields are preceded by their length and can be filled anywhere
+			if field.Kind() == reflect.String {
+				field.SetString(randomString(random, 0xff))
+			} else {
+				randomValue(random, field, true)
+			}
+		default:
+			randomValue(random, field, last && i == v.NumField()-1)
+		}
+	}
+	// the records with a reportable change write it only for analog data types, the commands with a
+	// count write the length of their slices
+	switch r := v.Addr().Interface().(type) {
+	case *AttributeReportingConfigurationRecord:
+		r.ReportableChange = randomReportableChange(random, r.Direction == ReportDirectionAttributeReported, r.AttributeDataType)
+	case *AttributeReportingConfigurationResponseRecord:
+		r.ReportableChange = randomReportableChange(random, r.Status == ZclStatusSuccess && r.Direction == ReportDirectionAttributeReported, r.AttributeDataType)
+	case *BypassCommand:
+		r.ZoneID = make([]uint8, random.Intn(4))
+		random.Read(r.ZoneID)
+		r.NumberOfZones = uint8(len(r.ZoneID))
+	case *GetZoneStatusResponse:
+		r.NumberOfZones = uint8(random.Intn(4))
+		r.ZoneID, r.ZoneStatus = []uint16{}, []uint16{}
+		for i := 0; i < int(r.NumberOfZones); i++ {
+			r.ZoneID = append(r.ZoneID, uint16(random.Intn(256)))
+			r.ZoneStatus = append(r.ZoneStatus, uint16(random.Uint32()))
+		}
+	}
+}
+
+func randomReportableChange(random *rand.Rand, present bool, dataType ZclDataType) *Attribute {
+	if !present || !dataType.Analog() {
+		return nil
+	}
+	return randomAttribute(random, dataType, 0)
+}
+
+// conditionsHold evaluates conditions of the form "uint:Field==1;uint:Other!=0" on the fields
+// filled before.
+func conditionsHold(cond string, v reflect.Value) bool {
+	if cond == "" {
+		return true
+	}
+	for _, c := range strings.Split(cond, ";") {
+		c = strings.TrimPrefix(c, "uint:")
+		operator := "=="
+		if strings.Contains(c, "!=") {
+			operator = "!="
+		}
+		parts := strings.SplitN(c, operator, 2)
+		expected, _ := strconv.ParseUint(parts[1], 10, 64)
+		equal := v.FieldByName(parts[0]).Uint() == expected
+		if equal != (operator == "==") {
+			return false
+		}
+	}
+	return true
+}
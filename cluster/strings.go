@@ -0,0 +1,180 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func (t ZclDataType) String() string {
+	return symbol(zclDataTypeNames[t], uint64(t), 1)
+}
+
+func (s ZclStatus) String() string {
+	return symbol(zclStatusNames[s], uint64(s), 1)
+}
+
+// Error lets a failed status be returned as an error and matched with errors.Is. It returns the
+// name, as String does, since fmt prefers Error when printing a status.
+func (s ZclStatus) Error() string {
+	return s.String()
+}
+
+func (c ZclCommand) String() string {
+	return symbol(zclCommandNames[c], uint64(c), 1)
+}
+
+func (id ClusterId) String() string {
+	return symbol(clusterIdNames[id], uint64(id), 2)
+}
+
+func (d ReportDirection) String() string {
+	return symbol(reportDirectionNames[d], uint64(d), 1)
+}
+
+var accessNames = []struct {
+	access Access
+	name   string
+}{
+	{Read, "Read"},
+	{Write, "Write"},
+	{Reportable, "Reportable"},
+	{Scene, "Scene"},
+}
+
+// String joins the names of the flags with '|', e.g. "Read|Reportable".
+func (a Access) String() string {
+	names := []string{}
+	rest := a
+	for _, n := range accessNames {
+		if a&n.access != 0 {
+			names = append(names, n.name)
+			rest &^= n.access
+		}
+	}
+	if rest != 0 || len(names) == 0 {
+		names = append(names, fmt.Sprintf("0x%02x", uint8(rest)))
+	}
+	return strings.Join(names, "|")
+}
+
+// ParseZclDataType accepts the names returned by String in any case and with or without
+// underscores, e.g. "CharStr" or "CHAR_STR", as well as hex and decimal numbers.
+func ParseZclDataType(s string) (ZclDataType, error) {
+	v, err := parseSymbol("data type", s, 8, func(name string) (uint64, bool) {
+		for t, n := range zclDataTypeNames {
+			if normalize(n) == name {
+				return uint64(t), true
+			}
+		}
+		return 0, false
+	})
+	return ZclDataType(v), err
+}
+
+// zclStatusAliases are the names of the specification which don't normalize to those of String.
+var zclStatusAliases = map[string]ZclStatus{
+	"UNSUP_MANUF_CLUSTER_COMMAND": ZclStatusUnsupManuClusterCommand,
+	"UNSUP_MANUF_GENERAL_COMMAND": ZclStatusUnsupManuGeneralCommand,
+}
+
+// ParseZclStatus accepts the names returned by String as well as those of the specification, e.g.
+// "UNSUPPORTED_ATTRIBUTE", and hex and decimal numbers.
+func ParseZclStatus(s string) (ZclStatus, error) {
+	v, err := parseSymbol("status", s, 8, func(name string) (uint64, bool) {
+		for status, n := range zclStatusNames {
+			if normalize(n) == name {
+				return uint64(status), true
+			}
+		}
+		for alias, status := range zclStatusAliases {
+			if normalize(alias) == name {
+				return uint64(status), true
+			}
+		}
+		return 0, false
+	})
+	return ZclStatus(v), err
+}
+
+func ParseZclCommand(s string) (ZclCommand, error) {
+	v, err := parseSymbol("command", s, 8, func(name string) (uint64, bool) {
+		for c, n := range zclCommandNames {
+			if normalize(n) == name {
+				return uint64(c), true
+			}
+		}
+		return 0, false
+	})
+	return ZclCommand(v), err
+}
+
+func ParseClusterId(s string) (ClusterId, error) {
+	v, err := parseSymbol("cluster", s, 16, func(name string) (uint64, bool) {
+		for id, n := range clusterIdNames {
+			if normalize(n) == name {
+				return uint64(id), true
+			}
+		}
+		return 0, false
+	})
+	return ClusterId(v), err
+}
+
+func ParseReportDirection(s string) (ReportDirection, error) {
+	v, err := parseSymbol("report direction", s, 8, func(name string) (uint64, bool) {
+		for d, n := range reportDirectionNames {
+			if normalize(n) == name {
+				return uint64(d), true
+			}
+		}
+		return 0, false
+	})
+	return ReportDirection(v), err
+}
+
+// ParseAccess reads flags joined by '|' as written by String.
+func ParseAccess(s string) (Access, error) {
+	var a Access
+	for _, part := range strings.Split(s, "|") {
+		v, err := parseSymbol("access", strings.TrimSpace(part), 8, func(name string) (uint64, bool) {
+			for _, n := range accessNames {
+				if normalize(n.name) == name {
+					return uint64(n.access), true
+				}
+			}
+			return 0, false
+		})
+		if err != nil {
+			return 0, err
+		}
+		a |= Access(v)
+	}
+	return a, nil
+}
+
+// symbol returns the name or, for values without one, the value as a hex number of the given size
+// in bytes.
+func symbol(name string, v uint64, size int) string {
+	if name != "" {
+		return name
+	}
+	return fmt.Sprintf("0x%0*x", 2*size, v)
+}
+
+func parseSymbol(kind string, s string, bitSize int, lookup func(name string) (uint64, bool)) (uint64, error) {
+	if v, ok := lookup(normalize(s)); ok {
+		return v, nil
+	}
+	if strings.HasPrefix(s, "0x") {
+		return strconv.ParseUint(s[2:], 16, bitSize)
+	}
+	if v, err := strconv.ParseUint(s, 10, bitSize); err == nil {
+		return v, nil
+	}
+	return 0, fmt.Errorf("unknown %s %q", kind, s)
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.Replace(name, "_", "", -1))
+}
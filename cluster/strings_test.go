@@ -0,0 +1,78 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+
+	. "gopkg.in/check.v1"
+)
+
+type StringsSuite struct{}
+
+var _ = Suite(&StringsSuite{})
+
+func (s *StringsSuite) TestString(c *C) {
+	c.Assert(ZclDataTypeCharStr.String(), Equals, "CharStr")
+	c.Assert(ZclDataType(0x4f).String(), Equals, "0x4f")
+	c.Assert(ZclStatusUnsupportedAttribute.String(), Equals, "UnsupportedAttribute")
+	c.Assert(ZclCommandDiscoverAttributesExtended.String(), Equals, "DiscoverAttributesExtended")
+	c.Assert(OnOff.String(), Equals, "OnOff")
+	c.Assert(ClusterId(0xef00).String(), Equals, "0xef00")
+	c.Assert(ReportDirectionAttributeReceived.String(), Equals, "AttributeReceived")
+	c.Assert((Read | Reportable).String(), Equals, "Read|Reportable")
+	c.Assert((Write | 0x40).String(), Equals, "Write|0x40")
+	c.Assert(Access(0).String(), Equals, "0x00")
+	c.Assert(fmt.Sprintf("%v", ZclDataTypeUint8), Equals, "Uint8")
+}
+
+func (s *StringsSuite) TestParse(c *C) {
+	status, err := ParseZclStatus("UNSUPPORTED_ATTRIBUTE")
+	c.Assert(err, IsNil)
+	c.Assert(status, Equals, ZclStatusUnsupportedAttribute)
+	status, err = ParseZclStatus("UNSUP_MANUF_CLUSTER_COMMAND")
+	c.Assert(err, IsNil)
+	c.Assert(status, Equals, ZclStatusUnsupManuClusterCommand)
+	status, err = ParseZclStatus("UNSUP_MANUF_GENERAL_COMMAND")
+	c.Assert(err, IsNil)
+	c.Assert(status, Equals, ZclStatusUnsupManuGeneralCommand)
+	status, err = ParseZclStatus("0x8b")
+	c.Assert(err, IsNil)
+	c.Assert(status, Equals, ZclStatusNotFound)
+	_, err = ParseZclStatus("BROKEN")
+	c.Assert(err, ErrorMatches, `unknown status "BROKEN"`)
+
+	clusterId, err := ParseClusterId("OnOff")
+	c.Assert(err, IsNil)
+	c.Assert(clusterId, Equals, OnOff)
+	clusterId, err = ParseClusterId("0xef00")
+	c.Assert(err, IsNil)
+	c.Assert(clusterId, Equals, ClusterId(0xef00))
+	for id, cluster := range New().Clusters() {
+		c.Assert(id.String(), Equals, cluster.Name)
+		clusterId, err = ParseClusterId(cluster.Name)
+		c.Assert(err, IsNil)
+		c.Assert(clusterId, Equals, id)
+	}
+
+	dataType, err := ParseZclDataType("char_str")
+	c.Assert(err, IsNil)
+	c.Assert(dataType, Equals, ZclDataTypeCharStr)
+	command, err := ParseZclCommand("READ_ATTRIBUTES")
+	c.Assert(err, IsNil)
+	c.Assert(command, Equals, ZclCommandReadAttributes)
+	direction, err := ParseReportDirection("AttributeReported")
+	c.Assert(err, IsNil)
+	c.Assert(direction, Equals, ReportDirectionAttributeReported)
+	access, err := ParseAccess("Read|Write")
+	c.Assert(err, IsNil)
+	c.Assert(access, Equals, Read|Write)
+}
+
+func (s *StringsSuite) TestStatusError(c *C) {
+	var err error = ZclStatusNotFound
+	c.Assert(err, ErrorMatches, "NotFound")
+	c.Assert(fmt.Sprint(ZclStatusSuccess), Equals, "Success")
+	wrapped := fmt.Errorf("reading attribute: %w", err)
+	c.Assert(errors.Is(wrapped, ZclStatusNotFound), Equals, true)
+	c.Assert(errors.Is(wrapped, ZclStatusFailure), Equals, false)
+}
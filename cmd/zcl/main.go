@@ -0,0 +1,389 @@
+// Command zcl decodes and encodes ZCL frames and lists the clusters, attributes and commands of the
+// cluster library.
+//
+//	zcl decode [--cluster 0x0006] [--json] <hex>, the cluster is required for cluster specific frames
+//	zcl encode --cluster OnOff --command On [--payload '{...}'] [--json]
+//	zcl list clusters | attributes <cluster> | commands [<cluster>] [--json]
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/reflection"
+)
+
+const usage = `usage:
+  zcl decode [--cluster <cluster>] [--json] <hex>
+  zcl encode --cluster <cluster> --command <command> [--payload <json>] [--direction <direction>]
+             [--global] [--tsn <n>] [--manufacturer <code>] [--disable-default-response] [--json]
+  zcl list clusters [--json]
+  zcl list attributes [--json] <cluster>
+  zcl list commands [--json] [<cluster>]
+
+Clusters and commands are given by name, e.g. OnOff, or by number, e.g. 0x0006.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	z := zcl.New()
+	var err error
+	switch args[0] {
+	case "decode":
+		err = decode(z, args[1:], stdout, stderr)
+	case "encode":
+		err = encode(z, args[1:], stdout, stderr)
+	case "list":
+		err = list(z, args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		err = fmt.Errorf("unknown command %q", args[0])
+	}
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "zcl: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func flags(name string, stderr io.Writer) (*flag.FlagSet, *bool) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs, fs.Bool("json", false, "print JSON")
+}
+
+// parse parses the flags wherever they appear among the arguments and returns the other arguments.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return rest, nil
+		}
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func decode(z *zcl.Zcl, args []string, stdout, stderr io.Writer) error {
+	fs, asJSON := flags("decode", stderr)
+	clusterName := fs.String("cluster", "", "cluster the frame was received on, required for cluster specific commands")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	data, err := parseHex(strings.Join(rest, ""))
+	if err != nil {
+		return err
+	}
+	if len(data) < 3 {
+		return errors.New("frame is too short")
+	}
+	// global commands decode without a cluster, their attributes are named after Basic
+	clusterId := cluster.Basic
+	if *clusterName != "" {
+		if clusterId, err = parseCluster(z.ClusterLibrary(), *clusterName); err != nil {
+			return err
+		}
+	} else if frame.FrameType(data[0]&0x03) != frame.FrameTypeGlobal {
+		return errors.New("--cluster is required for cluster specific frames")
+	}
+	if !*asJSON {
+		d := z.Dissect(uint16(clusterId), data)
+		fmt.Fprint(stdout, d)
+		return d.Err
+	}
+	im, err := z.ToZclIncomingMessage(&zcl.ApplicationMessage{ClusterID: uint16(clusterId), Data: data})
+	if err != nil {
+		return err
+	}
+	return printJSON(stdout, im.Data)
+}
+
+func encode(z *zcl.Zcl, args []string, stdout, stderr io.Writer) error {
+	fs, asJSON := flags("encode", stderr)
+	clusterName := fs.String("cluster", "", "cluster of the command")
+	commandName := fs.String("command", "", "command name or identifier")
+	payload := fs.String("payload", "", "command fields as JSON, e.g. '{\"AttributeIDs\":[0]}'")
+	directionName := fs.String("direction", "", "ClientToServer or ServerToClient, by default the direction the cluster supports the command in")
+	global := fs.Bool("global", false, "look the command up among the global commands only")
+	tsn := fs.Uint("tsn", 1, "transaction sequence number")
+	manufacturer := fs.String("manufacturer", "", "manufacturer code of a manufacturer specific frame")
+	disableDefaultResponse := fs.Bool("disable-default-response", false, "set the disable default response bit")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected arguments %q", rest)
+	}
+	if *commandName == "" {
+		return errors.New("--command is required")
+	}
+	if *tsn > 0xff {
+		return fmt.Errorf("invalid transaction sequence number %d", *tsn)
+	}
+	clusterId, err := parseCluster(z.ClusterLibrary(), *clusterName)
+	if err != nil {
+		return err
+	}
+	var directions []frame.Direction
+	if *directionName != "" {
+		direction, err := frame.ParseDirection(*directionName)
+		if err != nil {
+			return err
+		}
+		directions = []frame.Direction{direction}
+	} else {
+		directions = []frame.Direction{frame.DirectionClientServer, frame.DirectionServerClient}
+	}
+	frameType, direction, commandId, cd, err := findCommand(z.ClusterLibrary(), clusterId, *commandName, directions, *global)
+	if err != nil {
+		return err
+	}
+	command := reflection.Copy(cd.Command)
+	if *payload != "" {
+		if err := json.Unmarshal([]byte(*payload), command); err != nil {
+			return fmt.Errorf("invalid %s payload: %v", cd.Name, err)
+		}
+	}
+	builder := frame.New().
+		IdGenerator(func() uint8 { return uint8(*tsn) }).
+		FrameType(frameType).
+		Direction(direction).
+		DisableDefaultResponse(*disableDefaultResponse).
+		CommandId(commandId).
+		Command(command)
+	if *manufacturer != "" {
+		code, err := strconv.ParseUint(*manufacturer, 0, 16)
+		if err != nil {
+			return fmt.Errorf("invalid manufacturer code %q", *manufacturer)
+		}
+		builder.ManufacturerCode(uint16(code))
+	}
+	f, err := builder.Build()
+	if err != nil {
+		return err
+	}
+	data := frame.Encode(f)
+	if !*asJSON {
+		fmt.Fprintln(stdout, hex.EncodeToString(data))
+		return nil
+	}
+	im, err := z.ToZclIncomingMessage(&zcl.ApplicationMessage{ClusterID: uint16(clusterId), Data: data})
+	if err != nil {
+		return err
+	}
+	return printJSON(stdout, &struct {
+		Hex   string        `json:"hex"`
+		Frame *zcl.ZclFrame `json:"frame"`
+	}{hex.EncodeToString(data), im.Data})
+}
+
+// findCommand looks the command up among the commands of the cluster in the given directions and
+// then among the global commands.
+func findCommand(library *cluster.ClusterLibrary, clusterId cluster.ClusterId, name string, directions []frame.Direction, global bool) (frame.FrameType, frame.Direction, uint8, *cluster.CommandDescriptor, error) {
+	if c, ok := library.Clusters()[clusterId]; ok && c.CommandDescriptors != nil && !global {
+		for _, direction := range directions {
+			commandDescriptors := c.CommandDescriptors.Received
+			if direction == frame.DirectionServerClient {
+				commandDescriptors = c.CommandDescriptors.Generated
+			}
+			if commandId, cd, ok := lookupCommand(commandDescriptors, name); ok {
+				return frame.FrameTypeLocal, direction, commandId, cd, nil
+			}
+		}
+	}
+	if commandId, cd, ok := lookupCommand(library.Global(), name); ok {
+		return frame.FrameTypeGlobal, directions[0], commandId, cd, nil
+	}
+	return 0, 0, 0, nil, fmt.Errorf("unknown command %q of cluster %s", name, clusterId)
+}
+
+func lookupCommand(commandDescriptors map[uint8]*cluster.CommandDescriptor, name string) (uint8, *cluster.CommandDescriptor, bool) {
+	for commandId, cd := range commandDescriptors {
+		if strings.EqualFold(cd.Name, name) {
+			return commandId, cd, true
+		}
+	}
+	if commandId, err := strconv.ParseUint(name, 0, 8); err == nil {
+		cd, ok := commandDescriptors[uint8(commandId)]
+		return uint8(commandId), cd, ok
+	}
+	return 0, nil, false
+}
+
+type clusterJSON struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type attributeJSON struct {
+	Id       string              `json:"id"`
+	Name     string              `json:"name"`
+	DataType cluster.ZclDataType `json:"dataType"`
+	Access   string              `json:"access"`
+}
+
+type commandJSON struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Direction string `json:"direction,omitempty"`
+}
+
+func list(z *zcl.Zcl, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("list what? clusters, attributes or commands")
+	}
+	fs, asJSON := flags("list "+args[0], stderr)
+	rest, err := parse(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	library := z.ClusterLibrary()
+	var rows []interface{}
+	switch args[0] {
+	case "clusters":
+		ids := []int{}
+		for id := range library.Clusters() {
+			ids = append(ids, int(id))
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			rows = append(rows, &clusterJSON{fmt.Sprintf("0x%04x", id), library.Clusters()[cluster.ClusterId(id)].Name})
+		}
+	case "attributes":
+		c, err := clusterArg(library, rest)
+		if err != nil {
+			return err
+		}
+		ids := []int{}
+		for id := range c.AttributeDescriptors {
+			ids = append(ids, int(id))
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			ad := c.AttributeDescriptors[uint16(id)]
+			rows = append(rows, &attributeJSON{fmt.Sprintf("0x%04x", id), ad.Name, ad.Type, ad.Access.String()})
+		}
+	case "commands":
+		if len(rest) == 0 {
+			rows = commandRows(library.Global(), "")
+			break
+		}
+		c, err := clusterArg(library, rest)
+		if err != nil {
+			return err
+		}
+		if c.CommandDescriptors != nil {
+			rows = append(commandRows(c.CommandDescriptors.Received, frame.DirectionClientServer.String()),
+				commandRows(c.CommandDescriptors.Generated, frame.DirectionServerClient.String())...)
+		}
+	default:
+		return fmt.Errorf("unknown list %q", args[0])
+	}
+	if *asJSON {
+		if rows == nil {
+			rows = []interface{}{}
+		}
+		return printJSON(stdout, rows)
+	}
+	for _, row := range rows {
+		switch row := row.(type) {
+		case *clusterJSON:
+			fmt.Fprintf(stdout, "%s %s\n", row.Id, row.Name)
+		case *attributeJSON:
+			fmt.Fprintf(stdout, "%s %s %s %s\n", row.Id, row.Name, row.DataType, row.Access)
+		case *commandJSON:
+			fmt.Fprintln(stdout, strings.TrimSpace(fmt.Sprintf("%s %s %s", row.Id, row.Name, row.Direction)))
+		}
+	}
+	return nil
+}
+
+func commandRows(commandDescriptors map[uint8]*cluster.CommandDescriptor, direction string) []interface{} {
+	ids := []int{}
+	for id := range commandDescriptors {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	rows := []interface{}{}
+	for _, id := range ids {
+		rows = append(rows, &commandJSON{fmt.Sprintf("0x%02x", id), commandDescriptors[uint8(id)].Name, direction})
+	}
+	return rows
+}
+
+func clusterArg(library *cluster.ClusterLibrary, args []string) (*cluster.Cluster, error) {
+	if len(args) != 1 {
+		return nil, errors.New("expected a cluster")
+	}
+	id, err := parseCluster(library, args[0])
+	if err != nil {
+		return nil, err
+	}
+	c, ok := library.Clusters()[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown cluster %s", id)
+	}
+	return c, nil
+}
+
+// parseCluster accepts whatever cluster.ParseClusterId accepts and the names of the library, which
+// include clusters added by patches.
+func parseCluster(library *cluster.ClusterLibrary, s string) (cluster.ClusterId, error) {
+	if s == "" {
+		return 0, errors.New("--cluster is required")
+	}
+	for id, c := range library.Clusters() {
+		if strings.EqualFold(c.Name, s) {
+			return id, nil
+		}
+	}
+	return cluster.ParseClusterId(s)
+}
+
+// parseHex reads bytes written as "18010a", "0x18010a", "18 01 0a" or "18:01:0a".
+func parseHex(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.ToLower(s), "0x")
+	s = strings.NewReplacer(" ", "", ":", "", "\n", "", "\t", "").Replace(s)
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex frame: %v", err)
+	}
+	return data, nil
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
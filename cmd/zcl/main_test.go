@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestZcl(t *testing.T) { TestingT(t) }
+
+type MainSuite struct{}
+
+var _ = Suite(&MainSuite{})
+
+func invoke(args ...string) (int, string, string) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(args, stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func (s *MainSuite) TestDecode(c *C) {
+	code, out, _ := invoke("decode", "--cluster", "0x0006", "18 01 01 00 00 00 10 01")
+	c.Assert(code, Equals, 0)
+	c.Assert(out, Matches, `(?s).*Command Identifier: 0x01 ReadAttributesResponse.*AttributeID: 0x0000 OnOff.*Value: true.*`)
+
+	code, out, _ = invoke("decode", "--cluster", "OnOff", "--json", "1801010000")
+	c.Assert(code, Equals, 0)
+	var f map[string]interface{}
+	c.Assert(json.Unmarshal([]byte(out), &f), IsNil)
+	c.Assert(f["commandName"], Equals, "ReadAttributesResponse")
+
+	code, out, errOut := invoke("decode", "--cluster", "OnOff", "1801010000002101")
+	c.Assert(code, Equals, 1)
+	c.Assert(out, Matches, `(?s).*Undecoded \[6:8\] 21 01\nError: frame is truncated\n`)
+	c.Assert(errOut, Equals, "zcl: frame is truncated\n")
+
+	code, _, errOut = invoke("decode", "010101")
+	c.Assert(code, Equals, 1)
+	c.Assert(errOut, Equals, "zcl: --cluster is required for cluster specific frames\n")
+	code, out, _ = invoke("decode", "18010100000020 01")
+	c.Assert(code, Equals, 0)
+	c.Assert(out, Matches, `(?s).*Command Identifier: 0x01 ReadAttributesResponse.*Value: 1 .*`)
+
+	code, _, errOut = invoke("decode", "18zz")
+	c.Assert(code, Equals, 1)
+	c.Assert(errOut, Matches, "zcl: invalid hex frame.*\n")
+}
+
+func (s *MainSuite) TestEncode(c *C) {
+	code, out, _ := invoke("encode", "--cluster", "OnOff", "--command", "On")
+	c.Assert(code, Equals, 0)
+	c.Assert(out, Equals, "010101\n")
+
+	_, out, _ = invoke("encode", "--cluster", "Identify", "--command", "identifyqueryresponse", "--payload", `{"Timeout":5}`, "--tsn", "9", "--manufacturer", "0x115f")
+	c.Assert(out, Equals, "0d5f1109000500\n")
+
+	_, out, _ = invoke("encode", "--cluster", "OnOff", "--command", "0x00", "--global", "--payload", `{"AttributeIDs":[0,16384]}`, "--disable-default-response", "--json")
+	var encoded struct {
+		Hex   string
+		Frame struct {
+			CommandName string
+		}
+	}
+	c.Assert(json.Unmarshal([]byte(out), &encoded), IsNil)
+	c.Assert(encoded.Hex, Equals, "10010000000040")
+	c.Assert(encoded.Frame.CommandName, Equals, "ReadAttributes")
+
+	code, _, errOut := invoke("encode", "--cluster", "OnOff", "--command", "Dim")
+	c.Assert(code, Equals, 1)
+	c.Assert(errOut, Equals, "zcl: unknown command \"Dim\" of cluster OnOff\n")
+
+	code, _, errOut = invoke("encode", "--cluster", "OnOff", "--command", "On", "--payload", "{")
+	c.Assert(code, Equals, 1)
+	c.Assert(errOut, Matches, "zcl: invalid On payload.*\n")
+}
+
+func (s *MainSuite) TestList(c *C) {
+	_, out, _ := invoke("list", "clusters")
+	c.Assert(strings.Split(out, "\n")[6], Equals, "0x0006 OnOff")
+
+	_, out, _ = invoke("list", "attributes", "OnOff")
+	c.Assert(strings.Split(out, "\n")[0], Equals, "0x0000 OnOff Boolean Read|Reportable|Scene")
+
+	_, out, _ = invoke("list", "commands", "--json", "0x0003")
+	var commands []map[string]string
+	c.Assert(json.Unmarshal([]byte(out), &commands), IsNil)
+	c.Assert(commands[0], DeepEquals, map[string]string{"id": "0x00", "name": "Identify", "direction": "ClientToServer"})
+	c.Assert(commands[len(commands)-1]["direction"], Equals, "ServerToClient")
+
+	_, out, _ = invoke("list", "commands")
+	c.Assert(strings.Split(out, "\n")[0], Equals, "0x00 ReadAttributes")
+
+	code, _, errOut := invoke("list", "attributes", "Nope")
+	c.Assert(code, Equals, 1)
+	c.Assert(errOut, Equals, "zcl: unknown cluster \"Nope\"\n")
+}
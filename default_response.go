@@ -0,0 +1,71 @@
+package zcl
+
+import (
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+// DefaultResponse builds the DefaultResponse owed for an incoming command given the status the
+// handler finished with and whether it already sent a command-specific response. It returns nil
+// when no DefaultResponse must be sent: for broadcast and group-addressed commands, for default
+// responses themselves, for commands that got a specific response whatever their status, and for
+// successful commands that disabled the default response.
+func DefaultResponse(im *ZclIncomingMessage, status cluster.ZclStatus, specificResponseSent bool) *ZclOutgoingMessage {
+	f := im.Data
+	if f == nil || f.FrameControl == nil {
+		return nil
+	}
+	if im.WasBroadcast || im.GroupID != 0 {
+		return nil
+	}
+	if f.FrameControl.FrameType == frame.FrameTypeGlobal && f.CommandIdentifier == uint8(cluster.ZclCommandDefaultResponse) {
+		return nil
+	}
+	if specificResponseSent {
+		return nil
+	}
+	if status == cluster.ZclStatusSuccess && f.FrameControl.DisableDefaultResponse {
+		return nil
+	}
+
+	builder := frame.New().
+		IdGenerator(func() uint8 { return f.TransactionSequenceNumber }).
+		FrameType(frame.FrameTypeGlobal).
+		Direction(oppositeDirection(f.FrameControl.Direction)).
+		DisableDefaultResponse(true).
+		CommandId(uint8(cluster.ZclCommandDefaultResponse)).
+		Command(&cluster.DefaultResponseCommand{CommandID: f.CommandIdentifier, Status: status})
+	if f.FrameControl.ManufacturerSpecific {
+		builder.ManufacturerCode(f.ManufacturerCode)
+	}
+	response := frame.MustBuild(builder)
+	return &ZclOutgoingMessage{
+		DstAddrMode: AddressModeNwk,
+		DstAddr:     im.SrcAddr,
+		DstEndpoint: im.SrcEndpoint,
+		SrcEndpoint: im.DstEndpoint,
+		ClusterID:   im.ClusterID,
+		ProfileID:   im.ProfileID,
+		Frame:       response,
+	}
+}
+
+// UnsupportedCommandStatus returns the status to report for a command that couldn't be decoded.
+func UnsupportedCommandStatus(fc *ZclFrameControl) cluster.ZclStatus {
+	switch {
+	case fc.FrameType == frame.FrameTypeGlobal && fc.ManufacturerSpecific:
+		return cluster.ZclStatusUnsupManuGeneralCommand
+	case fc.FrameType == frame.FrameTypeGlobal:
+		return cluster.ZclStatusUnsupGeneralCommand
+	case fc.ManufacturerSpecific:
+		return cluster.ZclStatusUnsupManuClusterCommand
+	}
+	return cluster.ZclStatusUnsupClusterCommand
+}
+
+func oppositeDirection(direction frame.Direction) frame.Direction {
+	if direction == frame.DirectionClientServer {
+		return frame.DirectionServerClient
+	}
+	return frame.DirectionClientServer
+}
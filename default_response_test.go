@@ -0,0 +1,60 @@
+package zcl
+
+import (
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	. "gopkg.in/check.v1"
+)
+
+type DefaultResponseSuite struct{}
+
+var _ = Suite(&DefaultResponseSuite{})
+
+func incoming(data []uint8) *ZclIncomingMessage {
+	im, _ := New().ToZclIncomingMessage(&ApplicationMessage{
+		SrcAddr: "0x1234", SrcEndpoint: 1, DstEndpoint: 2, ClusterID: uint16(cluster.OnOff), ProfileID: 0x0104, Data: data,
+	})
+	return im
+}
+
+func (s *DefaultResponseSuite) TestSuccess(c *C) {
+	im := incoming([]uint8{0x01, 0x2a, 0x01}) // On
+	m := DefaultResponse(im, cluster.ZclStatusSuccess, false)
+	c.Assert(m, NotNil)
+	c.Assert(m.DstAddr, Equals, "0x1234")
+	c.Assert(m.DstEndpoint, Equals, uint8(1))
+	c.Assert(m.SrcEndpoint, Equals, uint8(2))
+	c.Assert(frame.Encode(m.Frame), DeepEquals, []uint8{0x18, 0x2a, 0x0b, 0x01, 0x00})
+}
+
+func (s *DefaultResponseSuite) TestDisabled(c *C) {
+	im := incoming([]uint8{0x11, 0x2a, 0x01})
+	c.Assert(DefaultResponse(im, cluster.ZclStatusSuccess, false), IsNil)
+	m := DefaultResponse(im, cluster.ZclStatusFailure, false)
+	c.Assert(frame.Encode(m.Frame), DeepEquals, []uint8{0x18, 0x2a, 0x0b, 0x01, 0x01})
+}
+
+func (s *DefaultResponseSuite) TestSpecificResponseSent(c *C) {
+	im := incoming([]uint8{0x00, 0x2a, 0x00, 0x00, 0x00}) // ReadAttributes
+	c.Assert(DefaultResponse(im, cluster.ZclStatusSuccess, true), IsNil)
+	c.Assert(DefaultResponse(im, cluster.ZclStatusFailure, true), IsNil)
+}
+
+func (s *DefaultResponseSuite) TestManufacturerSpecific(c *C) {
+	im := incoming([]uint8{0x05, 0x5f, 0x11, 0x2a, 0x7f})
+	m := DefaultResponse(im, UnsupportedCommandStatus(im.Data.FrameControl), false)
+	c.Assert(frame.Encode(m.Frame), DeepEquals, []uint8{0x1c, 0x5f, 0x11, 0x2a, 0x0b, 0x7f, 0x83})
+}
+
+func (s *DefaultResponseSuite) TestNoReply(c *C) {
+	im := incoming([]uint8{0x01, 0x2a, 0x01})
+	im.WasBroadcast = true
+	c.Assert(DefaultResponse(im, cluster.ZclStatusFailure, false), IsNil)
+
+	im = incoming([]uint8{0x01, 0x2a, 0x01})
+	im.GroupID = 5
+	c.Assert(DefaultResponse(im, cluster.ZclStatusFailure, false), IsNil)
+
+	im = incoming([]uint8{0x08, 0x2a, 0x0b, 0x01, 0x81})
+	c.Assert(DefaultResponse(im, cluster.ZclStatusFailure, false), IsNil)
+}
@@ -0,0 +1,494 @@
+package zcl
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/reflection"
+)
+
+var errTruncated = errors.New("frame is truncated")
+
+// Field is a node of a dissected frame. Offset and Length locate its bytes in the frame; bit fields
+// share the bytes they are packed in.
+type Field struct {
+	Name     string
+	Value    string
+	Offset   int
+	Length   int
+	Raw      []byte
+	Children []*Field
+}
+
+// Dissection is the outcome of Dissect. When the frame couldn't be decoded completely Err is set
+// and Fields holds everything decoded before the failure, followed by the undecoded bytes.
+type Dissection struct {
+	Fields      []*Field
+	CommandName string
+	Command     interface{}
+	Err         error
+}
+
+// Dissect decodes a frame of the cluster field by field using the default cluster library.
+func Dissect(clusterId uint16, payload []byte) *Dissection {
+	return defaultZcl.Dissect(clusterId, payload)
+}
+
+func (z *Zcl) Dissect(clusterId uint16, payload []byte) *Dissection {
+	d := &Dissection{}
+	c := &cursor{data: payload}
+	if err := z.dissect(d, c, clusterId); err != nil {
+		d.Err = err
+		if c.pos < len(payload) {
+			d.Fields = append(d.Fields, c.field("Undecoded", "", c.pos, len(payload)-c.pos))
+		}
+	} else if c.pos < len(payload) {
+		d.Fields = append(d.Fields, c.field("Trailing bytes", "", c.pos, len(payload)-c.pos))
+	}
+	return d
+}
+
+func (z *Zcl) dissect(d *Dissection, c *cursor, clusterId uint16) error {
+	b, err := c.read(1)
+	if err != nil {
+		return err
+	}
+	fc := &frame.FrameControl{
+		FrameType:              frame.FrameType(b[0] & 0x03),
+		ManufacturerSpecific:   (b[0] >> 2) & 1,
+		Direction:              frame.Direction((b[0] >> 3) & 1),
+		DisableDefaultResponse: (b[0] >> 4) & 1,
+	}
+	control := c.field("Frame Control", fmt.Sprintf("0x%02x", b[0]), 0, 1)
+	control.Children = []*Field{
+		c.field("Frame Type", fc.FrameType.String(), 0, 1),
+		c.field("Manufacturer Specific", strconv.FormatBool(fc.ManufacturerSpecific == 1), 0, 1),
+		c.field("Direction", fc.Direction.String(), 0, 1),
+		c.field("Disable Default Response", strconv.FormatBool(fc.DisableDefaultResponse == 1), 0, 1),
+	}
+	d.Fields = append(d.Fields, control)
+
+	if fc.ManufacturerSpecific == 1 {
+		start := c.pos
+		b, err := c.read(2)
+		if err != nil {
+			return err
+		}
+		d.Fields = append(d.Fields, c.field("Manufacturer Code", fmt.Sprintf("0x%04x", uint16(b[0])|uint16(b[1])<<8), start, 2))
+	}
+	start := c.pos
+	if b, err = c.read(1); err != nil {
+		return err
+	}
+	d.Fields = append(d.Fields, c.field("Transaction Sequence Number", strconv.Itoa(int(b[0])), start, 1))
+
+	start = c.pos
+	if b, err = c.read(1); err != nil {
+		return err
+	}
+	commandId := b[0]
+	cd, err := z.commandDescriptor(clusterId, fc.FrameType, fc.Direction, commandId)
+	if err != nil {
+		d.Fields = append(d.Fields, c.field("Command Identifier", fmt.Sprintf("0x%02x", commandId), start, 1))
+		return err
+	}
+	d.Fields = append(d.Fields, c.field("Command Identifier", fmt.Sprintf("0x%02x %s", commandId, cd.Name), start, 1))
+	d.CommandName = cd.Name
+
+	command := reflection.Copy(cd.Command)
+	w := &walker{z: z, c: c, clusterId: clusterId, global: fc.FrameType == frame.FrameTypeGlobal}
+	if reflect.TypeOf(command).Implements(serializableType) {
+		// commands with their own codec are decoded as a whole
+		value := reflect.New(reflect.TypeOf(command)).Elem()
+		node, err := w.serializable(cd.Name, value)
+		if err != nil {
+			return err
+		}
+		d.Fields = append(d.Fields, node)
+		d.Command = value.Interface()
+		return nil
+	}
+	node := &Field{Name: cd.Name, Offset: c.pos}
+	d.Fields = append(d.Fields, node)
+	err = w.strukt(node, reflect.ValueOf(command).Elem())
+	node.Length = c.pos - node.Offset
+	node.Raw = c.data[node.Offset:c.pos]
+	if err == nil {
+		d.Command = command
+	}
+	return err
+}
+
+// String renders the fields as an indented tree with their byte ranges and raw bytes.
+func (d *Dissection) String() string {
+	var b strings.Builder
+	var write func(fields []*Field, indent string)
+	write = func(fields []*Field, indent string) {
+		for _, f := range fields {
+			b.WriteString(indent + f.Name)
+			if f.Value != "" {
+				b.WriteString(": " + f.Value)
+			}
+			fmt.Fprintf(&b, " [%d:%d]", f.Offset, f.Offset+f.Length)
+			if len(f.Raw) > 0 && len(f.Children) == 0 {
+				b.WriteString(" " + hexBytes(f.Raw))
+			}
+			b.WriteString("\n")
+			write(f.Children, indent+"  ")
+		}
+	}
+	write(d.Fields, "")
+	if d.Err != nil {
+		b.WriteString("Error: " + d.Err.Error() + "\n")
+	}
+	return b.String()
+}
+
+func hexBytes(b []byte) string {
+	s := hex.EncodeToString(b)
+	parts := make([]string, 0, len(b))
+	for i := 0; i < len(s); i += 2 {
+		parts = append(parts, s[i:i+2])
+	}
+	return strings.Join(parts, " ")
+}
+
+type cursor struct {
+	data []byte
+	pos  int
+}
+
+func (c *cursor) read(n int) ([]byte, error) {
+	if c.pos+n > len(c.data) {
+		return nil, errTruncated
+	}
+	b := c.data[c.pos : c.pos+n]
+	c.pos += n
+	return b, nil
+}
+
+func (c *cursor) field(name string, value string, offset int, length int) *Field {
+	return &Field{Name: name, Value: value, Offset: offset, Length: length, Raw: c.data[offset : offset+length]}
+}
+
+// shortReader records whether a read hit the end of the data.
+type shortReader struct {
+	r     *bytes.Reader
+	short bool
+}
+
+func (s *shortReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if n < len(p) {
+		s.short = true
+	}
+	return n, err
+}
+
+type serializable interface {
+	Serialize(w io.Writer)
+	Deserialize(r io.Reader)
+}
+
+var serializableType = reflect.TypeOf((*serializable)(nil)).Elem()
+
+// walker decodes a command following the rules of the bin codec while recording the fields.
+type walker struct {
+	z         *Zcl
+	c         *cursor
+	clusterId uint16
+	global    bool
+}
+
+func (w *walker) strukt(node *Field, value reflect.Value) error {
+	var bitmask uint64
+	var bitmaskStart, bitmaskLength int
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		sf := value.Type().Field(i)
+		if sf.Tag.Get("transient") == "true" || !condition(sf.Tag.Get("cond"), value) {
+			continue
+		}
+		if sf.Tag.Get("bits") != "" {
+			if sf.Tag.Get("bitmask") == "start" {
+				bitmaskStart, bitmaskLength = w.c.pos, int(field.Type().Size())
+				b, err := w.c.read(bitmaskLength)
+				if err != nil {
+					return err
+				}
+				bitmask = readUint(b, sf.Tag.Get("endianness"))
+			}
+			bits := bitmaskBits(sf.Tag.Get("bits"))
+			shift := uint(0)
+			for bits != 0 && bits>>shift&1 == 0 {
+				shift++
+			}
+			field.SetUint(bitmask & bits >> shift)
+			node.Children = append(node.Children, w.c.field(sf.Name, w.format(sf.Name, field), bitmaskStart, bitmaskLength))
+			continue
+		}
+		child, err := w.value(sf.Name, field, sf.Tag)
+		if child != nil {
+			node.Children = append(node.Children, child)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if id := value.FieldByName("AttributeID"); w.global && id.IsValid() && id.Kind() == reflect.Uint16 {
+		node.Value = w.attributeName(uint16(id.Uint()))
+	}
+	return nil
+}
+
+func (w *walker) value(name string, value reflect.Value, tag reflect.StructTag) (*Field, error) {
+	start := w.c.pos
+	done := func(f *Field) *Field {
+		f.Offset, f.Length, f.Raw = start, w.c.pos-start, w.c.data[start:w.c.pos]
+		return f
+	}
+	switch value.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size := int(value.Type().Size())
+		if bound := tag.Get("bound"); bound != "" {
+			size, _ = strconv.Atoi(bound)
+		}
+		b, err := w.c.read(size)
+		if err != nil {
+			return nil, err
+		}
+		value.SetUint(readUint(b, tag.Get("endianness")))
+		return done(&Field{Name: name, Value: w.format(name, value)}), nil
+	case reflect.String:
+		var length int
+		if size := tag.Get("hex"); size != "" {
+			n, _ := strconv.Atoi(size)
+			b, err := w.c.read(n)
+			if err != nil {
+				return nil, err
+			}
+			value.SetString(fmt.Sprintf("0x%0*x", 2*n, readUint(b, tag.Get("endianness"))))
+			return done(&Field{Name: name, Value: value.String()}), nil
+		}
+		if size := tag.Get("size"); size != "" {
+			n, _ := strconv.Atoi(size)
+			b, err := w.c.read(n)
+			if err != nil {
+				return nil, err
+			}
+			length = int(readUint(b, tag.Get("endianness")))
+		} else {
+			length = len(w.c.data) - w.c.pos
+		}
+		b, err := w.c.read(length)
+		if err != nil {
+			return nil, err
+		}
+		value.SetString(string(b))
+		return done(&Field{Name: name, Value: strconv.Quote(value.String())}), nil
+	case reflect.Array:
+		size := int(value.Type().Elem().Size())
+		for i := 0; i < value.Len(); i++ {
+			b, err := w.c.read(size)
+			if err != nil {
+				return nil, err
+			}
+			value.Index(i).SetUint(readUint(b, tag.Get("endianness")))
+		}
+		return done(&Field{Name: name}), nil
+	case reflect.Slice:
+		node := &Field{Name: name}
+		length := -1
+		if size := tag.Get("size"); size != "" {
+			n, _ := strconv.Atoi(size)
+			b, err := w.c.read(n)
+			if err != nil {
+				return done(node), err
+			}
+			length = int(readUint(b, tag.Get("endianness")))
+		}
+		value.Set(reflect.MakeSlice(value.Type(), 0, 0))
+		for i := 0; i != length && (length >= 0 || w.c.pos < len(w.c.data)); i++ {
+			element := reflect.New(value.Type().Elem()).Elem()
+			child, err := w.value(fmt.Sprintf("%s[%d]", name, i), element, tag)
+			if child != nil {
+				node.Children = append(node.Children, child)
+			}
+			if err != nil {
+				return done(node), err
+			}
+			value.Set(reflect.Append(value, element))
+		}
+		return done(node), nil
+	case reflect.Ptr:
+		if value.Type().Implements(serializableType) {
+			return w.serializable(name, value)
+		}
+		value.Set(reflect.New(value.Type().Elem()))
+		return w.value(name, value.Elem(), tag)
+	case reflect.Struct:
+		node := &Field{Name: name}
+		err := w.strukt(node, value)
+		return done(node), err
+	}
+	return nil, nil
+}
+
+func (w *walker) serializable(name string, value reflect.Value) (f *Field, err error) {
+	start := w.c.pos
+	r := &shortReader{r: bytes.NewReader(w.c.data[start:])}
+	value.Set(reflect.New(value.Type().Elem()))
+	func() {
+		defer func() {
+			if recover() != nil {
+				r.short = true
+			}
+		}()
+		value.Interface().(serializable).Deserialize(r)
+	}()
+	if r.short {
+		return nil, errTruncated
+	}
+	w.c.pos = len(w.c.data) - r.r.Len()
+	f = w.c.field(name, formatValue(value), start, w.c.pos-start)
+	if _, ok := value.Interface().(*cluster.Attribute); ok && f.Length > 0 {
+		f.Children = []*Field{
+			w.c.field("DataType", formatValue(value.Elem().FieldByName("DataType")), start, 1),
+			w.c.field("Value", formatAttributeValue(value.Interface().(*cluster.Attribute)), start+1, f.Length-1),
+		}
+	}
+	return f, nil
+}
+
+// format renders an integer, naming it when its type has a String method and annotating
+// attribute identifiers with their names.
+func (w *walker) format(name string, value reflect.Value) string {
+	width := 2 * int(value.Type().Size())
+	if s, ok := value.Interface().(fmt.Stringer); ok {
+		return fmt.Sprintf("%s (0x%0*x)", s.String(), width, value.Uint())
+	}
+	if strings.HasSuffix(name, "ID") || strings.HasSuffix(name, "Id") {
+		s := fmt.Sprintf("0x%0*x", width, value.Uint())
+		if name == "AttributeID" && w.global {
+			if n := w.attributeName(uint16(value.Uint())); n != "" {
+				s += " " + n
+			}
+		}
+		return s
+	}
+	return strconv.FormatUint(value.Uint(), 10)
+}
+
+func (w *walker) attributeName(attributeId uint16) string {
+	return w.z.getAttributeName(w.clusterId, attributeId)
+}
+
+// formatValue renders a decoded value, following pointers and naming the fields of structs.
+func formatValue(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if value.IsNil() {
+			return "nil"
+		}
+		if a, ok := value.Interface().(*cluster.Attribute); ok {
+			return a.DataType.String() + " " + formatAttributeValue(a)
+		}
+		return formatValue(value.Elem())
+	case reflect.Struct:
+		parts := []string{}
+		for i := 0; i < value.NumField(); i++ {
+			sf := value.Type().Field(i)
+			if sf.Tag.Get("transient") == "true" {
+				continue
+			}
+			parts = append(parts, sf.Name+": "+formatValue(value.Field(i)))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case reflect.Slice:
+		parts := []string{}
+		for i := 0; i < value.Len(); i++ {
+			parts = append(parts, formatValue(value.Index(i)))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case reflect.String:
+		return strconv.Quote(value.String())
+	case reflect.Array:
+		return "0x" + hex.EncodeToString(arrayBytes(value))
+	}
+	if s, ok := value.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+func formatAttributeValue(a *cluster.Attribute) string {
+	if v, ok := a.Value.(uint64); ok && a.DataType >= cluster.ZclDataTypeBitmap8 && a.DataType <= cluster.ZclDataTypeBitmap64 {
+		return fmt.Sprintf("0x%0*x", 2*int(a.DataType-cluster.ZclDataTypeBitmap8+1), v)
+	}
+	return formatValue(reflect.ValueOf(&a.Value).Elem())
+}
+
+func arrayBytes(value reflect.Value) []byte {
+	b := make([]byte, value.Len())
+	for i := range b {
+		b[i] = byte(value.Index(i).Uint())
+	}
+	return b
+}
+
+func readUint(b []byte, endianness string) uint64 {
+	var v uint64
+	for i, t := range b {
+		if endianness == "be" {
+			v = v<<8 | uint64(t)
+		} else {
+			v |= uint64(t) << uint(8*i)
+		}
+	}
+	return v
+}
+
+// condition evaluates the cond tag of the bin codec, e.g. "uint:Status!=0".
+func condition(cond string, parent reflect.Value) bool {
+	if cond == "" {
+		return true
+	}
+	for _, c := range strings.Split(cond, ";") {
+		expression := strings.TrimPrefix(c, "uint:")
+		op := "=="
+		if strings.Contains(expression, "!=") {
+			op = "!="
+		}
+		operands := strings.Split(expression, op)
+		v := parent
+		for _, name := range strings.Split(operands[0], ".") {
+			v = v.FieldByName(name)
+			for v.Kind() == reflect.Ptr {
+				v = v.Elem()
+			}
+		}
+		n, _ := strconv.ParseUint(operands[1], 10, 64)
+		if (v.Uint() == n) != (op == "==") {
+			return false
+		}
+	}
+	return true
+}
+
+// bitmaskBits parses the bits tag, written in binary with "0b" or in hex with "0x".
+func bitmaskBits(tag string) uint64 {
+	if strings.HasPrefix(tag, "0x") {
+		bits, _ := strconv.ParseUint(tag[2:], 16, 64)
+		return bits
+	}
+	bits, _ := strconv.ParseUint(strings.TrimPrefix(tag, "0b"), 2, 64)
+	return bits
+}
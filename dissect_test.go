@@ -0,0 +1,99 @@
+package zcl
+
+import (
+	"math/rand"
+
+	"github.com/dyrkin/bin"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/reflection"
+	. "gopkg.in/check.v1"
+)
+
+type DissectSuite struct{}
+
+var _ = Suite(&DissectSuite{})
+
+func (s *DissectSuite) TestReportAttributes(c *C) {
+	d := Dissect(uint16(cluster.TemperatureMeasurement), []uint8{0x1c, 0x5f, 0x11, 0x07, 0x0a, 0x00, 0x00, 0x29, 0x38, 0xff, 0x10, 0x00, 0x18, 0x05})
+	c.Assert(d.Err, IsNil)
+	c.Assert(d.CommandName, Equals, "ReportAttributes")
+	c.Assert(d.String(), Equals, `Frame Control: 0x1c [0:1]
+  Frame Type: Global [0:1] 1c
+  Manufacturer Specific: true [0:1] 1c
+  Direction: ServerToClient [0:1] 1c
+  Disable Default Response: true [0:1] 1c
+Manufacturer Code: 0x115f [1:3] 5f 11
+Transaction Sequence Number: 7 [3:4] 07
+Command Identifier: 0x0a ReportAttributes [4:5] 0a
+ReportAttributes [5:14]
+  AttributeReports [5:14]
+    AttributeReports[0]: MeasuredValue [5:10]
+      AttributeID: 0x0000 MeasuredValue [5:7] 00 00
+      Attribute: Int16 -200 [7:10]
+        DataType: Int16 [7:8] 29
+        Value: -200 [8:10] 38 ff
+    AttributeReports[1] [10:14]
+      AttributeID: 0x0010 [10:12] 10 00
+      Attribute: Bitmap8 0x05 [12:14]
+        DataType: Bitmap8 [12:13] 18
+        Value: 0x05 [13:14] 05
+`)
+}
+
+func (s *DissectSuite) TestPartiallyDecodable(c *C) {
+	d := Dissect(uint16(cluster.OnOff), []uint8{0x18, 0x08, 0x01, 0x00, 0x00, 0x00, 0x10, 0x01, 0x01, 0x00, 0x00, 0x21, 0x01})
+	c.Assert(d.Err, NotNil)
+	c.Assert(d.Command, IsNil)
+	statuses := d.Fields[3].Children[0]
+	c.Assert(statuses.Children, HasLen, 2)
+	c.Assert(statuses.Children[0].Value, Equals, "OnOff")
+	c.Assert(statuses.Children[1].Children[1].Value, Equals, "Success (0x00)")
+	last := d.Fields[len(d.Fields)-1]
+	c.Assert(last.Name, Equals, "Undecoded")
+	c.Assert(last.Offset, Equals, 11)
+	c.Assert(last.Raw, DeepEquals, []byte{0x21, 0x01})
+
+	d = Dissect(0xef00, []uint8{0x09, 0x01, 0x02, 0x00})
+	c.Assert(d.Err, ErrorMatches, "unknown cluster 61184")
+	c.Assert(d.Fields[2].Value, Equals, "0x02")
+	c.Assert(d.Fields[3].Name, Equals, "Undecoded")
+}
+
+// Dissect must decode every command of the library like the codec does.
+func (s *DissectSuite) TestMatchesCodec(c *C) {
+	z := New()
+	random := rand.New(rand.NewSource(1))
+	check := func(clusterId cluster.ClusterId, frameType frame.FrameType, direction frame.Direction, commandId uint8, cd *cluster.CommandDescriptor) {
+		for i := 0; i < 20; i++ {
+			data := make([]byte, random.Intn(24))
+			random.Read(data)
+			command := reflection.Copy(cd.Command)
+			bin.Decode(data, command)
+			f, err := frame.New().FrameType(frameType).Direction(direction).CommandId(commandId).Command(command).Build()
+			c.Assert(err, IsNil)
+			encoded := frame.Encode(f)
+			expected := reflection.Copy(cd.Command)
+			bin.Decode(encoded[3:], expected)
+			c.Assert(bin.Encode(expected), DeepEquals, encoded[3:], Commentf("%s", cd.Name))
+
+			d := z.Dissect(uint16(clusterId), encoded)
+			c.Assert(d.Err, IsNil, Commentf("%s %x", cd.Name, encoded))
+			c.Assert(d.Command, DeepEquals, expected, Commentf("%s %x", cd.Name, encoded))
+		}
+	}
+	for commandId, cd := range z.ClusterLibrary().Global() {
+		check(cluster.Basic, frame.FrameTypeGlobal, frame.DirectionClientServer, commandId, cd)
+	}
+	for clusterId, cl := range z.ClusterLibrary().Clusters() {
+		if cl.CommandDescriptors == nil {
+			continue
+		}
+		for commandId, cd := range cl.CommandDescriptors.Received {
+			check(clusterId, frame.FrameTypeLocal, frame.DirectionClientServer, commandId, cd)
+		}
+		for commandId, cd := range cl.CommandDescriptors.Generated {
+			check(clusterId, frame.FrameTypeLocal, frame.DirectionServerClient, commandId, cd)
+		}
+	}
+}
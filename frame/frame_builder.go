@@ -2,11 +2,14 @@ package frame
 
 import (
 	"errors"
+	"sync/atomic"
+
 	"github.com/dyrkin/bin"
 )
 
 type frameConfiguration struct {
 	transactionIdProvider            func() uint8
+	transactionIdSource              func() (uint8, error)
 	frameType                        FrameType
 	frameTypeConfigured              bool
 	manufacturerCode                 uint16
@@ -22,6 +25,8 @@ type frameConfiguration struct {
 }
 
 type Builder interface {
+	IdGenerator(transactionIdProvider func() uint8) Builder
+	IdSource(transactionIdSource func() (uint8, error)) Builder
 	FrameType(frameType FrameType) Builder
 	ManufacturerCode(manufacturerCode uint16) Builder
 	Direction(direction Direction) Builder
@@ -39,6 +44,14 @@ func New() Builder {
 
 func (f *frameConfiguration) IdGenerator(transactionIdProvider func() uint8) Builder {
 	f.transactionIdProvider = transactionIdProvider
+	f.transactionIdSource = nil
+	return f
+}
+
+// IdSource takes the transaction id from a source which can fail, e.g. an allocator whose ids
+// are all in flight. Build returns the error of the source.
+func (f *frameConfiguration) IdSource(transactionIdSource func() (uint8, error)) Builder {
+	f.transactionIdSource = transactionIdSource
 	return f
 }
 
@@ -82,6 +95,10 @@ func (f *frameConfiguration) Build() (*Frame, error) {
 	if err := f.validateConfiguration(); err != nil {
 		return nil, err
 	}
+	transactionId, err := f.transactionId()
+	if err != nil {
+		return nil, err
+	}
 	frame := &Frame{}
 	frame.FrameControl = &FrameControl{}
 	frame.FrameControl.FrameType = f.frameType
@@ -89,7 +106,7 @@ func (f *frameConfiguration) Build() (*Frame, error) {
 	frame.FrameControl.Direction = f.direction
 	frame.FrameControl.DisableDefaultResponse = flag(f.disableDefaultResponse)
 	frame.ManufacturerCode = f.manufacturerCode
-	frame.TransactionSequenceNumber = f.transactionIdProvider()
+	frame.TransactionSequenceNumber = transactionId
 	frame.CommandIdentifier = f.commandId
 	if f.commandConfigured {
 		frame.Payload = bin.Encode(f.command)
@@ -99,6 +116,24 @@ func (f *frameConfiguration) Build() (*Frame, error) {
 	return frame, nil
 }
 
+// MustBuild builds a frame which can't fail to build: Build only fails for an unset frame type,
+// command id or direction and for a failing IdSource. It panics when the builder has either, as
+// that is a programming error.
+func MustBuild(builder Builder) *Frame {
+	frame, err := builder.Build()
+	if err != nil {
+		panic(err)
+	}
+	return frame
+}
+
+func (f *frameConfiguration) transactionId() (uint8, error) {
+	if f.transactionIdSource != nil {
+		return f.transactionIdSource()
+	}
+	return f.transactionIdProvider(), nil
+}
+
 func (f *frameConfiguration) validateConfiguration() error {
 	if !f.frameTypeConfigured {
 		return errors.New("frame type must be set")
@@ -121,13 +156,9 @@ func flag(flag bool) uint8 {
 }
 
 func MakeDefaultTransactionIdProvider() func() uint8 {
-	transactionId := uint8(1)
+	var transactionId uint32
 	return func() uint8 {
-		transactionId = transactionId + 1
-		if transactionId > 255 {
-			transactionId = 1
-		}
-		return transactionId
+		return uint8((atomic.AddUint32(&transactionId, 1)-1)%255) + 1
 	}
 }
 
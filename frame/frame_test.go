@@ -1,6 +1,7 @@
 package frame
 
 import (
+	"sync"
 	"testing"
 
 	. "gopkg.in/check.v1"
@@ -56,3 +57,108 @@ func (s *FrameSuite) TestDecode(c *C) {
 	res = Decode([]uint8{0x11, 0x1, 0x5, 0x0, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8, 0x9})
 	c.Assert(res, DeepEquals, frame)
 }
+
+func (s *FrameSuite) TestBuilderIdGenerator(c *C) {
+	frame, err := New().
+		IdGenerator(func() uint8 { return 42 }).
+		FrameType(FrameTypeGlobal).
+		Direction(DirectionClientServer).
+		CommandId(0x00).
+		Build()
+	c.Assert(err, IsNil)
+	c.Assert(frame.TransactionSequenceNumber, Equals, uint8(42))
+}
+
+func (s *FrameSuite) TestMustBuild(c *C) {
+	frame := MustBuild(New().IdGenerator(func() uint8 { return 42 }).FrameType(FrameTypeGlobal).Direction(DirectionClientServer).CommandId(0x00))
+	c.Assert(frame.TransactionSequenceNumber, Equals, uint8(42))
+	c.Assert(func() { MustBuild(New().FrameType(FrameTypeGlobal)) }, PanicMatches, "command id must be set")
+}
+
+func (s *FrameSuite) TestDefaultTransactionIdProviderWraps(c *C) {
+	provider := MakeDefaultTransactionIdProvider()
+	seen := map[uint8]bool{}
+	for i := 0; i < 255; i++ {
+		id := provider()
+		c.Assert(id, Not(Equals), uint8(0))
+		seen[id] = true
+	}
+	c.Assert(seen, HasLen, 255)
+	c.Assert(provider(), Equals, uint8(1))
+}
+
+func (s *FrameSuite) TestAllocatorSkipsInFlight(c *C) {
+	allocator := NewTransactionIdAllocator()
+	first, _ := allocator.Allocate("0x1234")
+	second, _ := allocator.Allocate("0x1234")
+	c.Assert(second, Equals, first+1)
+	other, _ := allocator.Allocate("0x5678")
+	c.Assert(other, Equals, first)
+
+	for i := 0; i < 254; i++ {
+		_, err := allocator.Allocate("0x1234")
+		c.Assert(err, IsNil)
+	}
+	_, err := allocator.Allocate("0x1234")
+	c.Assert(err, Equals, ErrNoFreeTransactionId)
+
+	allocator.Release("0x1234", second)
+	id, err := allocator.Allocate("0x1234")
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, second)
+	c.Assert(allocator.InFlight("0x1234"), Equals, 256)
+}
+
+func (s *FrameSuite) TestAllocatorProvider(c *C) {
+	allocator := NewTransactionIdAllocator()
+	builder := New().
+		IdSource(allocator.Provider("0x1234")).
+		FrameType(FrameTypeGlobal).
+		Direction(DirectionClientServer).
+		CommandId(0x00)
+	seen := map[uint8]bool{}
+	for i := 0; i < 256; i++ {
+		f, err := builder.Build()
+		c.Assert(err, IsNil)
+		c.Assert(seen[f.TransactionSequenceNumber], Equals, false)
+		seen[f.TransactionSequenceNumber] = true
+	}
+	_, err := builder.Build()
+	c.Assert(err, Equals, ErrNoFreeTransactionId)
+}
+
+func (s *FrameSuite) TestAllocatorConcurrent(c *C) {
+	allocator := NewTransactionIdAllocator()
+	ids := make(chan uint8, 200)
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := allocator.Allocate("0x1234")
+			c.Check(err, IsNil)
+			ids <- id
+		}()
+	}
+	wg.Wait()
+	close(ids)
+	seen := map[uint8]bool{}
+	for id := range ids {
+		c.Assert(seen[id], Equals, false)
+		seen[id] = true
+	}
+	c.Assert(seen, HasLen, 200)
+}
+
+func (s *FrameSuite) TestFrameControlStrings(c *C) {
+	c.Assert(FrameTypeLocal.String(), Equals, "Local")
+	c.Assert(DirectionServerClient.String(), Equals, "ServerToClient")
+	frameType, err := ParseFrameType("global")
+	c.Assert(err, IsNil)
+	c.Assert(frameType, Equals, FrameTypeGlobal)
+	direction, err := ParseDirection("SERVER_TO_CLIENT")
+	c.Assert(err, IsNil)
+	c.Assert(direction, Equals, DirectionServerClient)
+	_, err = ParseDirection("Sideways")
+	c.Assert(err, NotNil)
+}
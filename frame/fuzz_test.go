@@ -0,0 +1,26 @@
+package frame
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dyrkin/zcl-go/internal/alloc"
+)
+
+func FuzzDecode(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0x18, 0x01, 0x0a, 0x00, 0x00, 0x20, 0x01})
+	f.Add([]byte{0x1c, 0x5f, 0x11, 0x02, 0x0a})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var frame *Frame
+		allocated := alloc.Bytes(func() {
+			frame = Decode(data)
+		})
+		if limit := 1<<12 + 64*uint64(len(data)); allocated > limit {
+			t.Fatalf("decoding %d bytes allocated %d bytes", len(data), allocated)
+		}
+		if decoded := Decode(Encode(frame)); !reflect.DeepEqual(decoded, frame) {
+			t.Fatalf("%x decoded to %#v, which decodes to %#v once encoded", data, frame, decoded)
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package frame
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (t FrameType) String() string {
+	switch t {
+	case FrameTypeGlobal:
+		return "Global"
+	case FrameTypeLocal:
+		return "Local"
+	}
+	return fmt.Sprintf("0x%02x", uint8(t))
+}
+
+func (d Direction) String() string {
+	switch d {
+	case DirectionClientServer:
+		return "ClientToServer"
+	case DirectionServerClient:
+		return "ServerToClient"
+	}
+	return fmt.Sprintf("0x%02x", uint8(d))
+}
+
+// ParseFrameType accepts the names returned by String in any case.
+func ParseFrameType(s string) (FrameType, error) {
+	for _, t := range []FrameType{FrameTypeGlobal, FrameTypeLocal} {
+		if strings.EqualFold(s, t.String()) {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown frame type %q", s)
+}
+
+// ParseDirection accepts the names returned by String in any case and with or without
+// underscores, e.g. "SERVER_TO_CLIENT".
+func ParseDirection(s string) (Direction, error) {
+	for _, d := range []Direction{DirectionClientServer, DirectionServerClient} {
+		if strings.EqualFold(strings.Replace(s, "_", "", -1), d.String()) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown direction %q", s)
+}
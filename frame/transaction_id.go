@@ -0,0 +1,77 @@
+package frame
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrNoFreeTransactionId = errors.New("all transaction ids are in flight")
+
+type destinationIds struct {
+	next     uint8
+	inFlight [256]bool
+	count    int
+}
+
+// TransactionIdAllocator hands out transaction sequence numbers per destination. An id stays in
+// flight until it's released, and isn't handed out to the same destination again until then.
+type TransactionIdAllocator struct {
+	mutex        sync.Mutex
+	destinations map[string]*destinationIds
+}
+
+func NewTransactionIdAllocator() *TransactionIdAllocator {
+	return &TransactionIdAllocator{destinations: map[string]*destinationIds{}}
+}
+
+func (a *TransactionIdAllocator) Allocate(destination string) (uint8, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	d := a.destination(destination)
+	if d.count == len(d.inFlight) {
+		return 0, ErrNoFreeTransactionId
+	}
+	for d.inFlight[d.next] {
+		d.next++
+	}
+	id := d.next
+	d.inFlight[id] = true
+	d.count++
+	d.next++
+	return id, nil
+}
+
+func (a *TransactionIdAllocator) Release(destination string, id uint8) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if d, ok := a.destinations[destination]; ok && d.inFlight[id] {
+		d.inFlight[id] = false
+		d.count--
+	}
+}
+
+func (a *TransactionIdAllocator) InFlight(destination string) int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if d, ok := a.destinations[destination]; ok {
+		return d.count
+	}
+	return 0
+}
+
+// Provider returns a source suitable for Builder.IdSource. Building a frame fails with
+// ErrNoFreeTransactionId while every id of the destination is in flight.
+func (a *TransactionIdAllocator) Provider(destination string) func() (uint8, error) {
+	return func() (uint8, error) {
+		return a.Allocate(destination)
+	}
+}
+
+func (a *TransactionIdAllocator) destination(destination string) *destinationIds {
+	d, ok := a.destinations[destination]
+	if !ok {
+		d = &destinationIds{next: 1}
+		a.destinations[destination] = d
+	}
+	return d
+}
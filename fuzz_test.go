@@ -0,0 +1,42 @@
+package zcl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dyrkin/bin"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/internal/alloc"
+)
+
+func FuzzToZclIncomingMessage(f *testing.F) {
+	f.Add(uint16(cluster.Basic), []byte{0x18, 0x01, 0x01, 0x04, 0x00, 0x00, 0x42, 0x03, 'a', 'b', 'c'})
+	f.Add(uint16(cluster.OnOff), []byte{0x18, 0x02, 0x0a, 0x00, 0x00, 0x10, 0x01})
+	f.Add(uint16(cluster.Groups), []byte{0x01, 0x03, 0x00, 0x05, 0x00, 0x07, 'k', 'i', 't', 'c', 'h', 'e', 'n'})
+	f.Add(uint16(cluster.IASACE), []byte{0x01, 0x04, 0x00, 0x01, 0x04, '1', '2', '3', '4', 0x02})
+	f.Add(uint16(cluster.TemperatureMeasurement), []byte{0x00, 0x05, 0x06, 0x00, 0x00, 0x00, 0x29, 0x0a, 0x00, 0x2c, 0x01, 0x32, 0x00})
+	z := New()
+	f.Fuzz(func(t *testing.T, clusterId uint16, data []byte) {
+		var im *ZclIncomingMessage
+		var err error
+		allocated := alloc.Bytes(func() {
+			im, err = z.ToZclIncomingMessage(&ApplicationMessage{ClusterID: clusterId, Data: data})
+		})
+		if limit := 1<<18 + 512*uint64(len(data)); allocated > limit {
+			t.Fatalf("decoding %d bytes allocated %d bytes", len(data), allocated)
+		}
+		if err != nil {
+			return
+		}
+		f := frame.Decode(data)
+		f.Payload = bin.Encode(im.Data.Command)
+		reencoded, err := z.ToZclIncomingMessage(&ApplicationMessage{ClusterID: clusterId, Data: frame.Encode(f)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(reencoded, im) {
+			t.Fatalf("%x decoded to %#v, which decodes to %#v once encoded", data, im.Data.Command, reencoded.Data.Command)
+		}
+	})
+}
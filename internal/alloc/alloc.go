@@ -0,0 +1,14 @@
+// Package alloc measures allocations for the fuzz targets, which check that decoding never
+// allocates memory in proportion to lengths or counts read from the input.
+package alloc
+
+import "runtime"
+
+// Bytes returns the number of bytes f allocates.
+func Bytes(f func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
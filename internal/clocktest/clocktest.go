@@ -0,0 +1,48 @@
+// Package clocktest provides a zcl.Clock for tests which only moves when told to.
+package clocktest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a manual zcl.Clock. After doesn't wait but moves the clock forward by the duration
+// and records it.
+type Clock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	waited []time.Duration
+}
+
+func New(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.waited = append(c.waited, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func (c *Clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Waited returns the durations passed to After.
+func (c *Clock) Waited() []time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]time.Duration(nil), c.waited...)
+}
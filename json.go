@@ -0,0 +1,236 @@
+package zcl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/reflection"
+)
+
+var defaultZcl = New()
+
+// hexId and hexByte are written as hex strings and read from hex strings or numbers.
+type hexId uint16
+
+type hexByte uint8
+
+func (id hexId) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("0x%04x", uint16(id)))
+}
+
+func (id *hexId) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalHex(data, 16)
+	*id = hexId(v)
+	return err
+}
+
+func (b hexByte) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("0x%02x", uint8(b)))
+}
+
+func (b *hexByte) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalHex(data, 8)
+	*b = hexByte(v)
+	return err
+}
+
+func unmarshalHex(data []byte, bitSize int) (uint64, error) {
+	var s string
+	if json.Unmarshal(data, &s) != nil {
+		return strconv.ParseUint(string(data), 10, bitSize)
+	}
+	if !strings.HasPrefix(s, "0x") {
+		return 0, fmt.Errorf("%q is not a hex number", s)
+	}
+	return strconv.ParseUint(s[2:], 16, bitSize)
+}
+
+type zclFrameControlJSON struct {
+	FrameType              string `json:"frameType"`
+	ManufacturerSpecific   bool   `json:"manufacturerSpecific"`
+	Direction              string `json:"direction"`
+	DisableDefaultResponse bool   `json:"disableDefaultResponse"`
+}
+
+func (fc *ZclFrameControl) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&zclFrameControlJSON{
+		FrameType:              fc.FrameType.String(),
+		ManufacturerSpecific:   fc.ManufacturerSpecific,
+		Direction:              fc.Direction.String(),
+		DisableDefaultResponse: fc.DisableDefaultResponse,
+	})
+}
+
+func (fc *ZclFrameControl) UnmarshalJSON(data []byte) error {
+	fcj := &zclFrameControlJSON{}
+	if err := json.Unmarshal(data, fcj); err != nil {
+		return err
+	}
+	frameType, err := frame.ParseFrameType(fcj.FrameType)
+	if err != nil {
+		return err
+	}
+	direction, err := frame.ParseDirection(fcj.Direction)
+	if err != nil {
+		return err
+	}
+	fc.FrameType = frameType
+	fc.ManufacturerSpecific = fcj.ManufacturerSpecific
+	fc.Direction = direction
+	fc.DisableDefaultResponse = fcj.DisableDefaultResponse
+	return nil
+}
+
+type zclFrameJSON struct {
+	FrameControl              *ZclFrameControl `json:"frameControl"`
+	ManufacturerCode          hexId            `json:"manufacturerCode"`
+	TransactionSequenceNumber uint8            `json:"transactionSequenceNumber"`
+	CommandIdentifier         hexByte          `json:"commandIdentifier"`
+	CommandName               string           `json:"commandName"`
+	Command                   interface{}      `json:"command"`
+}
+
+func (f *ZclFrame) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&zclFrameJSON{
+		FrameControl:              f.FrameControl,
+		ManufacturerCode:          hexId(f.ManufacturerCode),
+		TransactionSequenceNumber: f.TransactionSequenceNumber,
+		CommandIdentifier:         hexByte(f.CommandIdentifier),
+		CommandName:               f.CommandName,
+		Command:                   f.Command,
+	})
+}
+
+// UnmarshalJSON restores global commands into their command structs. Local commands depend on the
+// cluster, which the frame doesn't know about, so they are kept as json.RawMessage; unmarshal the
+// ZclIncomingMessage instead to restore them.
+func (f *ZclFrame) UnmarshalJSON(data []byte) error {
+	return defaultZcl.unmarshalFrame(data, nil, f)
+}
+
+type zclIncomingMessageJSON struct {
+	GroupID              hexId           `json:"groupId"`
+	ClusterID            hexId           `json:"clusterId"`
+	ClusterName          string          `json:"clusterName,omitempty"`
+	ProfileID            hexId           `json:"profileId"`
+	SrcAddr              string          `json:"srcAddr"`
+	DstAddr              string          `json:"dstAddr"`
+	SrcEndpoint          uint8           `json:"srcEndpoint"`
+	DstEndpoint          uint8           `json:"dstEndpoint"`
+	WasBroadcast         bool            `json:"wasBroadcast"`
+	LinkQuality          uint8           `json:"linkQuality"`
+	Rssi                 int8            `json:"rssi"`
+	SecurityUse          bool            `json:"securityUse"`
+	Timestamp            uint32          `json:"timestamp"`
+	TransactionSeqNumber uint8           `json:"transactionSeqNumber"`
+	Data                 json.RawMessage `json:"data"`
+}
+
+func (im *ZclIncomingMessage) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(im.Data)
+	if err != nil {
+		return nil, err
+	}
+	imj := &zclIncomingMessageJSON{
+		GroupID:              hexId(im.GroupID),
+		ClusterID:            hexId(im.ClusterID),
+		ProfileID:            hexId(im.ProfileID),
+		SrcAddr:              im.SrcAddr,
+		DstAddr:              im.DstAddr,
+		SrcEndpoint:          im.SrcEndpoint,
+		DstEndpoint:          im.DstEndpoint,
+		WasBroadcast:         im.WasBroadcast,
+		LinkQuality:          im.LinkQuality,
+		Rssi:                 im.Rssi,
+		SecurityUse:          im.SecurityUse,
+		Timestamp:            im.Timestamp,
+		TransactionSeqNumber: im.TransactionSeqNumber,
+		Data:                 data,
+	}
+	if c, ok := defaultZcl.library.Clusters()[cluster.ClusterId(im.ClusterID)]; ok {
+		imj.ClusterName = c.Name
+	}
+	return json.Marshal(imj)
+}
+
+// UnmarshalJSON restores the command using the default cluster library. Use
+// Zcl.UnmarshalIncomingMessage for clusters added to a library.
+func (im *ZclIncomingMessage) UnmarshalJSON(data []byte) error {
+	return defaultZcl.unmarshalIncomingMessage(data, im)
+}
+
+// UnmarshalIncomingMessage reads a message written by ZclIncomingMessage.MarshalJSON and restores
+// its command using the descriptors of the library.
+func (z *Zcl) UnmarshalIncomingMessage(data []byte) (*ZclIncomingMessage, error) {
+	im := &ZclIncomingMessage{}
+	return im, z.unmarshalIncomingMessage(data, im)
+}
+
+func (z *Zcl) unmarshalIncomingMessage(data []byte, im *ZclIncomingMessage) error {
+	imj := &zclIncomingMessageJSON{}
+	if err := json.Unmarshal(data, imj); err != nil {
+		return err
+	}
+	im.GroupID = uint16(imj.GroupID)
+	im.ClusterID = uint16(imj.ClusterID)
+	im.ProfileID = uint16(imj.ProfileID)
+	im.SrcAddr = imj.SrcAddr
+	im.DstAddr = imj.DstAddr
+	im.SrcEndpoint = imj.SrcEndpoint
+	im.DstEndpoint = imj.DstEndpoint
+	im.WasBroadcast = imj.WasBroadcast
+	im.LinkQuality = imj.LinkQuality
+	im.Rssi = imj.Rssi
+	im.SecurityUse = imj.SecurityUse
+	im.Timestamp = imj.Timestamp
+	im.TransactionSeqNumber = imj.TransactionSeqNumber
+	im.Data = nil
+	if len(imj.Data) == 0 || string(imj.Data) == "null" {
+		return nil
+	}
+	im.Data = &ZclFrame{}
+	return z.unmarshalFrame(imj.Data, &im.ClusterID, im.Data)
+}
+
+// unmarshalFrame restores the command of the frame, leaving local commands raw without a cluster.
+func (z *Zcl) unmarshalFrame(data []byte, clusterId *uint16, f *ZclFrame) error {
+	var command json.RawMessage
+	fj := &zclFrameJSON{Command: &command}
+	if err := json.Unmarshal(data, fj); err != nil {
+		return err
+	}
+	if fj.FrameControl == nil {
+		return fmt.Errorf("frame control is missing")
+	}
+	f.FrameControl = fj.FrameControl
+	f.ManufacturerCode = uint16(fj.ManufacturerCode)
+	f.TransactionSequenceNumber = fj.TransactionSequenceNumber
+	f.CommandIdentifier = uint8(fj.CommandIdentifier)
+	f.CommandName = fj.CommandName
+	f.Command = nil
+	if len(command) == 0 || string(command) == "null" {
+		return nil
+	}
+	if f.FrameControl.FrameType == frame.FrameTypeLocal && clusterId == nil {
+		f.Command = command
+		return nil
+	}
+	var id uint16
+	if clusterId != nil {
+		id = *clusterId
+	}
+	cd, err := z.commandDescriptor(id, f.FrameControl.FrameType, f.FrameControl.Direction, f.CommandIdentifier)
+	if err != nil {
+		return err
+	}
+	cmd := reflection.Copy(cd.Command)
+	if err := json.Unmarshal(command, cmd); err != nil {
+		return err
+	}
+	f.Command = cmd
+	return nil
+}
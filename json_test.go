@@ -0,0 +1,66 @@
+package zcl
+
+import (
+	"encoding/json"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	. "gopkg.in/check.v1"
+)
+
+type JSONSuite struct{}
+
+var _ = Suite(&JSONSuite{})
+
+func decoded(c *C, clusterId cluster.ClusterId, data []uint8) *ZclIncomingMessage {
+	im, err := New().ToZclIncomingMessage(&ApplicationMessage{
+		SrcAddr: "0x1234", SrcEndpoint: 1, DstEndpoint: 2, ClusterID: uint16(clusterId), ProfileID: 0x0104, LinkQuality: 200, Data: data,
+	})
+	c.Assert(err, IsNil)
+	return im
+}
+
+func (s *JSONSuite) TestGlobalRoundTrip(c *C) {
+	im := decoded(c, cluster.TemperatureMeasurement, []uint8{0x18, 0x07, 0x0a, 0x00, 0x00, 0x29, 0x38, 0xff, 0x10, 0x00, 0x18, 0x05})
+	data, err := json.Marshal(im)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, `{"groupId":"0x0000","clusterId":"0x0402","clusterName":"TemperatureMeasurement","profileId":"0x0104",`+
+		`"srcAddr":"0x1234","dstAddr":"","srcEndpoint":1,"dstEndpoint":2,"wasBroadcast":false,"linkQuality":200,"rssi":0,"securityUse":false,"timestamp":0,"transactionSeqNumber":0,`+
+		`"data":{"frameControl":{"frameType":"Global","manufacturerSpecific":false,"direction":"ServerToClient","disableDefaultResponse":true},`+
+		`"manufacturerCode":"0x0000","transactionSequenceNumber":7,"commandIdentifier":"0x0a","commandName":"ReportAttributes",`+
+		`"command":{"AttributeReports":[{"AttributeName":"MeasuredValue","AttributeID":0,"Attribute":{"dataType":"Int16","value":-200}},`+
+		`{"AttributeName":"","AttributeID":16,"Attribute":{"dataType":"Bitmap8","value":"0x05"}}]}}}`)
+
+	restored := &ZclIncomingMessage{}
+	c.Assert(json.Unmarshal(data, restored), IsNil)
+	c.Assert(restored, DeepEquals, im)
+
+	frameData, err := json.Marshal(im.Data)
+	c.Assert(err, IsNil)
+	frame := &ZclFrame{}
+	c.Assert(json.Unmarshal(frameData, frame), IsNil)
+	c.Assert(frame, DeepEquals, im.Data)
+}
+
+func (s *JSONSuite) TestLocalRoundTrip(c *C) {
+	im := decoded(c, cluster.LevelControl, []uint8{0x01, 0x08, 0x00, 0x80, 0x0a, 0x00})
+	c.Assert(im.Data.Command, DeepEquals, &cluster.MoveToLevelCommand{Level: 0x80, TransitionTime: 10})
+	data, err := json.Marshal(im)
+	c.Assert(err, IsNil)
+
+	restored := &ZclIncomingMessage{}
+	c.Assert(json.Unmarshal(data, restored), IsNil)
+	c.Assert(restored, DeepEquals, im)
+
+	// without the cluster a local command can't be typed
+	frameData, err := json.Marshal(im.Data)
+	c.Assert(err, IsNil)
+	frame := &ZclFrame{}
+	c.Assert(json.Unmarshal(frameData, frame), IsNil)
+	c.Assert(frame.CommandName, Equals, im.Data.CommandName)
+	c.Assert(string(frame.Command.(json.RawMessage)), Equals, `{"Level":128,"TransitionTime":10}`)
+
+	library := cluster.New()
+	delete(library.Clusters(), cluster.LevelControl)
+	_, err = NewWithLibrary(library).UnmarshalIncomingMessage(data)
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,214 @@
+package quirks
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/tuya"
+	"github.com/dyrkin/zcl-go/xiaomi"
+)
+
+// Match selects devices by the Basic cluster ManufacturerName and ModelIdentifier. A trailing
+// '*' matches any suffix and an empty field matches anything.
+type Match struct {
+	ManufacturerName string
+	ModelIdentifier  string
+}
+
+// Quirk describes how a device deviates from the ZCL specification. Every hook is optional.
+type Quirk struct {
+	Name    string
+	Matches []Match
+	// PatchLibrary adjusts the cluster library used to decode frames of matching devices.
+	PatchLibrary func(library *cluster.ClusterLibrary)
+	// RewriteFrame rewrites a raw frame before its command is decoded.
+	RewriteFrame func(m *zcl.ApplicationMessage, f *frame.Frame)
+	// TranslateAttribute replaces an attribute value read or reported by the device. It returns
+	// the attribute unchanged when it doesn't apply.
+	TranslateAttribute func(clusterId cluster.ClusterId, attributeId uint16, attribute *cluster.Attribute) *cluster.Attribute
+	// DecodeAttribute decodes a proprietary attribute value read or reported by the device into
+	// ZclIncomingMessage.DecodedAttributes, leaving the attribute as received. It returns nil when it
+	// doesn't apply.
+	DecodeAttribute func(clusterId cluster.ClusterId, attributeId uint16, attribute *cluster.Attribute) interface{}
+	// ManufacturerCode restricts TranslateAttribute and DecodeAttribute to manufacturer specific
+	// frames carrying this code when set.
+	ManufacturerCode uint16
+}
+
+func (q *Quirk) matches(manufacturerName string, modelIdentifier string) bool {
+	for _, m := range q.Matches {
+		if matchField(m.ManufacturerName, manufacturerName) && matchField(m.ModelIdentifier, modelIdentifier) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchField(pattern string, value string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(value, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == "" || pattern == value
+}
+
+type Registry struct {
+	mutex  sync.RWMutex
+	quirks []*Quirk
+}
+
+// New returns a registry holding the quirks shipped with this package.
+func New() *Registry {
+	r := &Registry{}
+	for _, q := range builtin {
+		r.Register(q)
+	}
+	return r
+}
+
+func (r *Registry) Register(quirk *Quirk) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.quirks = append(r.quirks, quirk)
+}
+
+// Lookup returns the quirks applying to the device in registration order.
+func (r *Registry) Lookup(manufacturerName string, modelIdentifier string) []*Quirk {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	quirks := []*Quirk{}
+	for _, q := range r.quirks {
+		if q.matches(manufacturerName, modelIdentifier) {
+			quirks = append(quirks, q)
+		}
+	}
+	return quirks
+}
+
+// Decoder returns a decoder for the frames of a device which applies all its quirks.
+func (r *Registry) Decoder(manufacturerName string, modelIdentifier string) *Decoder {
+	quirks := r.Lookup(manufacturerName, modelIdentifier)
+	library := cluster.New()
+	for _, q := range quirks {
+		if q.PatchLibrary != nil {
+			q.PatchLibrary(library)
+		}
+	}
+	return &Decoder{zcl: zcl.NewWithLibrary(library), quirks: quirks}
+}
+
+type Decoder struct {
+	zcl    *zcl.Zcl
+	quirks []*Quirk
+}
+
+func (d *Decoder) ClusterLibrary() *cluster.ClusterLibrary {
+	return d.zcl.ClusterLibrary()
+}
+
+// ToZclIncomingMessage decodes the message like Zcl.ToZclIncomingMessage after letting the quirks
+// rewrite the raw frame, then translates and decodes the attribute values it carries.
+func (d *Decoder) ToZclIncomingMessage(m *zcl.ApplicationMessage) (*zcl.ZclIncomingMessage, error) {
+	rewritten := *m
+	rewriters := false
+	f := frame.Decode(m.Data)
+	for _, q := range d.quirks {
+		if q.RewriteFrame != nil {
+			q.RewriteFrame(&rewritten, f)
+			rewriters = true
+		}
+	}
+	if rewriters {
+		rewritten.Data = frame.Encode(f)
+	}
+	im, err := d.zcl.ToZclIncomingMessage(&rewritten)
+	if err != nil {
+		return im, err
+	}
+	var manufacturerCode uint16
+	if im.Data.FrameControl.ManufacturerSpecific {
+		manufacturerCode = im.Data.ManufacturerCode
+	}
+	d.translate(im, manufacturerCode)
+	return im, nil
+}
+
+func (d *Decoder) translate(im *zcl.ZclIncomingMessage, manufacturerCode uint16) {
+	switch cmd := im.Data.Command.(type) {
+	case *cluster.ReportAttributesCommand:
+		for _, report := range cmd.AttributeReports {
+			report.Attribute = d.translateAttribute(im, manufacturerCode, report.AttributeID, report.Attribute)
+		}
+	case *cluster.ReadAttributesResponse:
+		for _, status := range cmd.ReadAttributeStatuses {
+			if status.Status == cluster.ZclStatusSuccess {
+				status.Attribute = d.translateAttribute(im, manufacturerCode, status.AttributeID, status.Attribute)
+			}
+		}
+	}
+}
+
+func (d *Decoder) translateAttribute(im *zcl.ZclIncomingMessage, manufacturerCode uint16, attributeId uint16, attribute *cluster.Attribute) *cluster.Attribute {
+	if attribute == nil {
+		return nil
+	}
+	clusterId := cluster.ClusterId(im.ClusterID)
+	for _, q := range d.quirks {
+		if q.ManufacturerCode != 0 && q.ManufacturerCode != manufacturerCode {
+			continue
+		}
+		if q.TranslateAttribute != nil {
+			attribute = q.TranslateAttribute(clusterId, attributeId, attribute)
+		}
+		if q.DecodeAttribute == nil {
+			continue
+		}
+		if value := q.DecodeAttribute(clusterId, attributeId, attribute); value != nil {
+			if im.DecodedAttributes == nil {
+				im.DecodedAttributes = map[uint16]interface{}{}
+			}
+			im.DecodedAttributes[attributeId] = value
+		}
+	}
+	return attribute
+}
+
+// ScaleAttribute returns a TranslateAttribute hook multiplying an integer attribute by
+// numerator/denominator, e.g. for temperatures reported in the wrong unit.
+func ScaleAttribute(clusterId cluster.ClusterId, attributeId uint16, numerator int64, denominator int64) func(cluster.ClusterId, uint16, *cluster.Attribute) *cluster.Attribute {
+	return func(c cluster.ClusterId, a uint16, attribute *cluster.Attribute) *cluster.Attribute {
+		if c != clusterId || a != attributeId {
+			return attribute
+		}
+		switch v := attribute.Value.(type) {
+		case int64:
+			return &cluster.Attribute{DataType: attribute.DataType, Value: v * numerator / denominator}
+		case uint64:
+			return &cluster.Attribute{DataType: attribute.DataType, Value: uint64(int64(v) * numerator / denominator)}
+		}
+		return attribute
+	}
+}
+
+var builtin = []*Quirk{
+	{
+		// the patch only adds the 0xEF00 cluster, which Tuya devices speaking plain ZCL never use
+		Name:         "tuya datapoints",
+		Matches:      []Match{{ManufacturerName: "_TZ*"}, {ManufacturerName: "_TY*"}, {ModelIdentifier: "TS0601"}},
+		PatchLibrary: tuya.PatchLibrary,
+	},
+	{
+		Name:         "xiaomi proprietary attributes",
+		Matches:      []Match{{ManufacturerName: "LUMI"}},
+		PatchLibrary: xiaomi.PatchLibrary,
+		DecodeAttribute: func(clusterId cluster.ClusterId, attributeId uint16, attribute *cluster.Attribute) interface{} {
+			if values := xiaomi.Translate(clusterId, attributeId, attribute); values != nil {
+				return values
+			}
+			return nil
+		},
+		ManufacturerCode: xiaomi.ManufacturerCode,
+	},
+}
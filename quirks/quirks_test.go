@@ -0,0 +1,138 @@
+package quirks
+
+import (
+	"testing"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/tuya"
+	"github.com/dyrkin/zcl-go/xiaomi"
+	. "gopkg.in/check.v1"
+)
+
+func TestQuirks(t *testing.T) { TestingT(t) }
+
+type QuirksSuite struct {
+	registry *Registry
+}
+
+var _ = Suite(&QuirksSuite{})
+
+var temperatureInDegrees = &Quirk{
+	Name:               "temperature in whole degrees",
+	Matches:            []Match{{ManufacturerName: "Acme", ModelIdentifier: "TH-*"}},
+	TranslateAttribute: ScaleAttribute(cluster.TemperatureMeasurement, 0x0000, 100, 1),
+	// reports are sent as cluster specific frames
+	RewriteFrame: func(m *zcl.ApplicationMessage, f *frame.Frame) {
+		if m.ClusterID == uint16(cluster.TemperatureMeasurement) && f.FrameControl.FrameType == frame.FrameTypeLocal {
+			f.FrameControl.FrameType = frame.FrameTypeGlobal
+		}
+	},
+}
+
+var proprietaryAttribute = &Quirk{
+	Name:    "proprietary attribute",
+	Matches: []Match{{ManufacturerName: "Acme"}},
+	PatchLibrary: func(library *cluster.ClusterLibrary) {
+		library.Clusters()[cluster.Basic].AttributeDescriptors[0xfff0] = &cluster.AttributeDescriptor{Name: "AcmeMode", Type: cluster.ZclDataTypeUint8, Access: cluster.Read}
+	},
+}
+
+func (s *QuirksSuite) SetUpTest(c *C) {
+	s.registry = New()
+	s.registry.Register(temperatureInDegrees)
+	s.registry.Register(proprietaryAttribute)
+}
+
+func (s *QuirksSuite) TestLookup(c *C) {
+	c.Assert(s.registry.Lookup("Acme", "TH-2"), DeepEquals, []*Quirk{temperatureInDegrees, proprietaryAttribute})
+	c.Assert(s.registry.Lookup("Acme", "Plug"), DeepEquals, []*Quirk{proprietaryAttribute})
+	c.Assert(s.registry.Lookup("IKEA", "TH-2"), HasLen, 0)
+}
+
+func (s *QuirksSuite) TestRewriteAndTranslate(c *C) {
+	m := &zcl.ApplicationMessage{ClusterID: uint16(cluster.TemperatureMeasurement), Data: []uint8{0x09, 0x10, 0x0a, 0x00, 0x00, 0x29, 0x15, 0x00}}
+	_, err := zcl.New().ToZclIncomingMessage(m)
+	c.Assert(err, NotNil)
+
+	im, err := s.registry.Decoder("Acme", "TH-2").ToZclIncomingMessage(m)
+	c.Assert(err, IsNil)
+	c.Assert(im.Data.CommandName, Equals, "ReportAttributes")
+	report := im.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports[0]
+	c.Assert(report.AttributeName, Equals, "MeasuredValue")
+	c.Assert(report.Attribute, DeepEquals, &cluster.Attribute{DataType: cluster.ZclDataTypeInt16, Value: int64(2100)})
+	c.Assert(m.Data[0], Equals, uint8(0x09))
+}
+
+func (s *QuirksSuite) TestPatchLibrary(c *C) {
+	m := &zcl.ApplicationMessage{ClusterID: uint16(cluster.Basic), Data: []uint8{0x18, 0x10, 0x01, 0xf0, 0xff, 0x00, 0x20, 0x02}}
+	im, err := s.registry.Decoder("Acme", "Plug").ToZclIncomingMessage(m)
+	c.Assert(err, IsNil)
+	c.Assert(im.Data.Command.(*cluster.ReadAttributesResponse).ReadAttributeStatuses[0].AttributeName, Equals, "AcmeMode")
+
+	im, err = s.registry.Decoder("IKEA", "Plug").ToZclIncomingMessage(m)
+	c.Assert(err, IsNil)
+	c.Assert(im.Data.Command.(*cluster.ReadAttributesResponse).ReadAttributeStatuses[0].AttributeName, Equals, "")
+}
+
+func (s *QuirksSuite) TestTuyaDatapoints(c *C) {
+	m := &zcl.ApplicationMessage{ClusterID: uint16(tuya.ManufacturerSpecific), Data: []uint8{0x09, 0x2a, 0x02, 0x00, 0x05, 0x01, 0x01, 0x00, 0x01, 0x01}}
+	_, err := s.registry.Decoder("Acme", "TH-2").ToZclIncomingMessage(m)
+	c.Assert(err, NotNil)
+
+	for _, device := range []Match{
+		{ManufacturerName: "_TZE200_ckud7u2l", ModelIdentifier: "TS0601"},
+		{ManufacturerName: "_TZE608_xkr8gep3", ModelIdentifier: "TS0601"},
+		{ManufacturerName: "_TZ3210_dwytrmda", ModelIdentifier: "TS0601"},
+		{ManufacturerName: "_TYST11_zivfvd7h", ModelIdentifier: "ivfvd7h"},
+		{ManufacturerName: "_TYZB01_iuepbmpv", ModelIdentifier: "TS0011"},
+		{ManufacturerName: "Acme", ModelIdentifier: "TS0601"},
+	} {
+		im, err := s.registry.Decoder(device.ManufacturerName, device.ModelIdentifier).ToZclIncomingMessage(m)
+		c.Assert(err, IsNil, Commentf("%v", device))
+		c.Assert(im.Data.Command, DeepEquals, &tuya.DataReportCommand{
+			Sequence:   5,
+			Datapoints: []*tuya.Datapoint{{Id: 0x01, Type: tuya.DatapointTypeBool, Value: true}},
+		})
+	}
+}
+
+func (s *QuirksSuite) TestXiaomiAttributes(c *C) {
+	tlv := []uint8{0x01, 0x21, 0xd1, 0x0b, 0x03, 0x28, 0x1e}
+	data := append([]uint8{0x1c, 0x5f, 0x11, 0x01, 0x0a, 0x01, 0xff, 0x42, uint8(len(tlv))}, tlv...)
+	im, err := s.registry.Decoder("LUMI", "lumi.weather").ToZclIncomingMessage(&zcl.ApplicationMessage{ClusterID: uint16(cluster.Basic), Data: data})
+	c.Assert(err, IsNil)
+	report := im.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports[0]
+	c.Assert(report.AttributeName, Equals, "XiaomiTlv")
+	c.Assert(report.Attribute.Value, Equals, string(tlv))
+	c.Assert(im.DecodedAttributes, DeepEquals, map[uint16]interface{}{xiaomi.AttributeTlv: []*xiaomi.Value{
+		{Tag: 0x01, Name: "BatteryVoltage", Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeUint16, Value: uint64(3025)}},
+		{Tag: 0x03, Name: "DeviceTemperature", Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeInt8, Value: int64(30)}},
+	}})
+
+	// only frames carrying the Xiaomi manufacturer code are decoded
+	data = append([]uint8{0x18, 0x01, 0x0a, 0x01, 0xff, 0x42, uint8(len(tlv))}, tlv...)
+	im, err = s.registry.Decoder("LUMI", "lumi.weather").ToZclIncomingMessage(&zcl.ApplicationMessage{ClusterID: uint16(cluster.Basic), Data: data})
+	c.Assert(err, IsNil)
+	c.Assert(im.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports[0].Attribute.Value, Equals, string(tlv))
+	c.Assert(im.DecodedAttributes, IsNil)
+}
+
+func (s *QuirksSuite) TestManufacturerCode(c *C) {
+	s.registry.Register(&Quirk{
+		Name:               "manufacturer specific scale",
+		Matches:            []Match{{ManufacturerName: "Acme"}},
+		TranslateAttribute: ScaleAttribute(cluster.Basic, 0xfff0, 10, 1),
+		ManufacturerCode:   0x1234,
+	})
+	decoder := s.registry.Decoder("Acme", "Plug")
+	im, err := decoder.ToZclIncomingMessage(&zcl.ApplicationMessage{ClusterID: uint16(cluster.Basic), Data: []uint8{0x1c, 0x34, 0x12, 0x01, 0x0a, 0xf0, 0xff, 0x20, 0x02}})
+	c.Assert(err, IsNil)
+	c.Assert(im.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports[0].Attribute.Value, Equals, uint64(20))
+
+	// only frames carrying the manufacturer code are translated
+	im, err = decoder.ToZclIncomingMessage(&zcl.ApplicationMessage{ClusterID: uint16(cluster.Basic), Data: []uint8{0x18, 0x01, 0x0a, 0xf0, 0xff, 0x20, 0x02}})
+	c.Assert(err, IsNil)
+	c.Assert(im.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports[0].Attribute.Value, Equals, uint64(2))
+}
@@ -0,0 +1,211 @@
+// Package replay records the application messages exchanged with a network to a compact log and
+// plays them back into handlers, to turn field traffic into regression tests.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+)
+
+type Direction uint8
+
+const (
+	Incoming Direction = 0
+	Outgoing Direction = 1
+)
+
+const (
+	flagWasBroadcast = 0x01
+	flagSecurityUse  = 0x02
+)
+
+// maxFieldLength bounds addresses and payloads, which are far shorter in valid logs.
+const maxFieldLength = 0xffff
+
+var magic = []uint8{'Z', 'C', 'L', 'R', 1}
+
+var ErrInvalidLog = errors.New("not a zcl replay log")
+
+// Entry is a recorded message. Times are kept with microsecond precision.
+type Entry struct {
+	Time      time.Time
+	Direction Direction
+	Message   *zcl.ApplicationMessage
+}
+
+// Writer appends entries to a log. A log starts with a magic header followed by the entries, each
+// storing its time as the number of microseconds since the previous entry, or since the Unix epoch
+// for the first one.
+type Writer struct {
+	w       io.Writer
+	mutex   sync.Mutex
+	started bool
+	last    int64
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write is safe for concurrent use.
+func (w *Writer) Write(e *Entry) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	b := &bytes.Buffer{}
+	if !w.started {
+		b.Write(magic)
+	}
+	micros := e.Time.UnixNano() / int64(time.Microsecond)
+	writeVarint(b, micros-w.last)
+	m := e.Message
+	var flags uint8
+	if m.WasBroadcast {
+		flags |= flagWasBroadcast
+	}
+	if m.SecurityUse {
+		flags |= flagSecurityUse
+	}
+	b.WriteByte(uint8(e.Direction))
+	b.WriteByte(uint8(m.SrcAddrMode))
+	writeBytes(b, []uint8(m.SrcAddr))
+	b.WriteByte(uint8(m.DstAddrMode))
+	writeBytes(b, []uint8(m.DstAddr))
+	binary.Write(b, binary.LittleEndian, m.GroupID)
+	b.WriteByte(m.SrcEndpoint)
+	b.WriteByte(m.DstEndpoint)
+	binary.Write(b, binary.LittleEndian, m.ClusterID)
+	binary.Write(b, binary.LittleEndian, m.ProfileID)
+	b.WriteByte(flags)
+	b.WriteByte(m.LinkQuality)
+	b.WriteByte(uint8(m.Rssi))
+	binary.Write(b, binary.LittleEndian, m.Timestamp)
+	b.WriteByte(m.TransactionSeqNumber)
+	writeBytes(b, m.Data)
+	if _, err := w.w.Write(b.Bytes()); err != nil {
+		return err
+	}
+	w.started = true
+	w.last = micros
+	return nil
+}
+
+func writeVarint(b *bytes.Buffer, v int64) {
+	buf := make([]uint8, binary.MaxVarintLen64)
+	b.Write(buf[:binary.PutVarint(buf, v)])
+}
+
+func writeBytes(b *bytes.Buffer, v []uint8) {
+	buf := make([]uint8, binary.MaxVarintLen64)
+	b.Write(buf[:binary.PutUvarint(buf, uint64(len(v)))])
+	b.Write(v)
+}
+
+// Reader reads the entries of a log written by Writer.
+type Reader struct {
+	r       *bufio.Reader
+	started bool
+	last    int64
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next entry, or io.EOF at the end of the log.
+func (r *Reader) Next() (*Entry, error) {
+	if !r.started {
+		header := make([]uint8, len(magic))
+		if _, err := io.ReadFull(r.r, header); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, ErrInvalidLog
+		}
+		if !bytes.Equal(header, magic) {
+			return nil, ErrInvalidLog
+		}
+		r.started = true
+	}
+	delta, err := binary.ReadVarint(r.r)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	d := &decoder{r: r.r}
+	e := &Entry{Direction: Direction(d.uint8())}
+	m := &zcl.ApplicationMessage{}
+	m.SrcAddrMode = zcl.AddressMode(d.uint8())
+	m.SrcAddr = string(d.bytes())
+	m.DstAddrMode = zcl.AddressMode(d.uint8())
+	m.DstAddr = string(d.bytes())
+	m.GroupID = d.uint16()
+	m.SrcEndpoint = d.uint8()
+	m.DstEndpoint = d.uint8()
+	m.ClusterID = d.uint16()
+	m.ProfileID = d.uint16()
+	flags := d.uint8()
+	m.WasBroadcast = flags&flagWasBroadcast != 0
+	m.SecurityUse = flags&flagSecurityUse != 0
+	m.LinkQuality = d.uint8()
+	m.Rssi = int8(d.uint8())
+	m.Timestamp = d.uint32()
+	m.TransactionSeqNumber = d.uint8()
+	m.Data = d.bytes()
+	if d.err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	r.last += delta
+	e.Time = time.Unix(0, r.last*int64(time.Microsecond))
+	e.Message = m
+	return e, nil
+}
+
+// decoder reads the fields of an entry, remembering the first error.
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *decoder) read(n int) []uint8 {
+	buf := make([]uint8, n)
+	if d.err == nil {
+		_, d.err = io.ReadFull(d.r, buf)
+	}
+	return buf
+}
+
+func (d *decoder) uint8() uint8 {
+	return d.read(1)[0]
+}
+
+func (d *decoder) uint16() uint16 {
+	return binary.LittleEndian.Uint16(d.read(2))
+}
+
+func (d *decoder) uint32() uint32 {
+	return binary.LittleEndian.Uint32(d.read(4))
+}
+
+func (d *decoder) bytes() []uint8 {
+	if d.err != nil {
+		return nil
+	}
+	var n uint64
+	if n, d.err = binary.ReadUvarint(d.r); d.err != nil {
+		return nil
+	}
+	if n > maxFieldLength {
+		d.err = ErrInvalidLog
+		return nil
+	}
+	return d.read(int(n))
+}
@@ -0,0 +1,85 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+)
+
+// Player plays a log back in order on the calling goroutine. Incoming messages are decoded and
+// passed to the incoming handler together with the decoding error, if any; outgoing messages are
+// passed to the outgoing handler as recorded.
+type Player struct {
+	decoder  zcl.Decoder
+	clock    zcl.Clock
+	speed    float64
+	incoming func(im *zcl.ZclIncomingMessage, err error)
+	outgoing func(m *zcl.ApplicationMessage)
+}
+
+// NewPlayer returns a player that replays as fast as possible.
+func NewPlayer(decoder zcl.Decoder) *Player {
+	return &Player{
+		decoder:  decoder,
+		clock:    zcl.SystemClock{},
+		incoming: func(*zcl.ZclIncomingMessage, error) {},
+		outgoing: func(*zcl.ApplicationMessage) {},
+	}
+}
+
+func (p *Player) Clock(clock zcl.Clock) *Player {
+	p.clock = clock
+	return p
+}
+
+// Speed sets the pace of the replay relative to the recording: 1 waits as long between messages
+// as they were apart when recorded, 10 ten times less. 0 doesn't wait at all.
+func (p *Player) Speed(speed float64) *Player {
+	p.speed = speed
+	return p
+}
+
+func (p *Player) OnIncoming(handler func(im *zcl.ZclIncomingMessage, err error)) *Player {
+	p.incoming = handler
+	return p
+}
+
+func (p *Player) OnOutgoing(handler func(m *zcl.ApplicationMessage)) *Player {
+	p.outgoing = handler
+	return p
+}
+
+// Play replays the log until its end or until the context is done.
+func (p *Player) Play(ctx context.Context, r *Reader) error {
+	var last time.Time
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if p.speed > 0 && !last.IsZero() {
+			if wait := time.Duration(float64(e.Time.Sub(last)) / p.speed); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-p.clock.After(wait):
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		last = e.Time
+		switch e.Direction {
+		case Incoming:
+			p.incoming(p.decoder.ToZclIncomingMessage(e.Message))
+		case Outgoing:
+			p.outgoing(e.Message)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package replay
+
+import (
+	"context"
+	"io"
+
+	"github.com/dyrkin/zcl-go"
+)
+
+// Recorder writes the messages received from and sent to a network to a log.
+type Recorder struct {
+	w     *Writer
+	clock zcl.Clock
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{NewWriter(w), zcl.SystemClock{}}
+}
+
+func (r *Recorder) Clock(clock zcl.Clock) *Recorder {
+	r.clock = clock
+	return r
+}
+
+// Incoming records a received message. Record messages before decoding them, e.g. those yielded
+// by an adapter, so that the log keeps the raw frames.
+func (r *Recorder) Incoming(m *zcl.ApplicationMessage) error {
+	return r.w.Write(&Entry{r.clock.Now(), Incoming, m})
+}
+
+func (r *Recorder) Outgoing(m *zcl.ApplicationMessage) error {
+	return r.w.Write(&Entry{r.clock.Now(), Outgoing, m})
+}
+
+// Transport records the messages sent through the transport before sending them.
+func (r *Recorder) Transport(transport zcl.Transport) zcl.Transport {
+	return &recordingTransport{r, transport}
+}
+
+type recordingTransport struct {
+	recorder  *Recorder
+	transport zcl.Transport
+}
+
+func (t *recordingTransport) Send(ctx context.Context, m *zcl.ApplicationMessage) error {
+	if err := t.recorder.Outgoing(m); err != nil {
+		return err
+	}
+	return t.transport.Send(ctx, m)
+}
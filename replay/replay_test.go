@@ -0,0 +1,182 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/internal/clocktest"
+	. "gopkg.in/check.v1"
+)
+
+func TestReplay(t *testing.T) { TestingT(t) }
+
+type sentTransport struct {
+	sent []*zcl.ApplicationMessage
+}
+
+func (t *sentTransport) Send(ctx context.Context, m *zcl.ApplicationMessage) error {
+	t.sent = append(t.sent, m)
+	return nil
+}
+
+type ReplaySuite struct{}
+
+var _ = Suite(&ReplaySuite{})
+
+func report(value uint8) *zcl.ApplicationMessage {
+	data, _ := hex.DecodeString("18070a00001000")
+	data[len(data)-1] = value
+	return &zcl.ApplicationMessage{
+		SrcAddrMode: zcl.AddressModeNwk, SrcAddr: "0x1a2b", DstAddrMode: zcl.AddressModeNwk, DstAddr: "0x0000",
+		SrcEndpoint: 1, DstEndpoint: 1, ClusterID: uint16(cluster.OnOff), ProfileID: 0x0104,
+		LinkQuality: 200, Rssi: -60, SecurityUse: true, Timestamp: 1600000000, TransactionSeqNumber: 7, Data: data,
+	}
+}
+
+func toggle() *zcl.ApplicationMessage {
+	return &zcl.ApplicationMessage{
+		DstAddrMode: zcl.AddressModeGroup, GroupID: 0x0102, WasBroadcast: true,
+		SrcEndpoint: 1, DstEndpoint: 0xff, ClusterID: uint16(cluster.OnOff), ProfileID: 0x0104, Data: []uint8{0x01, 0x08, 0x02},
+	}
+}
+
+func (s *ReplaySuite) TestRoundTrip(c *C) {
+	start := time.Date(2020, 9, 13, 12, 26, 40, 123456789, time.UTC)
+	entries := []*Entry{
+		{start, Incoming, report(1)},
+		{start.Add(1500 * time.Microsecond), Outgoing, toggle()},
+		{start.Add(-time.Second), Incoming, report(0)},
+	}
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	for _, e := range entries {
+		c.Assert(w.Write(e), IsNil)
+	}
+	r := NewReader(b)
+	for _, e := range entries {
+		actual, err := r.Next()
+		c.Assert(err, IsNil)
+		c.Assert(actual.Time.Equal(e.Time.Truncate(time.Microsecond)), Equals, true)
+		c.Assert(actual.Direction, Equals, e.Direction)
+		if e.Message.Data == nil {
+			e.Message.Data = []uint8{}
+		}
+		c.Assert(actual.Message, DeepEquals, e.Message)
+	}
+	_, err := r.Next()
+	c.Assert(err, Equals, io.EOF)
+}
+
+func (s *ReplaySuite) TestEmptyLog(c *C) {
+	_, err := NewReader(&bytes.Buffer{}).Next()
+	c.Assert(err, Equals, io.EOF)
+}
+
+func (s *ReplaySuite) TestInvalidLog(c *C) {
+	_, err := NewReader(bytes.NewBufferString("PCAP\x01\x00")).Next()
+	c.Assert(err, Equals, ErrInvalidLog)
+}
+
+func (s *ReplaySuite) TestTruncatedLog(c *C) {
+	b := &bytes.Buffer{}
+	c.Assert(NewWriter(b).Write(&Entry{time.Unix(1, 0), Incoming, report(1)}), IsNil)
+	r := NewReader(bytes.NewReader(b.Bytes()[:b.Len()-2]))
+	_, err := r.Next()
+	c.Assert(err, Equals, io.ErrUnexpectedEOF)
+}
+
+func (s *ReplaySuite) TestRecorder(c *C) {
+	clock := clocktest.New(time.Unix(100, 0))
+	b := &bytes.Buffer{}
+	recorder := NewRecorder(b).Clock(clock)
+	transport := &sentTransport{}
+	c.Assert(recorder.Incoming(report(1)), IsNil)
+	clock.Advance(2 * time.Second)
+	c.Assert(recorder.Transport(transport).Send(context.Background(), toggle()), IsNil)
+	c.Assert(transport.sent, DeepEquals, []*zcl.ApplicationMessage{toggle()})
+
+	r := NewReader(b)
+	e, err := r.Next()
+	c.Assert(err, IsNil)
+	c.Assert(e.Time, Equals, time.Unix(100, 0))
+	c.Assert(e.Direction, Equals, Incoming)
+	e, err = r.Next()
+	c.Assert(err, IsNil)
+	c.Assert(e.Time, Equals, time.Unix(102, 0))
+	c.Assert(e.Direction, Equals, Outgoing)
+	c.Assert(e.Message.GroupID, Equals, uint16(0x0102))
+}
+
+func (s *ReplaySuite) log(c *C, entries ...*Entry) *Reader {
+	b := &bytes.Buffer{}
+	w := NewWriter(b)
+	for _, e := range entries {
+		c.Assert(w.Write(e), IsNil)
+	}
+	return NewReader(b)
+}
+
+func (s *ReplaySuite) TestPlay(c *C) {
+	r := s.log(c,
+		&Entry{time.Unix(10, 0), Incoming, report(1)},
+		&Entry{time.Unix(11, 0), Outgoing, toggle()},
+		&Entry{time.Unix(12, 0), Incoming, report(0)},
+		&Entry{time.Unix(13, 0), Incoming, &zcl.ApplicationMessage{ClusterID: uint16(cluster.OnOff), Data: []uint8{0x19, 0x07, 0xf0}}},
+	)
+	var events []string
+	clock := clocktest.New(time.Unix(0, 0))
+	err := NewPlayer(zcl.New()).
+		Clock(clock).
+		OnIncoming(func(im *zcl.ZclIncomingMessage, err error) {
+			if err != nil {
+				events = append(events, "error")
+				return
+			}
+			record := im.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports[0]
+			events = append(events, fmt.Sprintf("%s %v", im.SrcAddr, record.Attribute.Value))
+		}).
+		OnOutgoing(func(m *zcl.ApplicationMessage) {
+			events = append(events, "sent "+hex.EncodeToString(m.Data))
+		}).
+		Play(context.Background(), r)
+	c.Assert(err, IsNil)
+	c.Assert(events, DeepEquals, []string{"0x1a2b true", "sent 010802", "0x1a2b false", "error"})
+	c.Assert(clock.Waited(), HasLen, 0)
+}
+
+func (s *ReplaySuite) TestPlayAccelerated(c *C) {
+	r := s.log(c,
+		&Entry{time.Unix(10, 0), Incoming, report(1)},
+		&Entry{time.Unix(14, 0), Incoming, report(0)},
+		&Entry{time.Unix(14, 0), Incoming, report(1)},
+		&Entry{time.Unix(15, 0), Outgoing, toggle()},
+	)
+	clock := clocktest.New(time.Unix(0, 0))
+	c.Assert(NewPlayer(zcl.New()).Clock(clock).Speed(4).Play(context.Background(), r), IsNil)
+	c.Assert(clock.Waited(), DeepEquals, []time.Duration{time.Second, 250 * time.Millisecond})
+}
+
+func (s *ReplaySuite) TestPlayCancelled(c *C) {
+	r := s.log(c,
+		&Entry{time.Unix(10, 0), Incoming, report(1)},
+		&Entry{time.Unix(3610, 0), Incoming, report(0)},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	played := 0
+	err := NewPlayer(zcl.New()).
+		Speed(1).
+		OnIncoming(func(*zcl.ZclIncomingMessage, error) {
+			played++
+			cancel()
+		}).
+		Play(ctx, r)
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(played, Equals, 1)
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+type clusterAttributes struct {
+	cluster *cluster.Cluster
+	values  map[uint16]*cluster.Attribute
+}
+
+// AttributeStore holds the attribute values of the server clusters exposed on local endpoints
+// and answers the global attribute commands addressed to them.
+type AttributeStore struct {
+	library   *cluster.ClusterLibrary
+	mutex     sync.RWMutex
+	endpoints map[uint8]map[cluster.ClusterId]*clusterAttributes
+}
+
+func NewAttributeStore(library *cluster.ClusterLibrary) *AttributeStore {
+	return &AttributeStore{library: library, endpoints: map[uint8]map[cluster.ClusterId]*clusterAttributes{}}
+}
+
+func (s *AttributeStore) AddCluster(endpoint uint8, clusterId cluster.ClusterId) error {
+	c, ok := s.library.Clusters()[clusterId]
+	if !ok {
+		return fmt.Errorf("unknown cluster %d", clusterId)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	clusters, ok := s.endpoints[endpoint]
+	if !ok {
+		clusters = map[cluster.ClusterId]*clusterAttributes{}
+		s.endpoints[endpoint] = clusters
+	}
+	if _, ok := clusters[clusterId]; !ok {
+		clusters[clusterId] = &clusterAttributes{c, map[uint16]*cluster.Attribute{}}
+	}
+	return nil
+}
+
+func (s *AttributeStore) HasCluster(endpoint uint8, clusterId cluster.ClusterId) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	_, ok := s.endpoints[endpoint][clusterId]
+	return ok
+}
+
+// Set stores the value of an attribute, making it supported. Access restrictions don't apply.
+func (s *AttributeStore) Set(endpoint uint8, clusterId cluster.ClusterId, attributeId uint16, value interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ca, ok := s.endpoints[endpoint][clusterId]
+	if !ok {
+		return fmt.Errorf("cluster %d isn't exposed on endpoint %d", clusterId, endpoint)
+	}
+	descriptor, ok := ca.cluster.AttributeDescriptors[attributeId]
+	if !ok {
+		return fmt.Errorf("cluster %d has no attribute %d", clusterId, attributeId)
+	}
+	if !cluster.ValidAttributeValue(descriptor.Type, value) {
+		return fmt.Errorf("invalid value %v for attribute %s", value, descriptor.Name)
+	}
+	ca.values[attributeId] = &cluster.Attribute{DataType: descriptor.Type, Value: value}
+	return nil
+}
+
+func (s *AttributeStore) Get(endpoint uint8, clusterId cluster.ClusterId, attributeId uint16) (*cluster.Attribute, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if ca, ok := s.endpoints[endpoint][clusterId]; ok {
+		attribute, ok := ca.values[attributeId]
+		return attribute, ok
+	}
+	return nil, false
+}
+
+// Respond answers a global command addressed to a local endpoint. It returns the specific
+// response, a DefaultResponse or nil when nothing must be sent back.
+func (s *AttributeStore) Respond(im *zcl.ZclIncomingMessage) *zcl.ZclOutgoingMessage {
+	f := im.Data
+	if f == nil || f.FrameControl == nil || f.FrameControl.FrameType != frame.FrameTypeGlobal {
+		return nil
+	}
+	if f.Command == nil {
+		return zcl.DefaultResponse(im, zcl.UnsupportedCommandStatus(f.FrameControl), false)
+	}
+	// only DefaultResponses are suppressed for broadcast and group commands, specific responses are
+	// sent to their source as for unicasts
+	responseId, response, status := s.Handle(im.DstEndpoint, cluster.ClusterId(im.ClusterID), f.Command)
+	if response == nil {
+		return zcl.DefaultResponse(im, status, false)
+	}
+	return reply(im, responseId, response)
+}
+
+func reply(im *zcl.ZclIncomingMessage, commandId uint8, command interface{}) *zcl.ZclOutgoingMessage {
+	f := im.Data
+	builder := frame.New().
+		IdGenerator(func() uint8 { return f.TransactionSequenceNumber }).
+		FrameType(frame.FrameTypeGlobal).
+		Direction(frame.DirectionServerClient).
+		DisableDefaultResponse(true).
+		CommandId(commandId).
+		Command(command)
+	if f.FrameControl.ManufacturerSpecific {
+		builder.ManufacturerCode(f.ManufacturerCode)
+	}
+	rf := frame.MustBuild(builder)
+	return &zcl.ZclOutgoingMessage{
+		DstAddrMode: zcl.AddressModeNwk,
+		DstAddr:     im.SrcAddr,
+		DstEndpoint: im.SrcEndpoint,
+		SrcEndpoint: im.DstEndpoint,
+		ClusterID:   im.ClusterID,
+		ProfileID:   im.ProfileID,
+		Frame:       rf,
+	}
+}
+
+// Handle executes a decoded global command and returns the id and body of the response command.
+// A nil response means the status has to be reported with a DefaultResponse, e.g.
+// UnsupClusterCommand for clusters which aren't exposed on the endpoint.
+func (s *AttributeStore) Handle(endpoint uint8, clusterId cluster.ClusterId, command interface{}) (uint8, interface{}, cluster.ZclStatus) {
+	// clusters are never removed, so the cluster is still there when the command is executed
+	if !s.HasCluster(endpoint, clusterId) {
+		return 0, nil, cluster.ZclStatusUnsupClusterCommand
+	}
+	switch cmd := command.(type) {
+	case *cluster.ReadAttributesCommand:
+		return uint8(cluster.ZclCommandReadAttributesResponse), s.readAttributes(endpoint, clusterId, cmd), cluster.ZclStatusSuccess
+	case *cluster.WriteAttributesCommand:
+		return uint8(cluster.ZclCommandWriteAttributesResponse), s.writeAttributes(endpoint, clusterId, cmd.WriteAttributeRecords, false), cluster.ZclStatusSuccess
+	case *cluster.WriteAttributesUndividedCommand:
+		return uint8(cluster.ZclCommandWriteAttributesResponse), s.writeAttributes(endpoint, clusterId, cmd.WriteAttributeRecords, true), cluster.ZclStatusSuccess
+	case *cluster.WriteAttributesNoResponseCommand:
+		s.writeAttributes(endpoint, clusterId, cmd.WriteAttributeRecords, false)
+		return 0, nil, cluster.ZclStatusSuccess
+	case *cluster.DiscoverAttributesCommand:
+		return uint8(cluster.ZclCommandDiscoverAttributesResponse), s.discoverAttributes(endpoint, clusterId, cmd), cluster.ZclStatusSuccess
+	case *cluster.DiscoverAttributesExtendedCommand:
+		return uint8(cluster.ZclCommandDiscoverAttributesExtendedResponse), s.discoverAttributesExtended(endpoint, clusterId, cmd), cluster.ZclStatusSuccess
+	case *cluster.DiscoverCommandsReceivedCommand:
+		complete, ids := s.discoverCommands(clusterId, true, cmd.StartCommandID, cmd.MaximumCommandIdentifiers)
+		return uint8(cluster.ZclCommandDiscoverCommandsReceivedResponse), &cluster.DiscoverCommandsReceivedResponse{DiscoveryComplete: complete, CommandIdentifiers: ids}, cluster.ZclStatusSuccess
+	case *cluster.DiscoverCommandsGeneratedCommand:
+		complete, ids := s.discoverCommands(clusterId, false, cmd.StartCommandID, cmd.MaximumCommandIdentifiers)
+		return uint8(cluster.ZclCommandDiscoverCommandsGeneratedResponse), &cluster.DiscoverCommandsGeneratedResponse{DiscoveryComplete: complete, CommandIdentifiers: ids}, cluster.ZclStatusSuccess
+	}
+	return 0, nil, cluster.ZclStatusUnsupGeneralCommand
+}
+
+func (s *AttributeStore) readAttributes(endpoint uint8, clusterId cluster.ClusterId, cmd *cluster.ReadAttributesCommand) *cluster.ReadAttributesResponse {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	ca := s.endpoints[endpoint][clusterId]
+	response := &cluster.ReadAttributesResponse{ReadAttributeStatuses: []*cluster.ReadAttributeStatus{}}
+	for _, id := range cmd.AttributeIDs {
+		status := &cluster.ReadAttributeStatus{AttributeID: id}
+		descriptor, value, supported := ca.lookup(id)
+		switch {
+		case !supported:
+			status.Status = cluster.ZclStatusUnsupportedAttribute
+		case descriptor.Access&cluster.Read == 0:
+			status.Status = cluster.ZclStatusWriteOnly
+		default:
+			status.AttributeName = descriptor.Name
+			status.Status = cluster.ZclStatusSuccess
+			status.Attribute = value
+		}
+		response.ReadAttributeStatuses = append(response.ReadAttributeStatuses, status)
+	}
+	return response
+}
+
+func (s *AttributeStore) writeAttributes(endpoint uint8, clusterId cluster.ClusterId, records []*cluster.WriteAttributeRecord, undivided bool) *cluster.WriteAttributesResponse {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ca := s.endpoints[endpoint][clusterId]
+	failures := []*cluster.WriteAttributeStatus{}
+	accepted := []*cluster.WriteAttributeRecord{}
+	for _, record := range records {
+		if status := ca.checkWrite(record); status != cluster.ZclStatusSuccess {
+			failures = append(failures, &cluster.WriteAttributeStatus{Status: status, AttributeID: record.AttributeID})
+		} else {
+			accepted = append(accepted, record)
+		}
+	}
+	if !undivided || len(failures) == 0 {
+		for _, record := range accepted {
+			ca.values[record.AttributeID] = &cluster.Attribute{DataType: record.Attribute.DataType, Value: record.Attribute.Value}
+		}
+	}
+	if len(failures) == 0 {
+		return &cluster.WriteAttributesResponse{WriteAttributeStatuses: []*cluster.WriteAttributeStatus{{Status: cluster.ZclStatusSuccess}}}
+	}
+	return &cluster.WriteAttributesResponse{WriteAttributeStatuses: failures}
+}
+
+func (s *AttributeStore) discoverAttributes(endpoint uint8, clusterId cluster.ClusterId, cmd *cluster.DiscoverAttributesCommand) *cluster.DiscoverAttributesResponse {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	ca := s.endpoints[endpoint][clusterId]
+	ids, complete := ca.supportedFrom(cmd.StartAttributeID, cmd.MaximumAttributeIdentifiers)
+	response := &cluster.DiscoverAttributesResponse{DiscoveryComplete: complete, AttributeInformations: []*cluster.AttributeInformation{}}
+	for _, id := range ids {
+		descriptor := ca.cluster.AttributeDescriptors[id]
+		response.AttributeInformations = append(response.AttributeInformations,
+			&cluster.AttributeInformation{AttributeName: descriptor.Name, AttributeID: id, AttributeDataType: descriptor.Type})
+	}
+	return response
+}
+
+func (s *AttributeStore) discoverAttributesExtended(endpoint uint8, clusterId cluster.ClusterId, cmd *cluster.DiscoverAttributesExtendedCommand) *cluster.DiscoverAttributesExtendedResponse {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	ca := s.endpoints[endpoint][clusterId]
+	ids, complete := ca.supportedFrom(cmd.StartAttributeID, cmd.MaximumAttributeIdentifiers)
+	response := &cluster.DiscoverAttributesExtendedResponse{DiscoveryComplete: complete, ExtendedAttributeInformations: []*cluster.ExtendedAttributeInformation{}}
+	for _, id := range ids {
+		descriptor := ca.cluster.AttributeDescriptors[id]
+		response.ExtendedAttributeInformations = append(response.ExtendedAttributeInformations, &cluster.ExtendedAttributeInformation{
+			AttributeName:     descriptor.Name,
+			AttributeID:       id,
+			AttributeDataType: descriptor.Type,
+			AttributeAccessControl: &cluster.AttributeAccessControl{
+				Readable:   accessFlag(descriptor.Access, cluster.Read),
+				Writeable:  accessFlag(descriptor.Access, cluster.Write),
+				Reportable: accessFlag(descriptor.Access, cluster.Reportable),
+			},
+		})
+	}
+	return response
+}
+
+func (s *AttributeStore) discoverCommands(clusterId cluster.ClusterId, received bool, start uint8, max uint8) (uint8, []uint8) {
+	ids := []uint8{}
+	c, ok := s.library.Clusters()[clusterId]
+	if !ok || c.CommandDescriptors == nil {
+		return 1, ids
+	}
+	descriptors := c.CommandDescriptors.Generated
+	if received {
+		descriptors = c.CommandDescriptors.Received
+	}
+	all := []int{}
+	for id := range descriptors {
+		if id >= start {
+			all = append(all, int(id))
+		}
+	}
+	sort.Ints(all)
+	complete := uint8(1)
+	if len(all) > int(max) {
+		all = all[:max]
+		complete = 0
+	}
+	for _, id := range all {
+		ids = append(ids, uint8(id))
+	}
+	return complete, ids
+}
+
+func (ca *clusterAttributes) lookup(id uint16) (*cluster.AttributeDescriptor, *cluster.Attribute, bool) {
+	descriptor, ok := ca.cluster.AttributeDescriptors[id]
+	if !ok {
+		return nil, nil, false
+	}
+	value, ok := ca.values[id]
+	return descriptor, value, ok
+}
+
+func (ca *clusterAttributes) checkWrite(record *cluster.WriteAttributeRecord) cluster.ZclStatus {
+	descriptor, _, supported := ca.lookup(record.AttributeID)
+	switch {
+	case !supported:
+		return cluster.ZclStatusUnsupportedAttribute
+	case descriptor.Access&cluster.Write == 0:
+		return cluster.ZclStatusReadOnly
+	case record.Attribute == nil || record.Attribute.DataType != descriptor.Type:
+		return cluster.ZclStatusInvalidDataType
+	case !cluster.ValidAttributeValue(record.Attribute.DataType, record.Attribute.Value):
+		return cluster.ZclStatusInvalidValue
+	}
+	return cluster.ZclStatusSuccess
+}
+
+func (ca *clusterAttributes) supportedFrom(start uint16, max uint8) ([]uint16, uint8) {
+	all := []int{}
+	for id := range ca.values {
+		if id >= start {
+			all = append(all, int(id))
+		}
+	}
+	sort.Ints(all)
+	complete := uint8(1)
+	if len(all) > int(max) {
+		all = all[:max]
+		complete = 0
+	}
+	ids := make([]uint16, len(all))
+	for i, id := range all {
+		ids[i] = uint16(id)
+	}
+	return ids, complete
+}
+
+func accessFlag(access cluster.Access, flag cluster.Access) uint8 {
+	if access&flag != 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	. "gopkg.in/check.v1"
+)
+
+func TestServer(t *testing.T) { TestingT(t) }
+
+type AttributeStoreSuite struct {
+	zcl   *zcl.Zcl
+	store *AttributeStore
+}
+
+var _ = Suite(&AttributeStoreSuite{})
+
+func (s *AttributeStoreSuite) SetUpTest(c *C) {
+	s.zcl = zcl.New()
+	s.store = NewAttributeStore(s.zcl.ClusterLibrary())
+	c.Assert(s.store.AddCluster(1, cluster.Basic), IsNil)
+	c.Assert(s.store.Set(1, cluster.Basic, 0x0000, uint64(2)), IsNil)
+	c.Assert(s.store.Set(1, cluster.Basic, 0x0010, "hall"), IsNil)
+}
+
+func (s *AttributeStoreSuite) respond(c *C, clusterId cluster.ClusterId, data []uint8) []uint8 {
+	im, err := s.zcl.ToZclIncomingMessage(&zcl.ApplicationMessage{
+		SrcAddr: "0x1234", SrcEndpoint: 1, DstEndpoint: 1, ClusterID: uint16(clusterId), ProfileID: 0x0104, Data: data,
+	})
+	c.Assert(err, IsNil)
+	m := s.store.Respond(im)
+	if m == nil {
+		return nil
+	}
+	c.Assert(m.DstAddr, Equals, "0x1234")
+	return frame.Encode(m.Frame)
+}
+
+func (s *AttributeStoreSuite) TestSet(c *C) {
+	c.Assert(s.store.Set(1, cluster.Basic, 0x0000, "2"), NotNil)
+	c.Assert(s.store.Set(1, cluster.Basic, 0xfff0, uint64(2)), NotNil)
+	c.Assert(s.store.Set(2, cluster.Basic, 0x0000, uint64(2)), NotNil)
+	attribute, ok := s.store.Get(1, cluster.Basic, 0x0010)
+	c.Assert(ok, Equals, true)
+	c.Assert(attribute, DeepEquals, &cluster.Attribute{DataType: cluster.ZclDataTypeCharStr, Value: "hall"})
+}
+
+func (s *AttributeStoreSuite) TestReadAttributes(c *C) {
+	response := s.respond(c, cluster.Basic, []uint8{0x00, 0x01, 0x00, 0x00, 0x00, 0x04, 0x00})
+	c.Assert(response, DeepEquals, []uint8{0x18, 0x01, 0x01,
+		0x00, 0x00, 0x00, 0x20, 0x02,
+		0x04, 0x00, 0x86})
+}
+
+func (s *AttributeStoreSuite) TestWriteAttributes(c *C) {
+	response := s.respond(c, cluster.Basic, []uint8{0x00, 0x02, 0x02, 0x10, 0x00, 0x42, 0x03, 'b', 'e', 'd'})
+	c.Assert(response, DeepEquals, []uint8{0x18, 0x02, 0x04, 0x00})
+	attribute, _ := s.store.Get(1, cluster.Basic, 0x0010)
+	c.Assert(attribute.Value, Equals, "bed")
+
+	response = s.respond(c, cluster.Basic, []uint8{0x00, 0x03, 0x02, 0x00, 0x00, 0x20, 0x03, 0x10, 0x00, 0x20, 0x01})
+	c.Assert(response, DeepEquals, []uint8{0x18, 0x03, 0x04, 0x88, 0x00, 0x00, 0x8d, 0x10, 0x00})
+}
+
+func (s *AttributeStoreSuite) TestWriteAttributesUndivided(c *C) {
+	response := s.respond(c, cluster.Basic, []uint8{0x00, 0x04, 0x03, 0x10, 0x00, 0x42, 0x03, 'b', 'e', 'd', 0x00, 0x00, 0x20, 0x03})
+	c.Assert(response, DeepEquals, []uint8{0x18, 0x04, 0x04, 0x88, 0x00, 0x00})
+	attribute, _ := s.store.Get(1, cluster.Basic, 0x0010)
+	c.Assert(attribute.Value, Equals, "hall")
+}
+
+func (s *AttributeStoreSuite) TestWriteAttributesNoResponse(c *C) {
+	c.Assert(s.respond(c, cluster.Basic, []uint8{0x10, 0x05, 0x05, 0x10, 0x00, 0x42, 0x03, 'b', 'e', 'd'}), IsNil)
+	attribute, _ := s.store.Get(1, cluster.Basic, 0x0010)
+	c.Assert(attribute.Value, Equals, "bed")
+}
+
+func (s *AttributeStoreSuite) TestDiscoverAttributes(c *C) {
+	response := s.respond(c, cluster.Basic, []uint8{0x00, 0x06, 0x0c, 0x00, 0x00, 0x01})
+	c.Assert(response, DeepEquals, []uint8{0x18, 0x06, 0x0d, 0x00, 0x00, 0x00, 0x20})
+	response = s.respond(c, cluster.Basic, []uint8{0x00, 0x07, 0x0c, 0x01, 0x00, 0x05})
+	c.Assert(response, DeepEquals, []uint8{0x18, 0x07, 0x0d, 0x01, 0x10, 0x00, 0x42})
+}
+
+func (s *AttributeStoreSuite) TestDiscoverAttributesExtended(c *C) {
+	response := s.respond(c, cluster.Basic, []uint8{0x00, 0x08, 0x15, 0x00, 0x00, 0x05})
+	c.Assert(response, DeepEquals, []uint8{0x18, 0x08, 0x16, 0x01, 0x00, 0x00, 0x20, 0x01, 0x10, 0x00, 0x42, 0x03})
+}
+
+func (s *AttributeStoreSuite) TestDiscoverCommands(c *C) {
+	c.Assert(s.store.AddCluster(1, cluster.Groups), IsNil)
+	response := s.respond(c, cluster.Groups, []uint8{0x00, 0x09, 0x11, 0x02, 0x02})
+	c.Assert(response, DeepEquals, []uint8{0x18, 0x09, 0x12, 0x00, 0x02, 0x03})
+}
+
+func (s *AttributeStoreSuite) TestUnsupported(c *C) {
+	response := s.respond(c, cluster.OnOff, []uint8{0x00, 0x0a, 0x00, 0x00, 0x00})
+	c.Assert(response, DeepEquals, []uint8{0x18, 0x0a, 0x0b, 0x00, 0x81})
+	response = s.respond(c, cluster.Basic, []uint8{0x00, 0x0b, 0x06, 0x00, 0x00, 0x00, 0x20, 0x01, 0x00, 0x0a, 0x00})
+	c.Assert(response, DeepEquals, []uint8{0x18, 0x0b, 0x0b, 0x06, 0x82})
+}
+
+func (s *AttributeStoreSuite) TestHandleUnknownCluster(c *C) {
+	store := NewAttributeStore(s.zcl.ClusterLibrary())
+	_, response, status := store.Handle(1, cluster.Basic, &cluster.ReadAttributesCommand{AttributeIDs: []uint16{0x0000}})
+	c.Assert(response, IsNil)
+	c.Assert(status, Equals, cluster.ZclStatusUnsupClusterCommand)
+	_, response, status = s.store.Handle(1, 0xfc00, &cluster.DiscoverCommandsReceivedCommand{MaximumCommandIdentifiers: 10})
+	c.Assert(response, IsNil)
+	c.Assert(status, Equals, cluster.ZclStatusUnsupClusterCommand)
+}
+
+func (s *AttributeStoreSuite) TestGroupRead(c *C) {
+	im, err := s.zcl.ToZclIncomingMessage(&zcl.ApplicationMessage{
+		GroupID: 5, SrcAddr: "0x1234", SrcEndpoint: 1, DstEndpoint: 1, ClusterID: uint16(cluster.Basic), ProfileID: 0x0104,
+		Data: []uint8{0x00, 0x0c, 0x00, 0x00, 0x00},
+	})
+	c.Assert(err, IsNil)
+	m := s.store.Respond(im)
+	c.Assert(m, NotNil)
+	c.Assert(m.DstAddr, Equals, "0x1234")
+	c.Assert(frame.Encode(m.Frame), DeepEquals, []uint8{0x18, 0x0c, 0x01, 0x00, 0x00, 0x00, 0x20, 0x02})
+
+	// failures are reported with DefaultResponses, which aren't sent for group commands
+	im.ClusterID = uint16(cluster.OnOff)
+	c.Assert(s.store.Respond(im), IsNil)
+}
@@ -0,0 +1,324 @@
+package server
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+// NoReporting as the maximum reporting interval stops reporting of an attribute.
+const NoReporting = 0xffff
+
+type reportingKey struct {
+	endpoint    uint8
+	clusterId   cluster.ClusterId
+	attributeId uint16
+	direction   cluster.ReportDirection
+}
+
+type reportingConfiguration struct {
+	record       cluster.AttributeReportingConfigurationRecord
+	dstAddr      string
+	dstEndpoint  uint8
+	profileId    uint16
+	lastValue    interface{}
+	lastReported time.Time
+}
+
+type destination struct {
+	endpoint    uint8
+	clusterId   cluster.ClusterId
+	dstAddr     string
+	dstEndpoint uint8
+	profileId   uint16
+}
+
+// Reporter sends ReportAttributes commands for the attributes of an AttributeStore according to
+// the reporting configurations received from clients.
+type Reporter struct {
+	store                 *AttributeStore
+	clock                 zcl.Clock
+	transactionIdProvider func() uint8
+	sendErrorHandler      func(message *zcl.ZclOutgoingMessage, err error)
+	mutex                 sync.Mutex
+	configurations        map[reportingKey]*reportingConfiguration
+}
+
+func NewReporter(store *AttributeStore) *Reporter {
+	return &Reporter{
+		store:                 store,
+		clock:                 zcl.SystemClock{},
+		transactionIdProvider: frame.MakeDefaultTransactionIdProvider(),
+		configurations:        map[reportingKey]*reportingConfiguration{},
+	}
+}
+
+func (r *Reporter) Clock(clock zcl.Clock) *Reporter {
+	r.clock = clock
+	return r
+}
+
+func (r *Reporter) IdGenerator(transactionIdProvider func() uint8) *Reporter {
+	r.transactionIdProvider = transactionIdProvider
+	return r
+}
+
+// OnSendError sets the function Run passes the reports it failed to send to.
+func (r *Reporter) OnSendError(handler func(message *zcl.ZclOutgoingMessage, err error)) *Reporter {
+	r.sendErrorHandler = handler
+	return r
+}
+
+// Respond answers ConfigureReporting and ReadReportingConfiguration and passes every other
+// command to the attribute store.
+func (r *Reporter) Respond(im *zcl.ZclIncomingMessage) *zcl.ZclOutgoingMessage {
+	f := im.Data
+	if f == nil || f.FrameControl == nil || f.FrameControl.FrameType != frame.FrameTypeGlobal ||
+		!r.store.HasCluster(im.DstEndpoint, cluster.ClusterId(im.ClusterID)) {
+		return r.store.Respond(im)
+	}
+	switch cmd := f.Command.(type) {
+	case *cluster.ConfigureReportingCommand:
+		response := r.configure(im, cmd)
+		return reply(im, uint8(cluster.ZclCommandConfigureReportingResponse), response)
+	case *cluster.ReadReportingConfigurationCommand:
+		response := r.readConfiguration(im, cmd)
+		return reply(im, uint8(cluster.ZclCommandReadReportingConfigurationResponse), response)
+	}
+	return r.store.Respond(im)
+}
+
+func (r *Reporter) configure(im *zcl.ZclIncomingMessage, cmd *cluster.ConfigureReportingCommand) *cluster.ConfigureReportingResponse {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	clusterId := cluster.ClusterId(im.ClusterID)
+	failures := []*cluster.AttributeStatusRecord{}
+	for _, record := range cmd.AttributeReportingConfigurationRecords {
+		key := reportingKey{im.DstEndpoint, clusterId, record.AttributeID, record.Direction}
+		status := r.checkConfiguration(key, record)
+		if status != cluster.ZclStatusSuccess {
+			failures = append(failures, &cluster.AttributeStatusRecord{Status: status, Direction: record.Direction, AttributeID: record.AttributeID})
+			continue
+		}
+		if record.Direction == cluster.ReportDirectionAttributeReported && record.MaximumReportingInterval == NoReporting {
+			delete(r.configurations, key)
+			continue
+		}
+		configuration := &reportingConfiguration{
+			record:       *record,
+			dstAddr:      im.SrcAddr,
+			dstEndpoint:  im.SrcEndpoint,
+			profileId:    im.ProfileID,
+			lastReported: r.clock.Now(),
+		}
+		if attribute, ok := r.store.Get(key.endpoint, key.clusterId, key.attributeId); ok {
+			configuration.lastValue = attribute.Value
+		}
+		r.configurations[key] = configuration
+	}
+	if len(failures) == 0 {
+		return &cluster.ConfigureReportingResponse{AttributeStatusRecords: []*cluster.AttributeStatusRecord{{Status: cluster.ZclStatusSuccess}}}
+	}
+	return &cluster.ConfigureReportingResponse{AttributeStatusRecords: failures}
+}
+
+func (r *Reporter) checkConfiguration(key reportingKey, record *cluster.AttributeReportingConfigurationRecord) cluster.ZclStatus {
+	descriptor, ok := r.store.library.Clusters()[key.clusterId].AttributeDescriptors[key.attributeId]
+	if _, set := r.store.Get(key.endpoint, key.clusterId, key.attributeId); !ok || !set {
+		return cluster.ZclStatusUnsupportedAttribute
+	}
+	if record.Direction != cluster.ReportDirectionAttributeReported {
+		return cluster.ZclStatusSuccess
+	}
+	switch {
+	case descriptor.Access&cluster.Reportable == 0:
+		return cluster.ZclStatusUnreportableAttribute
+	case record.AttributeDataType != descriptor.Type:
+		return cluster.ZclStatusInvalidDataType
+	case record.MaximumReportingInterval != 0 && record.MaximumReportingInterval != NoReporting &&
+		record.MinimumReportingInterval > record.MaximumReportingInterval:
+		return cluster.ZclStatusInvalidValue
+	}
+	return cluster.ZclStatusSuccess
+}
+
+func (r *Reporter) readConfiguration(im *zcl.ZclIncomingMessage, cmd *cluster.ReadReportingConfigurationCommand) *cluster.ReadReportingConfigurationResponse {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	clusterId := cluster.ClusterId(im.ClusterID)
+	response := &cluster.ReadReportingConfigurationResponse{AttributeReportingConfigurationResponseRecords: []*cluster.AttributeReportingConfigurationResponseRecord{}}
+	for _, record := range cmd.AttributeRecords {
+		key := reportingKey{im.DstEndpoint, clusterId, record.AttributeID, record.Direction}
+		result := &cluster.AttributeReportingConfigurationResponseRecord{Direction: record.Direction, AttributeID: record.AttributeID}
+		descriptor, ok := r.store.library.Clusters()[clusterId].AttributeDescriptors[record.AttributeID]
+		_, set := r.store.Get(key.endpoint, key.clusterId, key.attributeId)
+		configuration, configured := r.configurations[key]
+		switch {
+		case !ok || !set:
+			result.Status = cluster.ZclStatusUnsupportedAttribute
+		case descriptor.Access&cluster.Reportable == 0:
+			result.Status = cluster.ZclStatusUnreportableAttribute
+		case !configured:
+			result.Status = cluster.ZclStatusNotFound
+		default:
+			result.Status = cluster.ZclStatusSuccess
+			result.AttributeName = descriptor.Name
+			result.AttributeDataType = configuration.record.AttributeDataType
+			result.MinimumReportingInterval = configuration.record.MinimumReportingInterval
+			result.MaximumReportingInterval = configuration.record.MaximumReportingInterval
+			result.ReportableChange = configuration.record.ReportableChange
+			result.TimeoutPeriod = configuration.record.TimeoutPeriod
+		}
+		response.AttributeReportingConfigurationResponseRecords = append(response.AttributeReportingConfigurationResponseRecords, result)
+	}
+	return response
+}
+
+// Poll returns the reports due at the current time of the clock. Attributes of the same cluster
+// reported to the same destination are coalesced into one command.
+func (r *Reporter) Poll() []*zcl.ZclOutgoingMessage {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	now := r.clock.Now()
+	due := map[destination][]*cluster.AttributeReport{}
+	for key, configuration := range r.configurations {
+		if key.direction != cluster.ReportDirectionAttributeReported {
+			continue
+		}
+		attribute, ok := r.store.Get(key.endpoint, key.clusterId, key.attributeId)
+		if !ok || !configuration.due(now, attribute.Value) {
+			continue
+		}
+		configuration.lastValue = attribute.Value
+		configuration.lastReported = now
+		d := destination{key.endpoint, key.clusterId, configuration.dstAddr, configuration.dstEndpoint, configuration.profileId}
+		due[d] = append(due[d], &cluster.AttributeReport{AttributeID: key.attributeId, Attribute: attribute})
+	}
+	destinations := make([]destination, 0, len(due))
+	for d := range due {
+		destinations = append(destinations, d)
+	}
+	sort.Slice(destinations, func(i, j int) bool {
+		a, b := destinations[i], destinations[j]
+		if a.endpoint != b.endpoint {
+			return a.endpoint < b.endpoint
+		}
+		if a.clusterId != b.clusterId {
+			return a.clusterId < b.clusterId
+		}
+		return a.dstAddr < b.dstAddr || a.dstAddr == b.dstAddr && a.dstEndpoint < b.dstEndpoint
+	})
+	messages := []*zcl.ZclOutgoingMessage{}
+	for _, d := range destinations {
+		reports := due[d]
+		sort.Slice(reports, func(i, j int) bool { return reports[i].AttributeID < reports[j].AttributeID })
+		f := frame.MustBuild(frame.New().
+			IdGenerator(r.transactionIdProvider).
+			FrameType(frame.FrameTypeGlobal).
+			Direction(frame.DirectionServerClient).
+			DisableDefaultResponse(true).
+			CommandId(uint8(cluster.ZclCommandReportAttributes)).
+			Command(&cluster.ReportAttributesCommand{AttributeReports: reports}))
+		messages = append(messages, &zcl.ZclOutgoingMessage{
+			DstAddrMode: zcl.AddressModeNwk,
+			DstAddr:     d.dstAddr,
+			DstEndpoint: d.dstEndpoint,
+			SrcEndpoint: d.endpoint,
+			ClusterID:   uint16(d.clusterId),
+			ProfileID:   d.profileId,
+			Frame:       f,
+		})
+	}
+	return messages
+}
+
+// Run polls once a second and sends the due reports until the context is done. Reports which
+// fail to send are passed to the OnSendError handler and skipped, they aren't sent again before
+// they are due again.
+func (r *Reporter) Run(ctx context.Context, transport zcl.Transport) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.clock.After(time.Second):
+			for _, message := range r.Poll() {
+				err := transport.Send(ctx, message.ToApplicationMessage())
+				if err != nil && r.sendErrorHandler != nil {
+					r.sendErrorHandler(message, err)
+				}
+			}
+		}
+	}
+}
+
+func (c *reportingConfiguration) due(now time.Time, value interface{}) bool {
+	elapsed := now.Sub(c.lastReported)
+	max := c.record.MaximumReportingInterval
+	if max != 0 && elapsed >= time.Duration(max)*time.Second {
+		return true
+	}
+	return elapsed >= time.Duration(c.record.MinimumReportingInterval)*time.Second && c.changed(value)
+}
+
+func (c *reportingConfiguration) changed(value interface{}) bool {
+	if !c.record.AttributeDataType.Analog() || c.record.ReportableChange == nil {
+		return !reflect.DeepEqual(value, c.lastValue)
+	}
+	switch threshold := c.record.ReportableChange.Value.(type) {
+	case uint64:
+		current, ok := value.(uint64)
+		last, lastOk := c.lastValue.(uint64)
+		if !ok || !lastOk {
+			break
+		}
+		if current >= last {
+			return current != last && current-last >= threshold
+		}
+		return last-current >= threshold
+	case int64:
+		current, ok := value.(int64)
+		last, lastOk := c.lastValue.(int64)
+		if !ok || !lastOk {
+			break
+		}
+		delta := current - last
+		if delta < 0 {
+			delta = -delta
+		}
+		return delta != 0 && delta >= threshold
+	case uint32:
+		current, ok := value.(uint32)
+		last, lastOk := c.lastValue.(uint32)
+		if !ok || !lastOk {
+			break
+		}
+		if current >= last {
+			return current != last && current-last >= threshold
+		}
+		return last-current >= threshold
+	case float32:
+		current, ok := value.(float32)
+		last, lastOk := c.lastValue.(float32)
+		if !ok || !lastOk {
+			break
+		}
+		delta := math.Abs(float64(current) - float64(last))
+		return delta != 0 && delta >= float64(threshold)
+	case float64:
+		current, ok := value.(float64)
+		last, lastOk := c.lastValue.(float64)
+		if !ok || !lastOk {
+			break
+		}
+		delta := math.Abs(current - last)
+		return delta != 0 && delta >= threshold
+	}
+	return !reflect.DeepEqual(value, c.lastValue)
+}
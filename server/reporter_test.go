@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/internal/clocktest"
+	. "gopkg.in/check.v1"
+)
+
+type ReporterSuite struct {
+	zcl      *zcl.Zcl
+	store    *AttributeStore
+	clock    *clocktest.Clock
+	reporter *Reporter
+}
+
+var _ = Suite(&ReporterSuite{})
+
+func (s *ReporterSuite) SetUpTest(c *C) {
+	s.zcl = zcl.New()
+	s.store = NewAttributeStore(s.zcl.ClusterLibrary())
+	c.Assert(s.store.AddCluster(1, cluster.OnOff), IsNil)
+	c.Assert(s.store.AddCluster(1, cluster.ColorControl), IsNil)
+	c.Assert(s.store.Set(1, cluster.OnOff, 0x0000, false), IsNil)
+	c.Assert(s.store.Set(1, cluster.OnOff, 0x4000, false), IsNil)
+	c.Assert(s.store.Set(1, cluster.ColorControl, 0x0003, uint64(1000)), IsNil)
+	c.Assert(s.store.Set(1, cluster.ColorControl, 0x0004, uint64(2000)), IsNil)
+	s.clock = clocktest.New(time.Unix(0, 0))
+	s.reporter = NewReporter(s.store).Clock(s.clock).IdGenerator(func() uint8 { return 0x40 })
+}
+
+func (s *ReporterSuite) request(c *C, clusterId cluster.ClusterId, commandId cluster.ZclCommand, command interface{}) *zcl.ZclOutgoingMessage {
+	f, err := frame.New().
+		IdGenerator(func() uint8 { return 0x20 }).
+		FrameType(frame.FrameTypeGlobal).
+		Direction(frame.DirectionClientServer).
+		CommandId(uint8(commandId)).
+		Command(command).
+		Build()
+	c.Assert(err, IsNil)
+	im, err := s.zcl.ToZclIncomingMessage(&zcl.ApplicationMessage{
+		SrcAddr: "0x0000", SrcEndpoint: 1, DstEndpoint: 1, ClusterID: uint16(clusterId), ProfileID: 0x0104, Data: frame.Encode(f),
+	})
+	c.Assert(err, IsNil)
+	return s.reporter.Respond(im)
+}
+
+func (s *ReporterSuite) configure(c *C, clusterId cluster.ClusterId, records ...*cluster.AttributeReportingConfigurationRecord) []uint8 {
+	m := s.request(c, clusterId, cluster.ZclCommandConfigureReporting, &cluster.ConfigureReportingCommand{AttributeReportingConfigurationRecords: records})
+	return frame.Encode(m.Frame)
+}
+
+func reported(attributeId uint16, dataType cluster.ZclDataType, min, max uint16, change interface{}) *cluster.AttributeReportingConfigurationRecord {
+	record := &cluster.AttributeReportingConfigurationRecord{
+		AttributeID:              attributeId,
+		AttributeDataType:        dataType,
+		MinimumReportingInterval: min,
+		MaximumReportingInterval: max,
+	}
+	if change != nil {
+		record.ReportableChange = &cluster.Attribute{DataType: dataType, Value: change}
+	}
+	return record
+}
+
+func (s *ReporterSuite) reports(c *C, m *zcl.ZclOutgoingMessage) []*cluster.AttributeReport {
+	im, err := s.zcl.ToZclIncomingMessage(m.ToApplicationMessage())
+	c.Assert(err, IsNil)
+	return im.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports
+}
+
+func (s *ReporterSuite) TestConfigureStatuses(c *C) {
+	c.Assert(s.configure(c, cluster.OnOff, reported(0x0000, cluster.ZclDataTypeBoolean, 0, 10, nil)),
+		DeepEquals, []uint8{0x18, 0x20, 0x07, 0x00})
+	c.Assert(s.configure(c, cluster.OnOff,
+		reported(0x0000, cluster.ZclDataTypeUint8, 0, 10, nil),
+		reported(0x4000, cluster.ZclDataTypeBoolean, 0, 10, nil),
+		reported(0x0000, cluster.ZclDataTypeBoolean, 20, 10, nil),
+		reported(0x1234, cluster.ZclDataTypeBoolean, 0, 10, nil)),
+		DeepEquals, []uint8{0x18, 0x20, 0x07,
+			0x8d, 0x00, 0x00, 0x00,
+			0x8c, 0x00, 0x00, 0x40,
+			0x87, 0x00, 0x00, 0x00,
+			0x86, 0x00, 0x34, 0x12})
+}
+
+func (s *ReporterSuite) TestPeriodicReports(c *C) {
+	s.configure(c, cluster.ColorControl,
+		reported(0x0003, cluster.ZclDataTypeUint16, 1, 10, uint64(100)),
+		reported(0x0004, cluster.ZclDataTypeUint16, 1, 10, uint64(100)))
+	s.clock.Advance(9 * time.Second)
+	c.Assert(s.reporter.Poll(), HasLen, 0)
+	s.clock.Advance(time.Second)
+	messages := s.reporter.Poll()
+	c.Assert(messages, HasLen, 1)
+	c.Assert(messages[0].DstAddr, Equals, "0x0000")
+	c.Assert(frame.Encode(messages[0].Frame), DeepEquals, []uint8{0x18, 0x40, 0x0a,
+		0x03, 0x00, 0x21, 0xe8, 0x03,
+		0x04, 0x00, 0x21, 0xd0, 0x07})
+	c.Assert(s.reporter.Poll(), HasLen, 0)
+}
+
+func (s *ReporterSuite) TestReportableChange(c *C) {
+	s.configure(c, cluster.ColorControl,
+		reported(0x0003, cluster.ZclDataTypeUint16, 5, 0, uint64(100)),
+		reported(0x0004, cluster.ZclDataTypeUint16, 5, 0, uint64(100)))
+	s.store.Set(1, cluster.ColorControl, 0x0003, uint64(1099))
+	s.store.Set(1, cluster.ColorControl, 0x0004, uint64(1900))
+	s.clock.Advance(4 * time.Second)
+	c.Assert(s.reporter.Poll(), HasLen, 0)
+	s.clock.Advance(time.Second)
+	messages := s.reporter.Poll()
+	c.Assert(messages, HasLen, 1)
+	c.Assert(s.reports(c, messages[0]), HasLen, 1)
+	c.Assert(s.reports(c, messages[0])[0].AttributeID, Equals, uint16(0x0004))
+	s.clock.Advance(3600 * time.Second)
+	c.Assert(s.reporter.Poll(), HasLen, 0)
+}
+
+func (s *ReporterSuite) TestFloatChange(c *C) {
+	c.Assert(s.store.AddCluster(1, cluster.AnalogInputBasic), IsNil)
+	c.Assert(s.store.Set(1, cluster.AnalogInputBasic, 0x0055, float32(20)), IsNil)
+	s.configure(c, cluster.AnalogInputBasic, reported(0x0055, cluster.ZclDataTypeSinglePrec, 5, 0, float32(0.5)))
+	s.store.Set(1, cluster.AnalogInputBasic, 0x0055, float32(20.25))
+	s.clock.Advance(5 * time.Second)
+	c.Assert(s.reporter.Poll(), HasLen, 0)
+	s.store.Set(1, cluster.AnalogInputBasic, 0x0055, float32(19.5))
+	messages := s.reporter.Poll()
+	c.Assert(messages, HasLen, 1)
+	c.Assert(s.reports(c, messages[0])[0].Attribute.Value, Equals, float32(19.5))
+}
+
+func (s *ReporterSuite) TestDiscreteChange(c *C) {
+	s.configure(c, cluster.OnOff, reported(0x0000, cluster.ZclDataTypeBoolean, 0, NoReporting-1, nil))
+	c.Assert(s.reporter.Poll(), HasLen, 0)
+	s.store.Set(1, cluster.OnOff, 0x0000, true)
+	messages := s.reporter.Poll()
+	c.Assert(messages, HasLen, 1)
+	c.Assert(s.reports(c, messages[0])[0].Attribute.Value, Equals, true)
+
+	s.configure(c, cluster.OnOff, reported(0x0000, cluster.ZclDataTypeBoolean, 0, NoReporting, nil))
+	s.store.Set(1, cluster.OnOff, 0x0000, false)
+	c.Assert(s.reporter.Poll(), HasLen, 0)
+}
+
+func (s *ReporterSuite) TestReadReportingConfiguration(c *C) {
+	s.configure(c, cluster.OnOff, reported(0x0000, cluster.ZclDataTypeBoolean, 1, 300, nil))
+	m := s.request(c, cluster.OnOff, cluster.ZclCommandReadReportingConfiguration, &cluster.ReadReportingConfigurationCommand{
+		AttributeRecords: []*cluster.AttributeRecord{{AttributeID: 0x0000}, {AttributeID: 0x4000}, {Direction: 1, AttributeID: 0x0000}},
+	})
+	c.Assert(frame.Encode(m.Frame), DeepEquals, []uint8{0x18, 0x20, 0x09,
+		0x00, 0x00, 0x00, 0x00, 0x10, 0x01, 0x00, 0x2c, 0x01,
+		0x8c, 0x00, 0x00, 0x40,
+		0x8b, 0x01, 0x00, 0x00})
+}
+
+func (s *ReporterSuite) TestOtherCommandsPassThrough(c *C) {
+	m := s.request(c, cluster.OnOff, cluster.ZclCommandReadAttributes, &cluster.ReadAttributesCommand{AttributeIDs: []uint16{0x0000}})
+	c.Assert(frame.Encode(m.Frame), DeepEquals, []uint8{0x18, 0x20, 0x01, 0x00, 0x00, 0x00, 0x10, 0x00})
+}
+
+func (s *ReporterSuite) TestGroupConfigure(c *C) {
+	im, err := s.zcl.ToZclIncomingMessage(&zcl.ApplicationMessage{
+		GroupID: 5, SrcAddr: "0x1234", SrcEndpoint: 1, DstEndpoint: 1, ClusterID: uint16(cluster.OnOff), ProfileID: 0x0104,
+		Data: []uint8{0x00, 0x0c, 0x06, 0x00, 0x00, 0x00, 0x10, 0x01, 0x00, 0x0a, 0x00},
+	})
+	c.Assert(err, IsNil)
+	m := s.reporter.Respond(im)
+	c.Assert(m, NotNil)
+	c.Assert(frame.Encode(m.Frame), DeepEquals, []uint8{0x18, 0x0c, 0x07, 0x00})
+}
+
+type failingTransport struct {
+	sent   int
+	cancel func()
+}
+
+func (t *failingTransport) Send(ctx context.Context, message *zcl.ApplicationMessage) error {
+	if t.sent++; t.sent == 3 {
+		t.cancel()
+	}
+	return errors.New("no route")
+}
+
+func (s *ReporterSuite) TestRunSkipsFailedReports(c *C) {
+	s.configure(c, cluster.ColorControl, reported(0x0003, cluster.ZclDataTypeUint16, 0, 1, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	transport := &failingTransport{cancel: cancel}
+	failed := 0
+	s.reporter.OnSendError(func(message *zcl.ZclOutgoingMessage, err error) {
+		c.Check(err, ErrorMatches, "no route")
+		failed++
+	})
+	c.Assert(s.reporter.Run(ctx, transport), Equals, context.Canceled)
+	// the clock never blocks, so a poll may race the cancellation
+	c.Assert(transport.sent >= 3, Equals, true)
+	c.Assert(failed, Equals, transport.sent)
+}
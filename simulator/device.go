@@ -0,0 +1,249 @@
+package simulator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/server"
+)
+
+// Handler executes a cluster specific command received on an endpoint and returns the status of
+// the DefaultResponse owed for it.
+type Handler func(endpoint uint8, command interface{}) cluster.ZclStatus
+
+type sensor struct {
+	endpoint uint8
+	*Sensor
+	next time.Time
+}
+
+// Device is a simulated device. Global commands are answered from its attribute store, which also
+// sends the configured reports, and cluster specific commands are passed to the handler of their
+// cluster.
+type Device struct {
+	addr      uint16
+	network   *Network
+	store     *server.AttributeStore
+	reporter  *server.Reporter
+	mutex     sync.Mutex
+	handlers  map[cluster.ClusterId]Handler
+	endpoints []uint8
+	groups    map[uint16][]uint8
+	sensors   []*sensor
+	levels    map[uint8]*transition
+	offTimers map[uint8]time.Time
+}
+
+func newDevice(n *Network, addr uint16, profile *Profile) (*Device, error) {
+	store := server.NewAttributeStore(n.zcl.ClusterLibrary())
+	d := &Device{
+		addr:      addr,
+		network:   n,
+		store:     store,
+		reporter:  server.NewReporter(store).Clock(n.clock),
+		groups:    map[uint16][]uint8{},
+		levels:    map[uint8]*transition{},
+		offTimers: map[uint8]time.Time{},
+	}
+	d.handlers = map[cluster.ClusterId]Handler{
+		cluster.OnOff:        d.handleOnOff,
+		cluster.LevelControl: d.handleLevelControl,
+	}
+	now := n.clock.Now()
+	for _, e := range profile.Endpoints {
+		d.endpoints = append(d.endpoints, e.Id)
+		for _, clusterId := range e.Clusters {
+			if err := store.AddCluster(e.Id, clusterId); err != nil {
+				return nil, err
+			}
+		}
+		for _, a := range e.Attributes {
+			if err := store.Set(e.Id, a.ClusterId, a.AttributeId, a.Value); err != nil {
+				return nil, err
+			}
+		}
+		for _, s := range e.Sensors {
+			if err := store.Set(e.Id, s.ClusterId, s.AttributeId, s.Read(now)); err != nil {
+				return nil, err
+			}
+			d.sensors = append(d.sensors, &sensor{e.Id, s, now.Add(s.Interval)})
+		}
+		if err := d.configureReporting(e); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// configureReporting configures the reporting of the endpoint the way the gateway would, with
+// ConfigureReporting commands.
+func (d *Device) configureReporting(e *Endpoint) error {
+	records := map[cluster.ClusterId][]*cluster.AttributeReportingConfigurationRecord{}
+	clusterIds := []cluster.ClusterId{}
+	for _, r := range e.Reporting {
+		c, ok := d.network.zcl.ClusterLibrary().Clusters()[r.ClusterId]
+		if !ok {
+			return fmt.Errorf("unknown cluster %d", r.ClusterId)
+		}
+		descriptor, ok := c.AttributeDescriptors[r.AttributeId]
+		if !ok {
+			return fmt.Errorf("cluster %d has no attribute %d", r.ClusterId, r.AttributeId)
+		}
+		record := &cluster.AttributeReportingConfigurationRecord{
+			Direction:                cluster.ReportDirectionAttributeReported,
+			AttributeID:              r.AttributeId,
+			AttributeDataType:        descriptor.Type,
+			MinimumReportingInterval: r.Min,
+			MaximumReportingInterval: r.Max,
+		}
+		if descriptor.Type.Analog() {
+			record.ReportableChange = &cluster.Attribute{DataType: descriptor.Type, Value: r.Change}
+		}
+		if _, ok := records[r.ClusterId]; !ok {
+			clusterIds = append(clusterIds, r.ClusterId)
+		}
+		records[r.ClusterId] = append(records[r.ClusterId], record)
+	}
+	for _, clusterId := range clusterIds {
+		f, err := frame.New().
+			FrameType(frame.FrameTypeGlobal).
+			Direction(frame.DirectionClientServer).
+			CommandId(uint8(cluster.ZclCommandConfigureReporting)).
+			Command(&cluster.ConfigureReportingCommand{AttributeReportingConfigurationRecords: records[clusterId]}).
+			Build()
+		if err != nil {
+			return err
+		}
+		im, err := d.network.zcl.ToZclIncomingMessage(&zcl.ApplicationMessage{
+			SrcAddrMode: zcl.AddressModeNwk,
+			SrcAddr:     nwkAddr(gatewayAddr),
+			DstAddrMode: zcl.AddressModeNwk,
+			DstAddr:     nwkAddr(d.addr),
+			SrcEndpoint: gatewayEndpoint,
+			DstEndpoint: e.Id,
+			ClusterID:   uint16(clusterId),
+			ProfileID:   e.ProfileId,
+			Data:        frame.Encode(f),
+		})
+		if err != nil {
+			return err
+		}
+		reply, err := d.network.zcl.ToZclIncomingMessage(d.reporter.Respond(im).ToApplicationMessage())
+		if err != nil {
+			return err
+		}
+		response, ok := reply.Data.Command.(*cluster.ConfigureReportingResponse)
+		if !ok {
+			return fmt.Errorf("reporting of cluster %d isn't supported", clusterId)
+		}
+		for _, status := range response.AttributeStatusRecords {
+			if status.Status != cluster.ZclStatusSuccess {
+				return fmt.Errorf("reporting of attribute %d of cluster %d: %v", status.AttributeID, clusterId, status.Status)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Device) Addr() string {
+	return nwkAddr(d.addr)
+}
+
+// Handle replaces the handler of the cluster specific commands of a cluster.
+func (d *Device) Handle(clusterId cluster.ClusterId, handler Handler) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.handlers[clusterId] = handler
+	return d
+}
+
+// AddGroup makes the endpoint receive the commands sent to the group.
+func (d *Device) AddGroup(groupId uint16, endpoint uint8) *Device {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.groups[groupId] = append(d.groups[groupId], endpoint)
+	return d
+}
+
+func (d *Device) Get(endpoint uint8, clusterId cluster.ClusterId, attributeId uint16) (interface{}, bool) {
+	if attribute, ok := d.store.Get(endpoint, clusterId, attributeId); ok {
+		return attribute.Value, true
+	}
+	return nil, false
+}
+
+// Set changes an attribute as the device itself would, e.g. when a button is pressed. Changes are
+// reported on the next poll of the network.
+func (d *Device) Set(endpoint uint8, clusterId cluster.ClusterId, attributeId uint16, value interface{}) error {
+	return d.store.Set(endpoint, clusterId, attributeId, value)
+}
+
+// receive executes a command and returns the messages to send back.
+func (d *Device) receive(im *zcl.ZclIncomingMessage) []*zcl.ZclOutgoingMessage {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	endpoints := []uint8{im.DstEndpoint}
+	switch {
+	case im.GroupID != 0:
+		endpoints = d.groups[im.GroupID]
+	case im.DstEndpoint == broadcastEndpoint:
+		endpoints = d.endpoints
+	}
+	messages := []*zcl.ZclOutgoingMessage{}
+	for _, endpoint := range endpoints {
+		if (im.GroupID != 0 || im.DstEndpoint == broadcastEndpoint) && !d.store.HasCluster(endpoint, cluster.ClusterId(im.ClusterID)) {
+			continue
+		}
+		em := *im
+		em.DstEndpoint = endpoint
+		if m := d.respond(&em); m != nil {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+func (d *Device) respond(im *zcl.ZclIncomingMessage) *zcl.ZclOutgoingMessage {
+	f := im.Data
+	if f == nil || f.FrameControl == nil {
+		return nil
+	}
+	if f.FrameControl.FrameType == frame.FrameTypeGlobal {
+		return d.reporter.Respond(im)
+	}
+	clusterId := cluster.ClusterId(im.ClusterID)
+	if f.Command == nil {
+		return zcl.DefaultResponse(im, zcl.UnsupportedCommandStatus(f.FrameControl), false)
+	}
+	handler, ok := d.handlers[clusterId]
+	if !ok || !d.store.HasCluster(im.DstEndpoint, clusterId) {
+		return zcl.DefaultResponse(im, cluster.ZclStatusUnsupClusterCommand, false)
+	}
+	return zcl.DefaultResponse(im, handler(im.DstEndpoint, f.Command), false)
+}
+
+// poll advances transitions, timers and sensors to the current time and returns the due reports.
+func (d *Device) poll(now time.Time) []*zcl.ZclOutgoingMessage {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for endpoint := range d.levels {
+		d.advanceLevel(endpoint, now)
+	}
+	for endpoint, offAt := range d.offTimers {
+		if !now.Before(offAt) {
+			d.setOnOff(endpoint, false)
+		}
+	}
+	for _, s := range d.sensors {
+		if now.Before(s.next) {
+			continue
+		}
+		d.store.Set(s.endpoint, s.ClusterId, s.AttributeId, s.Read(now))
+		s.next = now.Add(s.Interval)
+	}
+	return d.reporter.Poll()
+}
@@ -0,0 +1,177 @@
+package simulator
+
+import (
+	"time"
+
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+const (
+	currentLevelAttribute        = 0x0000
+	remainingTimeAttribute       = 0x0001
+	onOffTransitionTimeAttribute = 0x0010
+	defaultMoveRateAttribute     = 0x0014
+)
+
+const (
+	minLevel = 0x01
+	maxLevel = 0xfe
+)
+
+const (
+	modeUp   = 0x00
+	modeDown = 0x01
+)
+
+// A transition time or rate with these values is replaced by the one stored in the attributes.
+const (
+	defaultTransitionTime = 0xffff
+	defaultRate           = 0xff
+)
+
+// transition moves the level linearly. Transitions of the commands with OnOff turn the endpoint
+// on when they start upwards and off when they end at the minimum level.
+type transition struct {
+	from      uint8
+	to        uint8
+	start     time.Time
+	duration  time.Duration
+	withOnOff bool
+}
+
+func (t *transition) level(now time.Time) uint8 {
+	elapsed := now.Sub(t.start)
+	if elapsed >= t.duration {
+		return t.to
+	}
+	return uint8(int64(t.from) + (int64(t.to)-int64(t.from))*int64(elapsed)/int64(t.duration))
+}
+
+func (t *transition) remaining(now time.Time) time.Duration {
+	if remaining := t.start.Add(t.duration).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+func (d *Device) handleLevelControl(endpoint uint8, command interface{}) cluster.ZclStatus {
+	switch cmd := command.(type) {
+	case *cluster.MoveToLevelCommand:
+		d.moveToLevel(endpoint, cmd.Level, d.transitionTime(endpoint, cmd.TransitionTime), false)
+	case *cluster.MoveToLevelOnOffCommand:
+		d.moveToLevel(endpoint, cmd.Level, d.transitionTime(endpoint, cmd.TransitionTime), true)
+	case *cluster.MoveCommand:
+		return d.move(endpoint, cmd.MoveMode, cmd.Rate, false)
+	case *cluster.MoveOnOffCommand:
+		return d.move(endpoint, cmd.MoveMode, cmd.Rate, true)
+	case *cluster.StepCommand:
+		return d.step(endpoint, cmd.StepMode, cmd.StepSize, cmd.TransitionTime, false)
+	case *cluster.StepOnOffCommand:
+		return d.step(endpoint, cmd.StepMode, cmd.StepSize, cmd.TransitionTime, true)
+	case *cluster.StopCommand, *cluster.StopOnOffCommand:
+		d.advanceLevel(endpoint, d.network.clock.Now())
+		delete(d.levels, endpoint)
+		d.store.Set(endpoint, cluster.LevelControl, remainingTimeAttribute, uint64(0))
+	default:
+		return cluster.ZclStatusUnsupClusterCommand
+	}
+	return cluster.ZclStatusSuccess
+}
+
+func (d *Device) move(endpoint uint8, mode uint8, rate uint8, withOnOff bool) cluster.ZclStatus {
+	var target uint8
+	switch mode {
+	case modeUp:
+		target = maxLevel
+	case modeDown:
+		target = minLevel
+	default:
+		return cluster.ZclStatusInvalidField
+	}
+	if rate == defaultRate {
+		rate = 0
+		if value, ok := d.Get(endpoint, cluster.LevelControl, defaultMoveRateAttribute); ok {
+			rate = uint8(value.(uint64))
+		}
+	}
+	if rate == 0 {
+		return cluster.ZclStatusSuccess
+	}
+	distance := int64(target) - int64(d.level(endpoint))
+	if distance < 0 {
+		distance = -distance
+	}
+	d.moveToLevel(endpoint, target, time.Duration(distance)*time.Second/time.Duration(rate), withOnOff)
+	return cluster.ZclStatusSuccess
+}
+
+func (d *Device) step(endpoint uint8, mode uint8, size uint8, transitionTime uint16, withOnOff bool) cluster.ZclStatus {
+	level := int(d.level(endpoint))
+	switch mode {
+	case modeUp:
+		level += int(size)
+	case modeDown:
+		level -= int(size)
+	default:
+		return cluster.ZclStatusInvalidField
+	}
+	d.moveToLevel(endpoint, clampLevel(level), d.transitionTime(endpoint, transitionTime), withOnOff)
+	return cluster.ZclStatusSuccess
+}
+
+func (d *Device) moveToLevel(endpoint uint8, level uint8, duration time.Duration, withOnOff bool) {
+	now := d.network.clock.Now()
+	d.advanceLevel(endpoint, now)
+	to := clampLevel(int(level))
+	if withOnOff && to > minLevel {
+		d.setOnOff(endpoint, true)
+	}
+	d.levels[endpoint] = &transition{d.level(endpoint), to, now, duration, withOnOff}
+	d.advanceLevel(endpoint, now)
+}
+
+// advanceLevel updates CurrentLevel and RemainingTime to the progress of the transition.
+func (d *Device) advanceLevel(endpoint uint8, now time.Time) {
+	t, ok := d.levels[endpoint]
+	if !ok {
+		return
+	}
+	d.store.Set(endpoint, cluster.LevelControl, currentLevelAttribute, uint64(t.level(now)))
+	remaining := (t.remaining(now) + 100*time.Millisecond - 1) / (100 * time.Millisecond)
+	d.store.Set(endpoint, cluster.LevelControl, remainingTimeAttribute, uint64(remaining))
+	if remaining == 0 {
+		delete(d.levels, endpoint)
+		if t.withOnOff && t.to == minLevel {
+			d.setOnOff(endpoint, false)
+		}
+	}
+}
+
+func (d *Device) level(endpoint uint8) uint8 {
+	if value, ok := d.Get(endpoint, cluster.LevelControl, currentLevelAttribute); ok {
+		return uint8(value.(uint64))
+	}
+	return minLevel
+}
+
+// transitionTime returns the duration of a transition time in tenths of a second, taking the
+// default one from OnOffTransitionTime.
+func (d *Device) transitionTime(endpoint uint8, transitionTime uint16) time.Duration {
+	if transitionTime != defaultTransitionTime {
+		return tenths(transitionTime)
+	}
+	if value, ok := d.Get(endpoint, cluster.LevelControl, onOffTransitionTimeAttribute); ok {
+		return tenths(uint16(value.(uint64)))
+	}
+	return 0
+}
+
+func clampLevel(level int) uint8 {
+	switch {
+	case level < minLevel:
+		return minLevel
+	case level > maxLevel:
+		return maxLevel
+	}
+	return uint8(level)
+}
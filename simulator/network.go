@@ -0,0 +1,216 @@
+// Package simulator runs virtual devices on an in-memory network, so that gateway logic can be
+// tested end to end without radios. Frames exchanged with the devices go through the same encoding
+// and decoding as frames exchanged with real ones.
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+)
+
+// The gateway is the coordinator of the network and receives reports on its first endpoint.
+const (
+	gatewayAddr     = 0x0000
+	gatewayEndpoint = 1
+)
+
+const broadcastEndpoint = 0xff
+
+// PollInterval is the resolution at which Run advances the devices.
+const PollInterval = 100 * time.Millisecond
+
+// Network connects simulated devices to the gateway. It is the gateway's transport: messages sent
+// to it are delivered to the addressed devices, and the messages devices send to the gateway are
+// queued for Next.
+type Network struct {
+	zcl     *zcl.Zcl
+	clock   zcl.Clock
+	mutex   sync.Mutex
+	devices map[uint16]*Device
+	queue   []*zcl.ApplicationMessage
+	ready   chan struct{}
+}
+
+func NewNetwork(z *zcl.Zcl) *Network {
+	return &Network{
+		zcl:     z,
+		clock:   zcl.SystemClock{},
+		devices: map[uint16]*Device{},
+		ready:   make(chan struct{}),
+	}
+}
+
+// Clock sets the clock of transitions, sensors and reports. Set it before adding devices.
+func (n *Network) Clock(clock zcl.Clock) *Network {
+	n.clock = clock
+	return n
+}
+
+// AddDevice joins a device with the profile at the network address.
+func (n *Network) AddDevice(addr uint16, profile *Profile) (*Device, error) {
+	if addr == gatewayAddr {
+		return nil, fmt.Errorf("%s is the address of the gateway", nwkAddr(addr))
+	}
+	n.mutex.Lock()
+	_, taken := n.devices[addr]
+	n.mutex.Unlock()
+	if taken {
+		return nil, fmt.Errorf("%s is already taken", nwkAddr(addr))
+	}
+	d, err := newDevice(n, addr, profile)
+	if err != nil {
+		return nil, err
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.devices[addr] = d
+	return d, nil
+}
+
+func (n *Network) RemoveDevice(addr uint16) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	delete(n.devices, addr)
+}
+
+// Send delivers a message from the gateway. Unicasts fail when no device has the address.
+func (n *Network) Send(ctx context.Context, m *zcl.ApplicationMessage) error {
+	return n.route(gatewayAddr, m)
+}
+
+// Next returns the next message sent to the gateway, waiting for one until the context is done.
+func (n *Network) Next(ctx context.Context) (*zcl.ApplicationMessage, error) {
+	for {
+		n.mutex.Lock()
+		if len(n.queue) > 0 {
+			m := n.queue[0]
+			n.queue = n.queue[1:]
+			n.mutex.Unlock()
+			return m, nil
+		}
+		ready := n.ready
+		n.mutex.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ready:
+		}
+	}
+}
+
+// Pending returns the number of messages queued for the gateway.
+func (n *Network) Pending() int {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return len(n.queue)
+}
+
+// Poll advances the devices to the current time of the clock and sends their due reports.
+// Messages to devices which left the network are dropped.
+func (n *Network) Poll() {
+	now := n.clock.Now()
+	for _, d := range n.sortedDevices() {
+		for _, m := range d.poll(now) {
+			n.route(d.addr, m.ToApplicationMessage())
+		}
+	}
+}
+
+// Run polls the devices every PollInterval until the context is done.
+func (n *Network) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-n.clock.After(PollInterval):
+			n.Poll()
+		}
+	}
+}
+
+func (n *Network) sortedDevices() []*Device {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	devices := make([]*Device, 0, len(n.devices))
+	for _, d := range n.devices {
+		devices = append(devices, d)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].addr < devices[j].addr })
+	return devices
+}
+
+func (n *Network) route(src uint16, m *zcl.ApplicationMessage) error {
+	var devices []*Device
+	switch m.DstAddrMode {
+	case zcl.AddressModeGroup, zcl.AddressModeBroadcast:
+		for _, d := range n.sortedDevices() {
+			if d.addr != src {
+				devices = append(devices, d)
+			}
+		}
+		if src != gatewayAddr {
+			n.deliver(src, m)
+		}
+	default:
+		addr, err := parseNwkAddr(m.DstAddr)
+		if err != nil {
+			return err
+		}
+		if addr == gatewayAddr {
+			n.deliver(src, m)
+			return nil
+		}
+		n.mutex.Lock()
+		d, ok := n.devices[addr]
+		n.mutex.Unlock()
+		if !ok {
+			return fmt.Errorf("no device at %s", nwkAddr(addr))
+		}
+		devices = []*Device{d}
+	}
+	am := *m
+	am.SrcAddrMode = zcl.AddressModeNwk
+	am.SrcAddr = nwkAddr(src)
+	am.WasBroadcast = m.DstAddrMode == zcl.AddressModeGroup || m.DstAddrMode == zcl.AddressModeBroadcast
+	im, _ := n.zcl.ToZclIncomingMessage(&am)
+	for _, d := range devices {
+		for _, reply := range d.receive(im) {
+			n.route(d.addr, reply.ToApplicationMessage())
+		}
+	}
+	return nil
+}
+
+// deliver queues a message for the gateway as its adapter would have received it.
+func (n *Network) deliver(src uint16, m *zcl.ApplicationMessage) {
+	am := *m
+	am.SrcAddrMode = zcl.AddressModeNwk
+	am.SrcAddr = nwkAddr(src)
+	am.WasBroadcast = m.DstAddrMode == zcl.AddressModeGroup || m.DstAddrMode == zcl.AddressModeBroadcast
+	am.LinkQuality = 0xff
+	am.Timestamp = uint32(n.clock.Now().Unix())
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.queue = append(n.queue, &am)
+	close(n.ready)
+	n.ready = make(chan struct{})
+}
+
+func nwkAddr(addr uint16) string {
+	return fmt.Sprintf("0x%04x", addr)
+}
+
+func parseNwkAddr(addr string) (uint16, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(addr), "0x"), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid network address %q", addr)
+	}
+	return uint16(value), nil
+}
@@ -0,0 +1,67 @@
+package simulator
+
+import (
+	"time"
+
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+const (
+	onOffAttribute              = 0x0000
+	globalSceneControlAttribute = 0x4000
+	onTimeAttribute             = 0x4001
+	offWaitTimeAttribute        = 0x4002
+)
+
+// acceptOnlyWhenOn is the bit of the OnOffControl field of OnWithTimedOff which makes the command
+// apply only to devices that are already on.
+const acceptOnlyWhenOn = 0x01
+
+func (d *Device) handleOnOff(endpoint uint8, command interface{}) cluster.ZclStatus {
+	switch cmd := command.(type) {
+	case *cluster.OffCommand, *cluster.OffWithEffectCommand:
+		d.setOnOff(endpoint, false)
+	case *cluster.OnCommand:
+		d.setOnOff(endpoint, true)
+	case *cluster.OnWithRecallGlobalSceneCommand:
+		d.setOnOff(endpoint, true)
+		d.store.Set(endpoint, cluster.OnOff, globalSceneControlAttribute, true)
+	case *cluster.ToggleCommand:
+		d.setOnOff(endpoint, !d.on(endpoint))
+	case *cluster.OnWithTimedOffCommand:
+		if cmd.OnOffControl&acceptOnlyWhenOn != 0 && !d.on(endpoint) {
+			break
+		}
+		d.setOnOff(endpoint, true)
+		d.store.Set(endpoint, cluster.OnOff, offWaitTimeAttribute, uint64(cmd.OffWaitTime))
+		if cmd.OnTime != 0 && cmd.OnTime != 0xffff {
+			d.store.Set(endpoint, cluster.OnOff, onTimeAttribute, uint64(cmd.OnTime))
+			d.offTimers[endpoint] = d.network.clock.Now().Add(tenths(cmd.OnTime))
+		}
+	default:
+		return cluster.ZclStatusUnsupClusterCommand
+	}
+	return cluster.ZclStatusSuccess
+}
+
+func (d *Device) on(endpoint uint8) bool {
+	on, _ := d.Get(endpoint, cluster.OnOff, onOffAttribute)
+	return on == true
+}
+
+// setOnOff switches the endpoint, cancelling a timed off. Endpoints without an OnOff cluster are
+// left alone.
+func (d *Device) setOnOff(endpoint uint8, on bool) {
+	if !d.store.HasCluster(endpoint, cluster.OnOff) {
+		return
+	}
+	d.store.Set(endpoint, cluster.OnOff, onOffAttribute, on)
+	if _, ok := d.offTimers[endpoint]; ok {
+		delete(d.offTimers, endpoint)
+		d.store.Set(endpoint, cluster.OnOff, onTimeAttribute, uint64(0))
+	}
+}
+
+func tenths(t uint16) time.Duration {
+	return time.Duration(t) * 100 * time.Millisecond
+}
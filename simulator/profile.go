@@ -0,0 +1,108 @@
+package simulator
+
+import (
+	"math"
+	"time"
+
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+const HomeAutomationProfile = 0x0104
+
+const (
+	measuredValueAttribute    = 0x0000
+	minMeasuredValueAttribute = 0x0001
+	maxMeasuredValueAttribute = 0x0002
+)
+
+// Profile describes the endpoints a simulated device exposes.
+type Profile struct {
+	Endpoints []*Endpoint
+}
+
+// Endpoint lists the server clusters of an endpoint and the initial values of their attributes.
+// Sensors update attributes over time and Reporting configures the device to report attributes to
+// the gateway as soon as it joins, as a device bound by its installer would.
+type Endpoint struct {
+	Id         uint8
+	ProfileId  uint16
+	Clusters   []cluster.ClusterId
+	Attributes []*Attribute
+	Sensors    []*Sensor
+	Reporting  []*Reporting
+}
+
+type Attribute struct {
+	ClusterId   cluster.ClusterId
+	AttributeId uint16
+	Value       interface{}
+}
+
+// Sensor sets an attribute to the value read from it when the device joins and every interval
+// after that. Values have the types of the attribute store, e.g. int64 for signed integers.
+type Sensor struct {
+	ClusterId   cluster.ClusterId
+	AttributeId uint16
+	Interval    time.Duration
+	Read        func(now time.Time) interface{}
+}
+
+// Reporting is a reporting configuration of an attribute. Change is the reportable change of
+// analog attributes and is ignored for discrete ones.
+type Reporting struct {
+	ClusterId   cluster.ClusterId
+	AttributeId uint16
+	Min         uint16
+	Max         uint16
+	Change      interface{}
+}
+
+func OnOffLight(id uint8) *Endpoint {
+	return &Endpoint{
+		Id:        id,
+		ProfileId: HomeAutomationProfile,
+		Clusters:  []cluster.ClusterId{cluster.OnOff},
+		Attributes: []*Attribute{
+			{cluster.OnOff, onOffAttribute, false},
+			{cluster.OnOff, globalSceneControlAttribute, true},
+			{cluster.OnOff, onTimeAttribute, uint64(0)},
+			{cluster.OnOff, offWaitTimeAttribute, uint64(0)},
+		},
+	}
+}
+
+func DimmableLight(id uint8) *Endpoint {
+	e := OnOffLight(id)
+	e.Clusters = append(e.Clusters, cluster.LevelControl)
+	e.Attributes = append(e.Attributes,
+		&Attribute{cluster.LevelControl, currentLevelAttribute, uint64(maxLevel)},
+		&Attribute{cluster.LevelControl, remainingTimeAttribute, uint64(0)},
+		&Attribute{cluster.LevelControl, onOffTransitionTimeAttribute, uint64(0)},
+	)
+	return e
+}
+
+// TemperatureSensor measures the temperature in degrees Celsius every 10 seconds and reports it
+// at least every 5 minutes, or 10 seconds after it changed by half a degree.
+func TemperatureSensor(id uint8, celsius func(now time.Time) float64) *Endpoint {
+	return &Endpoint{
+		Id:        id,
+		ProfileId: HomeAutomationProfile,
+		Clusters:  []cluster.ClusterId{cluster.TemperatureMeasurement},
+		Attributes: []*Attribute{
+			{cluster.TemperatureMeasurement, minMeasuredValueAttribute, int64(-4000)},
+			{cluster.TemperatureMeasurement, maxMeasuredValueAttribute, int64(12500)},
+		},
+		Sensors: []*Sensor{{
+			ClusterId:   cluster.TemperatureMeasurement,
+			AttributeId: measuredValueAttribute,
+			Interval:    10 * time.Second,
+			Read: func(now time.Time) interface{} {
+				return int64(math.Round(celsius(now) * 100))
+			},
+		}},
+		Reporting: []*Reporting{
+			{cluster.TemperatureMeasurement, measuredValueAttribute, 10, 300, int64(50)},
+		},
+	}
+}
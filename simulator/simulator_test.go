@@ -0,0 +1,285 @@
+package simulator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/internal/clocktest"
+	. "gopkg.in/check.v1"
+)
+
+func TestSimulator(t *testing.T) { TestingT(t) }
+
+type SimulatorSuite struct {
+	zcl     *zcl.Zcl
+	clock   *clocktest.Clock
+	network *Network
+}
+
+var _ = Suite(&SimulatorSuite{})
+
+func (s *SimulatorSuite) SetUpTest(c *C) {
+	s.zcl = zcl.New()
+	s.clock = clocktest.New(time.Unix(1600000000, 0))
+	s.network = NewNetwork(s.zcl).Clock(s.clock)
+}
+
+func (s *SimulatorSuite) advance(d time.Duration) {
+	s.clock.Advance(d)
+	s.network.Poll()
+}
+
+func (s *SimulatorSuite) send(c *C, dst uint16, frameType frame.FrameType, clusterId cluster.ClusterId, commandId uint8, command interface{}) error {
+	f, err := frame.New().
+		FrameType(frameType).
+		Direction(frame.DirectionClientServer).
+		CommandId(commandId).
+		Command(command).
+		Build()
+	c.Assert(err, IsNil)
+	m := &zcl.ZclOutgoingMessage{
+		DstAddrMode: zcl.AddressModeNwk,
+		DstAddr:     nwkAddr(dst),
+		DstEndpoint: 1,
+		SrcEndpoint: 1,
+		ClusterID:   uint16(clusterId),
+		ProfileID:   HomeAutomationProfile,
+		Frame:       f,
+	}
+	return s.network.Send(context.Background(), m.ToApplicationMessage())
+}
+
+func (s *SimulatorSuite) next(c *C) *zcl.ZclIncomingMessage {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	am, err := s.network.Next(ctx)
+	c.Assert(err, IsNil)
+	im, err := s.zcl.ToZclIncomingMessage(am)
+	c.Assert(err, IsNil)
+	return im
+}
+
+func (s *SimulatorSuite) defaultResponse(c *C) cluster.ZclStatus {
+	response, ok := s.next(c).Data.Command.(*cluster.DefaultResponseCommand)
+	c.Assert(ok, Equals, true)
+	return response.Status
+}
+
+func (s *SimulatorSuite) TestOnOff(c *C) {
+	light, err := s.network.AddDevice(0x1a2b, &Profile{Endpoints: []*Endpoint{OnOffLight(1)}})
+	c.Assert(err, IsNil)
+	c.Assert(light.Addr(), Equals, "0x1a2b")
+
+	c.Assert(s.send(c, 0x1a2b, frame.FrameTypeLocal, cluster.OnOff, 0x01, &cluster.OnCommand{}), IsNil)
+	c.Assert(s.defaultResponse(c), Equals, cluster.ZclStatusSuccess)
+	on, _ := light.Get(1, cluster.OnOff, onOffAttribute)
+	c.Assert(on, Equals, true)
+
+	c.Assert(s.send(c, 0x1a2b, frame.FrameTypeLocal, cluster.OnOff, 0x02, &cluster.ToggleCommand{}), IsNil)
+	c.Assert(s.defaultResponse(c), Equals, cluster.ZclStatusSuccess)
+	on, _ = light.Get(1, cluster.OnOff, onOffAttribute)
+	c.Assert(on, Equals, false)
+
+	c.Assert(s.send(c, 0x1a2b, frame.FrameTypeGlobal, cluster.OnOff, uint8(cluster.ZclCommandReadAttributes), &cluster.ReadAttributesCommand{AttributeIDs: []uint16{onOffAttribute}}), IsNil)
+	im := s.next(c)
+	c.Assert(im.SrcAddr, Equals, "0x1a2b")
+	c.Assert(im.DstEndpoint, Equals, uint8(1))
+	response := im.Data.Command.(*cluster.ReadAttributesResponse)
+	c.Assert(response.ReadAttributeStatuses[0].Attribute.Value, Equals, false)
+}
+
+func (s *SimulatorSuite) TestTimedOff(c *C) {
+	light, err := s.network.AddDevice(0x1a2b, &Profile{Endpoints: []*Endpoint{OnOffLight(1)}})
+	c.Assert(err, IsNil)
+	c.Assert(s.send(c, 0x1a2b, frame.FrameTypeLocal, cluster.OnOff, 0x42, &cluster.OnWithTimedOffCommand{OnOffControl: 1, OnTime: 30}), IsNil)
+	s.defaultResponse(c)
+	on, _ := light.Get(1, cluster.OnOff, onOffAttribute)
+	c.Assert(on, Equals, false)
+
+	c.Assert(s.send(c, 0x1a2b, frame.FrameTypeLocal, cluster.OnOff, 0x42, &cluster.OnWithTimedOffCommand{OnTime: 30}), IsNil)
+	s.defaultResponse(c)
+	s.advance(2900 * time.Millisecond)
+	on, _ = light.Get(1, cluster.OnOff, onOffAttribute)
+	c.Assert(on, Equals, true)
+	s.advance(100 * time.Millisecond)
+	on, _ = light.Get(1, cluster.OnOff, onOffAttribute)
+	c.Assert(on, Equals, false)
+	onTime, _ := light.Get(1, cluster.OnOff, onTimeAttribute)
+	c.Assert(onTime, Equals, uint64(0))
+}
+
+func (s *SimulatorSuite) TestLevelTransition(c *C) {
+	light, err := s.network.AddDevice(0x1a2b, &Profile{Endpoints: []*Endpoint{DimmableLight(1)}})
+	c.Assert(err, IsNil)
+	level := func() (interface{}, interface{}) {
+		current, _ := light.Get(1, cluster.LevelControl, currentLevelAttribute)
+		remaining, _ := light.Get(1, cluster.LevelControl, remainingTimeAttribute)
+		return current, remaining
+	}
+
+	c.Assert(s.send(c, 0x1a2b, frame.FrameTypeLocal, cluster.LevelControl, 0x00, &cluster.MoveToLevelCommand{Level: 54, TransitionTime: 20}), IsNil)
+	c.Assert(s.defaultResponse(c), Equals, cluster.ZclStatusSuccess)
+	s.advance(time.Second)
+	current, remaining := level()
+	c.Assert(current, Equals, uint64(154))
+	c.Assert(remaining, Equals, uint64(10))
+	s.advance(time.Second)
+	current, remaining = level()
+	c.Assert(current, Equals, uint64(54))
+	c.Assert(remaining, Equals, uint64(0))
+
+	c.Assert(s.send(c, 0x1a2b, frame.FrameTypeLocal, cluster.LevelControl, 0x04, &cluster.MoveToLevelOnOffCommand{Level: 0}), IsNil)
+	s.defaultResponse(c)
+	current, _ = level()
+	c.Assert(current, Equals, uint64(minLevel))
+	on, _ := light.Get(1, cluster.OnOff, onOffAttribute)
+	c.Assert(on, Equals, false)
+
+	c.Assert(s.send(c, 0x1a2b, frame.FrameTypeLocal, cluster.LevelControl, 0x05, &cluster.MoveOnOffCommand{MoveMode: modeUp, Rate: 50}), IsNil)
+	s.defaultResponse(c)
+	on, _ = light.Get(1, cluster.OnOff, onOffAttribute)
+	c.Assert(on, Equals, true)
+	s.advance(time.Second)
+	c.Assert(s.send(c, 0x1a2b, frame.FrameTypeLocal, cluster.LevelControl, 0x03, &cluster.StopCommand{}), IsNil)
+	s.defaultResponse(c)
+	s.advance(time.Second)
+	current, remaining = level()
+	c.Assert(current, Equals, uint64(51))
+	c.Assert(remaining, Equals, uint64(0))
+
+	c.Assert(s.send(c, 0x1a2b, frame.FrameTypeLocal, cluster.LevelControl, 0x02, &cluster.StepCommand{StepMode: 2, StepSize: 10}), IsNil)
+	c.Assert(s.defaultResponse(c), Equals, cluster.ZclStatusInvalidField)
+}
+
+func (s *SimulatorSuite) TestTemperatureSensorReports(c *C) {
+	celsius := 21.0
+	sensor, err := s.network.AddDevice(0x3c4d, &Profile{Endpoints: []*Endpoint{
+		TemperatureSensor(1, func(time.Time) float64 { return celsius }),
+	}})
+	c.Assert(err, IsNil)
+	value, _ := sensor.Get(1, cluster.TemperatureMeasurement, measuredValueAttribute)
+	c.Assert(value, Equals, int64(2100))
+
+	celsius = 21.3
+	s.advance(10 * time.Second)
+	c.Assert(s.network.Pending(), Equals, 0)
+	celsius = 22
+	s.advance(10 * time.Second)
+	im := s.next(c)
+	c.Assert(im.SrcAddr, Equals, "0x3c4d")
+	c.Assert(im.DstAddr, Equals, "0x0000")
+	c.Assert(im.DstEndpoint, Equals, uint8(gatewayEndpoint))
+	c.Assert(im.ClusterID, Equals, uint16(cluster.TemperatureMeasurement))
+	report := im.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports[0]
+	c.Assert(report.Attribute.Value, Equals, int64(2200))
+
+	for i := 0; i < 29; i++ {
+		s.advance(10 * time.Second)
+	}
+	c.Assert(s.network.Pending(), Equals, 0)
+	s.advance(10 * time.Second)
+	report = s.next(c).Data.Command.(*cluster.ReportAttributesCommand).AttributeReports[0]
+	c.Assert(report.Attribute.Value, Equals, int64(2200))
+}
+
+func (s *SimulatorSuite) TestGroups(c *C) {
+	for _, addr := range []uint16{0x0001, 0x0002, 0x0003} {
+		light, err := s.network.AddDevice(addr, &Profile{Endpoints: []*Endpoint{OnOffLight(1), OnOffLight(2)}})
+		c.Assert(err, IsNil)
+		if addr != 0x0003 {
+			light.AddGroup(5, 2)
+		}
+	}
+	f, _ := frame.New().FrameType(frame.FrameTypeLocal).Direction(frame.DirectionClientServer).CommandId(0x01).Command(&cluster.OnCommand{}).Build()
+	m := &zcl.ZclOutgoingMessage{DstAddrMode: zcl.AddressModeGroup, SrcEndpoint: 1, ClusterID: uint16(cluster.OnOff), ProfileID: HomeAutomationProfile, Frame: f}
+	am := m.ToApplicationMessage()
+	am.GroupID = 5
+	c.Assert(s.network.Send(context.Background(), am), IsNil)
+	c.Assert(s.network.Pending(), Equals, 0)
+
+	states := []interface{}{}
+	for _, d := range s.network.sortedDevices() {
+		for _, endpoint := range []uint8{1, 2} {
+			on, _ := d.Get(endpoint, cluster.OnOff, onOffAttribute)
+			states = append(states, on)
+		}
+	}
+	c.Assert(states, DeepEquals, []interface{}{false, true, false, true, false, false})
+}
+
+func (s *SimulatorSuite) TestUnsupported(c *C) {
+	_, err := s.network.AddDevice(0x3c4d, &Profile{Endpoints: []*Endpoint{TemperatureSensor(1, func(time.Time) float64 { return 20 })}})
+	c.Assert(err, IsNil)
+	_, err = s.network.AddDevice(0x3c4d, &Profile{})
+	c.Assert(err, ErrorMatches, "0x3c4d is already taken")
+
+	c.Assert(s.send(c, 0x3c4d, frame.FrameTypeLocal, cluster.OnOff, 0x01, &cluster.OnCommand{}), IsNil)
+	c.Assert(s.defaultResponse(c), Equals, cluster.ZclStatusUnsupClusterCommand)
+	c.Assert(s.send(c, 0x1a2b, frame.FrameTypeLocal, cluster.OnOff, 0x01, &cluster.OnCommand{}), ErrorMatches, "no device at 0x1a2b")
+
+	_, err = s.network.AddDevice(0x1a2b, &Profile{Endpoints: []*Endpoint{{
+		Id:         1,
+		Clusters:   []cluster.ClusterId{cluster.OnOff},
+		Attributes: []*Attribute{{cluster.OnOff, globalSceneControlAttribute, true}},
+		Reporting:  []*Reporting{{cluster.OnOff, globalSceneControlAttribute, 0, 60, nil}},
+	}}})
+	c.Assert(err, ErrorMatches, "reporting of attribute 16384 of cluster 6: UnreportableAttribute")
+
+	_, err = s.network.AddDevice(0x5e6f, &Profile{Endpoints: []*Endpoint{{
+		Id:        1,
+		Clusters:  []cluster.ClusterId{cluster.OnOff},
+		Reporting: []*Reporting{{0xfc00, 0x0000, 0, 60, nil}},
+	}}})
+	c.Assert(err, ErrorMatches, "unknown cluster 64512")
+}
+
+func (s *SimulatorSuite) TestHandler(c *C) {
+	var received []interface{}
+	light, err := s.network.AddDevice(0x1a2b, &Profile{Endpoints: []*Endpoint{OnOffLight(1)}})
+	c.Assert(err, IsNil)
+	light.Handle(cluster.OnOff, func(endpoint uint8, command interface{}) cluster.ZclStatus {
+		received = append(received, command)
+		return cluster.ZclStatusFailure
+	})
+	c.Assert(s.send(c, 0x1a2b, frame.FrameTypeLocal, cluster.OnOff, 0x01, &cluster.OnCommand{}), IsNil)
+	c.Assert(s.defaultResponse(c), Equals, cluster.ZclStatusFailure)
+	c.Assert(received, DeepEquals, []interface{}{&cluster.OnCommand{}})
+}
+
+func (s *SimulatorSuite) TestTransactor(c *C) {
+	_, err := s.network.AddDevice(0x1a2b, &Profile{Endpoints: []*Endpoint{DimmableLight(1)}})
+	c.Assert(err, IsNil)
+	transactor := zcl.NewTransactor(s.network, s.zcl.ClusterLibrary(), time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the suite's fields are replaced by the next test while the loop may still be running
+	network, z := s.network, s.zcl
+	go func() {
+		for {
+			am, err := network.Next(ctx)
+			if err != nil {
+				return
+			}
+			if im, err := z.ToZclIncomingMessage(am); err == nil {
+				transactor.Dispatch(im)
+			}
+		}
+	}()
+	f, _ := frame.New().
+		FrameType(frame.FrameTypeGlobal).
+		Direction(frame.DirectionClientServer).
+		CommandId(uint8(cluster.ZclCommandReadAttributes)).
+		Command(&cluster.ReadAttributesCommand{AttributeIDs: []uint16{currentLevelAttribute}}).
+		Build()
+	im, err := transactor.Do(ctx, &zcl.ZclOutgoingMessage{
+		DstAddrMode: zcl.AddressModeNwk, DstAddr: "0x1A2B", DstEndpoint: 1, SrcEndpoint: 1,
+		ClusterID: uint16(cluster.LevelControl), ProfileID: HomeAutomationProfile, Frame: f,
+	})
+	c.Assert(err, IsNil)
+	response := im.Data.Command.(*cluster.ReadAttributesResponse)
+	c.Assert(response.ReadAttributeStatuses[0].Attribute.Value, Equals, uint64(maxLevel))
+}
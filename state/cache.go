@@ -0,0 +1,247 @@
+package state
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+type Key struct {
+	Addr        string            `json:"addr"`
+	Endpoint    uint8             `json:"endpoint"`
+	ClusterId   cluster.ClusterId `json:"clusterId"`
+	AttributeId uint16            `json:"attributeId"`
+}
+
+// Value is the last known value of an attribute together with the frame it arrived in.
+type Value struct {
+	AttributeName             string
+	Attribute                 *cluster.Attribute
+	Timestamp                 time.Time
+	LinkQuality               uint8
+	TransactionSequenceNumber uint8
+}
+
+// Change is passed to subscribers when an attribute gets a different value. Old is nil the first
+// time the attribute is seen.
+type Change struct {
+	Key Key
+	Old *Value
+	New *Value
+}
+
+type expectation struct {
+	maxInterval time.Duration
+	since       time.Time
+}
+
+// Cache keeps the last known attribute values of remote devices per device, endpoint and cluster.
+type Cache struct {
+	mutex        sync.RWMutex
+	clock        zcl.Clock
+	values       map[Key]*Value
+	expectations map[Key]*expectation
+	nextId       int
+	subscribers  map[int]func(*Change)
+}
+
+func NewCache() *Cache {
+	return &Cache{
+		clock:        zcl.SystemClock{},
+		values:       map[Key]*Value{},
+		expectations: map[Key]*expectation{},
+		subscribers:  map[int]func(*Change){},
+	}
+}
+
+func (c *Cache) Clock(clock zcl.Clock) *Cache {
+	c.clock = clock
+	return c
+}
+
+// Ingest stores the attributes carried by a ReportAttributes command or a ReadAttributes
+// response. It returns false for any other message.
+func (c *Cache) Ingest(im *zcl.ZclIncomingMessage) bool {
+	if im.Data == nil {
+		return false
+	}
+	base := Value{
+		Timestamp:                 c.clock.Now(),
+		LinkQuality:               im.LinkQuality,
+		TransactionSequenceNumber: im.Data.TransactionSequenceNumber,
+	}
+	key := Key{Addr: normalizeAddr(im.SrcAddr), Endpoint: im.SrcEndpoint, ClusterId: cluster.ClusterId(im.ClusterID)}
+	updates := map[Key]*Value{}
+	switch cmd := im.Data.Command.(type) {
+	case *cluster.ReportAttributesCommand:
+		for _, report := range cmd.AttributeReports {
+			if report.Attribute == nil {
+				continue
+			}
+			key.AttributeId = report.AttributeID
+			value := base
+			value.AttributeName = report.AttributeName
+			value.Attribute = report.Attribute
+			updates[key] = &value
+		}
+	case *cluster.ReadAttributesResponse:
+		for _, status := range cmd.ReadAttributeStatuses {
+			if status.Status != cluster.ZclStatusSuccess || status.Attribute == nil {
+				continue
+			}
+			key.AttributeId = status.AttributeID
+			value := base
+			value.AttributeName = status.AttributeName
+			value.Attribute = status.Attribute
+			updates[key] = &value
+		}
+	default:
+		return false
+	}
+	c.update(updates)
+	return true
+}
+
+// Set stores a value directly, e.g. one obtained outside of ZCL frames.
+func (c *Cache) Set(key Key, value *Value) {
+	key.Addr = normalizeAddr(key.Addr)
+	c.update(map[Key]*Value{key: value})
+}
+
+func (c *Cache) update(updates map[Key]*Value) {
+	changes := []*Change{}
+	c.mutex.Lock()
+	for key, value := range updates {
+		old := c.values[key]
+		c.values[key] = value
+		if old == nil || !reflect.DeepEqual(old.Attribute, value.Attribute) {
+			changes = append(changes, &Change{Key: key, Old: old, New: value})
+		}
+	}
+	subscribers := make([]func(*Change), 0, len(c.subscribers))
+	ids := make([]int, 0, len(c.subscribers))
+	for id := range c.subscribers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		subscribers = append(subscribers, c.subscribers[id])
+	}
+	c.mutex.Unlock()
+
+	sort.Slice(changes, func(i, j int) bool { return lessKey(changes[i].Key, changes[j].Key) })
+	for _, change := range changes {
+		for _, subscriber := range subscribers {
+			subscriber(change)
+		}
+	}
+}
+
+func (c *Cache) Get(key Key) (*Value, bool) {
+	key.Addr = normalizeAddr(key.Addr)
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// Cluster returns the known values of a cluster on a device endpoint by attribute id.
+func (c *Cache) Cluster(addr string, endpoint uint8, clusterId cluster.ClusterId) map[uint16]*Value {
+	addr = normalizeAddr(addr)
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	values := map[uint16]*Value{}
+	for key, value := range c.values {
+		if key.Addr == addr && key.Endpoint == endpoint && key.ClusterId == clusterId {
+			values[key.AttributeId] = value
+		}
+	}
+	return values
+}
+
+// Forget drops every value and expectation of a device.
+func (c *Cache) Forget(addr string) {
+	addr = normalizeAddr(addr)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key := range c.values {
+		if key.Addr == addr {
+			delete(c.values, key)
+		}
+	}
+	for key := range c.expectations {
+		if key.Addr == addr {
+			delete(c.expectations, key)
+		}
+	}
+}
+
+// Subscribe registers a function called with every change. Calls happen on the goroutine
+// ingesting the frame. The returned function cancels the subscription.
+func (c *Cache) Subscribe(subscriber func(*Change)) func() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	id := c.nextId
+	c.nextId++
+	c.subscribers[id] = subscriber
+	return func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		delete(c.subscribers, id)
+	}
+}
+
+// ExpectReports marks the attribute as reported at least every maxInterval, usually the maximum
+// reporting interval it was configured with. A zero interval removes the expectation.
+func (c *Cache) ExpectReports(key Key, maxInterval time.Duration) {
+	key.Addr = normalizeAddr(key.Addr)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if maxInterval <= 0 {
+		delete(c.expectations, key)
+		return
+	}
+	c.expectations[key] = &expectation{maxInterval: maxInterval, since: c.clock.Now()}
+}
+
+// Stale returns the attributes expected to be reported whose last value, or the expectation
+// itself if no value arrived yet, is older than their maximum interval.
+func (c *Cache) Stale() []Key {
+	now := c.clock.Now()
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	stale := []Key{}
+	for key, e := range c.expectations {
+		last := e.since
+		if value, ok := c.values[key]; ok && value.Timestamp.After(last) {
+			last = value.Timestamp
+		}
+		if now.Sub(last) > e.maxInterval {
+			stale = append(stale, key)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return lessKey(stale[i], stale[j]) })
+	return stale
+}
+
+func lessKey(a, b Key) bool {
+	if a.Addr != b.Addr {
+		return a.Addr < b.Addr
+	}
+	if a.Endpoint != b.Endpoint {
+		return a.Endpoint < b.Endpoint
+	}
+	if a.ClusterId != b.ClusterId {
+		return a.ClusterId < b.ClusterId
+	}
+	return a.AttributeId < b.AttributeId
+}
+
+func normalizeAddr(addr string) string {
+	return strings.ToLower(addr)
+}
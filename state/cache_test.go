@@ -0,0 +1,134 @@
+package state
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/internal/clocktest"
+	. "gopkg.in/check.v1"
+)
+
+func TestState(t *testing.T) { TestingT(t) }
+
+type CacheSuite struct {
+	zcl   *zcl.Zcl
+	clock *clocktest.Clock
+	cache *Cache
+}
+
+var _ = Suite(&CacheSuite{})
+
+func (s *CacheSuite) SetUpTest(c *C) {
+	s.zcl = zcl.New()
+	s.clock = clocktest.New(time.Unix(1500000000, 0).UTC())
+	s.cache = NewCache().Clock(s.clock)
+}
+
+func (s *CacheSuite) ingest(c *C, clusterId cluster.ClusterId, data []uint8) {
+	im, err := s.zcl.ToZclIncomingMessage(&zcl.ApplicationMessage{
+		SrcAddr: "0xABCD", SrcEndpoint: 1, DstEndpoint: 1, ClusterID: uint16(clusterId), LinkQuality: 120, Data: data,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.cache.Ingest(im), Equals, true)
+}
+
+var onOffKey = Key{Addr: "0xabcd", Endpoint: 1, ClusterId: cluster.OnOff, AttributeId: 0x0000}
+
+func (s *CacheSuite) TestIngestReport(c *C) {
+	s.ingest(c, cluster.OnOff, []uint8{0x18, 0x05, 0x0a, 0x00, 0x00, 0x10, 0x01})
+	value, ok := s.cache.Get(Key{Addr: "0xABCD", Endpoint: 1, ClusterId: cluster.OnOff, AttributeId: 0x0000})
+	c.Assert(ok, Equals, true)
+	c.Assert(value, DeepEquals, &Value{
+		AttributeName:             "OnOff",
+		Attribute:                 &cluster.Attribute{DataType: cluster.ZclDataTypeBoolean, Value: true},
+		Timestamp:                 s.clock.Now(),
+		LinkQuality:               120,
+		TransactionSequenceNumber: 5,
+	})
+}
+
+func (s *CacheSuite) TestIngestReadAttributesResponse(c *C) {
+	s.ingest(c, cluster.Basic, []uint8{0x18, 0x06, 0x01,
+		0x04, 0x00, 0x00, 0x42, 0x04, 'I', 'K', 'E', 'A',
+		0x05, 0x00, 0x86})
+	values := s.cache.Cluster("0xabcd", 1, cluster.Basic)
+	c.Assert(values, HasLen, 1)
+	c.Assert(values[0x0004].AttributeName, Equals, "ManufacturerName")
+	c.Assert(values[0x0004].Attribute.Value, Equals, "IKEA")
+}
+
+func (s *CacheSuite) TestIgnoresOtherCommands(c *C) {
+	im, _ := s.zcl.ToZclIncomingMessage(&zcl.ApplicationMessage{SrcAddr: "0xabcd", ClusterID: uint16(cluster.OnOff), Data: []uint8{0x01, 0x01, 0x01}})
+	c.Assert(s.cache.Ingest(im), Equals, false)
+}
+
+func (s *CacheSuite) TestSubscribe(c *C) {
+	changes := []*Change{}
+	unsubscribe := s.cache.Subscribe(func(change *Change) { changes = append(changes, change) })
+	s.ingest(c, cluster.OnOff, []uint8{0x18, 0x05, 0x0a, 0x00, 0x00, 0x10, 0x01})
+	s.ingest(c, cluster.OnOff, []uint8{0x18, 0x06, 0x0a, 0x00, 0x00, 0x10, 0x01})
+	s.ingest(c, cluster.OnOff, []uint8{0x18, 0x07, 0x0a, 0x00, 0x00, 0x10, 0x00})
+	c.Assert(changes, HasLen, 2)
+	c.Assert(changes[0].Old, IsNil)
+	c.Assert(changes[1].Key, Equals, onOffKey)
+	c.Assert(changes[1].Old.Attribute.Value, Equals, true)
+	c.Assert(changes[1].New.Attribute.Value, Equals, false)
+	unsubscribe()
+	s.ingest(c, cluster.OnOff, []uint8{0x18, 0x08, 0x0a, 0x00, 0x00, 0x10, 0x01})
+	c.Assert(changes, HasLen, 2)
+}
+
+func (s *CacheSuite) TestStale(c *C) {
+	s.cache.ExpectReports(onOffKey, time.Minute)
+	s.clock.Advance(time.Minute)
+	c.Assert(s.cache.Stale(), HasLen, 0)
+	s.clock.Advance(time.Second)
+	c.Assert(s.cache.Stale(), DeepEquals, []Key{onOffKey})
+	s.ingest(c, cluster.OnOff, []uint8{0x18, 0x05, 0x0a, 0x00, 0x00, 0x10, 0x01})
+	c.Assert(s.cache.Stale(), HasLen, 0)
+	s.clock.Advance(2 * time.Minute)
+	c.Assert(s.cache.Stale(), DeepEquals, []Key{onOffKey})
+	s.cache.Forget("0xABCD")
+	c.Assert(s.cache.Stale(), HasLen, 0)
+}
+
+func (s *CacheSuite) TestSnapshotRestore(c *C) {
+	s.ingest(c, cluster.OnOff, []uint8{0x18, 0x05, 0x0a, 0x00, 0x00, 0x10, 0x01})
+	s.ingest(c, cluster.TemperatureMeasurement, []uint8{0x18, 0x06, 0x0a, 0x00, 0x00, 0x29, 0x0c, 0xfe})
+	s.cache.ExpectReports(onOffKey, time.Hour)
+	buf := &bytes.Buffer{}
+	c.Assert(s.cache.Snapshot(buf), IsNil)
+
+	restored := NewCache().Clock(s.clock)
+	c.Assert(restored.Restore(bytes.NewReader(buf.Bytes())), IsNil)
+	c.Assert(restored.values, DeepEquals, s.cache.values)
+	c.Assert(restored.expectations, DeepEquals, s.cache.expectations)
+	value, _ := restored.Get(Key{Addr: "0xabcd", Endpoint: 1, ClusterId: cluster.TemperatureMeasurement})
+	c.Assert(value.Attribute.Value, Equals, int64(-500))
+	c.Assert(buf.String(), Matches, `(?s).*"addr": "0xabcd",\s+"endpoint": 1,\s+"clusterId": 1026,\s+"attributeId": 0,.*`)
+}
+
+func (s *CacheSuite) TestSnapshotMismatchedValue(c *C) {
+	s.cache.Set(Key{Addr: "0xabcd", Endpoint: 1, ClusterId: 0xfcc0, AttributeId: 0x00f7},
+		&Value{Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeOctetStr, Value: []string{"decoded"}}})
+	c.Assert(s.cache.Snapshot(&bytes.Buffer{}), ErrorMatches, "attribute 0x00f7 of cluster 0xfcc0 of 0xabcd endpoint 1 has no valid value")
+}
+
+func (s *CacheSuite) TestSaveLoadFile(c *C) {
+	dir, err := ioutil.TempDir("", "state")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache.json")
+	s.ingest(c, cluster.OnOff, []uint8{0x18, 0x05, 0x0a, 0x00, 0x00, 0x10, 0x01})
+	c.Assert(s.cache.SaveFile(path), IsNil)
+	restored := NewCache()
+	c.Assert(restored.LoadFile(path), IsNil)
+	c.Assert(restored.values, DeepEquals, s.cache.values)
+	c.Assert(restored.Restore(bytes.NewReader([]byte(`{"version":2}`))), ErrorMatches, "unsupported snapshot version 2")
+}
@@ -0,0 +1,129 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+const snapshotVersion = 1
+
+type snapshot struct {
+	Version      int                    `json:"version"`
+	Values       []*snapshotValue       `json:"values"`
+	Expectations []*snapshotExpectation `json:"expectations"`
+}
+
+// snapshotValue keeps the attribute in its JSON form, which preserves the data type and the exact
+// Go type of values matching it.
+type snapshotValue struct {
+	Key
+	AttributeName             string             `json:"attributeName"`
+	Attribute                 *cluster.Attribute `json:"attribute"`
+	Timestamp                 time.Time          `json:"timestamp"`
+	LinkQuality               uint8              `json:"linkQuality"`
+	TransactionSequenceNumber uint8              `json:"transactionSequenceNumber"`
+}
+
+type snapshotExpectation struct {
+	Key
+	MaxInterval time.Duration `json:"maxInterval"`
+	Since       time.Time     `json:"since"`
+}
+
+// Snapshot writes every value and expectation of the cache as JSON. It fails if a value doesn't
+// match its data type, e.g. one replaced by a quirk, as it couldn't be restored.
+func (c *Cache) Snapshot(w io.Writer) error {
+	c.mutex.RLock()
+	s := &snapshot{Version: snapshotVersion, Values: []*snapshotValue{}, Expectations: []*snapshotExpectation{}}
+	for key, value := range c.values {
+		if value.Attribute == nil || !cluster.ValidAttributeValue(value.Attribute.DataType, value.Attribute.Value) {
+			c.mutex.RUnlock()
+			return fmt.Errorf("attribute 0x%04x of cluster %s of %s endpoint %d has no valid value", key.AttributeId, key.ClusterId, key.Addr, key.Endpoint)
+		}
+		s.Values = append(s.Values, &snapshotValue{
+			Key:                       key,
+			AttributeName:             value.AttributeName,
+			Attribute:                 value.Attribute,
+			Timestamp:                 value.Timestamp,
+			LinkQuality:               value.LinkQuality,
+			TransactionSequenceNumber: value.TransactionSequenceNumber,
+		})
+	}
+	for key, e := range c.expectations {
+		s.Expectations = append(s.Expectations, &snapshotExpectation{Key: key, MaxInterval: e.maxInterval, Since: e.since})
+	}
+	c.mutex.RUnlock()
+	sortSnapshot(s)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// Restore replaces the content of the cache with a snapshot. Subscribers aren't notified.
+func (c *Cache) Restore(r io.Reader) error {
+	s := &snapshot{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return err
+	}
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	values := map[Key]*Value{}
+	for _, v := range s.Values {
+		values[v.Key] = &Value{
+			AttributeName:             v.AttributeName,
+			Attribute:                 v.Attribute,
+			Timestamp:                 v.Timestamp,
+			LinkQuality:               v.LinkQuality,
+			TransactionSequenceNumber: v.TransactionSequenceNumber,
+		}
+	}
+	expectations := map[Key]*expectation{}
+	for _, e := range s.Expectations {
+		expectations[e.Key] = &expectation{maxInterval: e.MaxInterval, since: e.Since}
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.values = values
+	c.expectations = expectations
+	return nil
+}
+
+// SaveFile writes a snapshot to the file, replacing it only once the snapshot is complete.
+func (c *Cache) SaveFile(path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := c.Snapshot(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (c *Cache) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.Restore(f)
+}
+
+func sortSnapshot(s *snapshot) {
+	sort.Slice(s.Values, func(i, j int) bool { return lessKey(s.Values[i].Key, s.Values[j].Key) })
+	sort.Slice(s.Expectations, func(i, j int) bool { return lessKey(s.Expectations[i].Key, s.Expectations[j].Key) })
+}
@@ -0,0 +1,304 @@
+package zcl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+var ErrTransactionInFlight = errors.New("transaction with the same sequence number is already in flight")
+
+type ZclOutgoingMessage struct {
+	DstAddrMode AddressMode
+	DstAddr     string
+	DstEndpoint uint8
+	SrcEndpoint uint8
+	ClusterID   uint16
+	ProfileID   uint16
+	Frame       *frame.Frame
+}
+
+type Transport interface {
+	Send(ctx context.Context, message *ApplicationMessage) error
+}
+
+func (m *ZclOutgoingMessage) ToApplicationMessage() *ApplicationMessage {
+	am := &ApplicationMessage{}
+	am.DstAddrMode = m.DstAddrMode
+	am.DstAddr = m.DstAddr
+	am.DstEndpoint = m.DstEndpoint
+	am.SrcEndpoint = m.SrcEndpoint
+	am.ClusterID = m.ClusterID
+	am.ProfileID = m.ProfileID
+	am.TransactionSeqNumber = m.Frame.TransactionSequenceNumber
+	am.Data = frame.Encode(m.Frame)
+	return am
+}
+
+type transactionKey struct {
+	addr      string
+	endpoint  uint8
+	clusterId uint16
+	tsn       uint8
+}
+
+type Transaction struct {
+	key        transactionKey
+	dstAddr    string
+	frameType  frame.FrameType
+	direction  frame.Direction
+	commandId  uint8
+	responseId *uint8
+	allocator  *frame.TransactionIdAllocator
+	done       chan struct{}
+	once       sync.Once
+	response   *ZclIncomingMessage
+	err        error
+}
+
+type Transactor struct {
+	transport Transport
+	library   *cluster.ClusterLibrary
+	timeout   time.Duration
+	allocator *frame.TransactionIdAllocator
+	mutex     sync.Mutex
+	pending   map[transactionKey]*Transaction
+}
+
+func NewTransactor(transport Transport, library *cluster.ClusterLibrary, timeout time.Duration) *Transactor {
+	return &Transactor{
+		transport: transport,
+		library:   library,
+		timeout:   timeout,
+		pending:   map[transactionKey]*Transaction{},
+	}
+}
+
+// IdAllocator makes the transactor allocate the sequence number of every request for its
+// destination, replacing the one the frame was built with, and release it once the transaction
+// completes. Requests fail with frame.ErrNoFreeTransactionId while all ids are in flight.
+func (t *Transactor) IdAllocator(allocator *frame.TransactionIdAllocator) *Transactor {
+	t.allocator = allocator
+	return t
+}
+
+// Request sends the message and returns a transaction which completes on the matching specific
+// response or DefaultResponse. A transaction that expects no reply completes as soon as it is sent.
+func (t *Transactor) Request(ctx context.Context, message *ZclOutgoingMessage) (*Transaction, error) {
+	message, allocator, err := t.allocate(message)
+	if err != nil {
+		return nil, err
+	}
+	f := message.Frame
+	key := transactionKey{normalizeAddr(message.DstAddr), message.DstEndpoint, message.ClusterID, f.TransactionSequenceNumber}
+	tx := &Transaction{
+		key:       key,
+		dstAddr:   normalizeAddr(message.DstAddr),
+		allocator: allocator,
+		frameType: f.FrameControl.FrameType,
+		direction: f.FrameControl.Direction,
+		commandId: f.CommandIdentifier,
+		done:      make(chan struct{}),
+	}
+	tx.responseId = t.responseId(message.ClusterID, f)
+	if tx.responseId == nil && f.FrameControl.DisableDefaultResponse == 1 {
+		err := t.transport.Send(ctx, message.ToApplicationMessage())
+		t.release(tx)
+		if err != nil {
+			return nil, err
+		}
+		tx.complete(nil, nil)
+		return tx, nil
+	}
+
+	t.mutex.Lock()
+	if _, ok := t.pending[key]; ok {
+		t.mutex.Unlock()
+		t.release(tx)
+		return nil, ErrTransactionInFlight
+	}
+	t.pending[key] = tx
+	t.mutex.Unlock()
+
+	if err := t.transport.Send(ctx, message.ToApplicationMessage()); err != nil {
+		if t.remove(key, tx) {
+			t.release(tx)
+		}
+		return nil, err
+	}
+
+	go t.expire(ctx, key, tx)
+	return tx, nil
+}
+
+// Do sends the message and blocks until the transaction completes, the context is done or the timeout elapses.
+func (t *Transactor) Do(ctx context.Context, message *ZclOutgoingMessage) (*ZclIncomingMessage, error) {
+	tx, err := t.Request(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	<-tx.Done()
+	return tx.Result()
+}
+
+// Dispatch completes the pending transaction matching the incoming message. It returns false if
+// the message isn't a reply to any in-flight request.
+func (t *Transactor) Dispatch(im *ZclIncomingMessage) bool {
+	if im.Data == nil || im.Data.FrameControl == nil {
+		return false
+	}
+	key := transactionKey{normalizeAddr(im.SrcAddr), im.SrcEndpoint, im.ClusterID, im.Data.TransactionSequenceNumber}
+	t.mutex.Lock()
+	tx, ok := t.pending[key]
+	if !ok || !tx.matches(im.Data) {
+		t.mutex.Unlock()
+		return false
+	}
+	delete(t.pending, key)
+	t.mutex.Unlock()
+	t.release(tx)
+	tx.complete(im, nil)
+	return true
+}
+
+func (t *Transactor) Pending() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.pending)
+}
+
+func (t *Transactor) expire(ctx context.Context, key transactionKey, tx *Transaction) {
+	var timeout <-chan time.Time
+	if t.timeout > 0 {
+		timer := time.NewTimer(t.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-tx.done:
+	case <-ctx.Done():
+		if t.remove(key, tx) {
+			t.release(tx)
+			tx.complete(nil, ctx.Err())
+		}
+	case <-timeout:
+		if t.remove(key, tx) {
+			t.release(tx)
+			tx.complete(nil, context.DeadlineExceeded)
+		}
+	}
+}
+
+func (t *Transactor) remove(key transactionKey, tx *Transaction) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.pending[key] == tx {
+		delete(t.pending, key)
+		return true
+	}
+	return false
+}
+
+// allocate returns a copy of the message carrying a sequence number from the allocator, if any,
+// together with the allocator to release it to.
+func (t *Transactor) allocate(message *ZclOutgoingMessage) (*ZclOutgoingMessage, *frame.TransactionIdAllocator, error) {
+	allocator := t.allocator
+	if allocator == nil {
+		return message, nil, nil
+	}
+	id, err := allocator.Allocate(normalizeAddr(message.DstAddr))
+	if err != nil {
+		return nil, nil, err
+	}
+	f := *message.Frame
+	f.TransactionSequenceNumber = id
+	m := *message
+	m.Frame = &f
+	return &m, allocator, nil
+}
+
+// release frees the sequence number of the transaction if it was allocated by the transactor.
+func (t *Transactor) release(tx *Transaction) {
+	if tx.allocator != nil {
+		tx.allocator.Release(tx.dstAddr, tx.key.tsn)
+	}
+}
+
+func (t *Transactor) responseId(clusterId uint16, f *frame.Frame) *uint8 {
+	var cd *cluster.CommandDescriptor
+	var ok bool
+	switch f.FrameControl.FrameType {
+	case frame.FrameTypeGlobal:
+		cd, ok = t.library.Global()[f.CommandIdentifier]
+	case frame.FrameTypeLocal:
+		var c *cluster.Cluster
+		if c, ok = t.library.Clusters()[cluster.ClusterId(clusterId)]; ok && c.CommandDescriptors != nil {
+			switch f.FrameControl.Direction {
+			case frame.DirectionClientServer:
+				cd, ok = c.CommandDescriptors.Received[f.CommandIdentifier]
+			case frame.DirectionServerClient:
+				cd, ok = c.CommandDescriptors.Generated[f.CommandIdentifier]
+			}
+		} else {
+			ok = false
+		}
+	}
+	if !ok {
+		return nil
+	}
+	return cd.Response
+}
+
+func (tx *Transaction) matches(f *ZclFrame) bool {
+	if f.FrameControl.Direction == tx.direction {
+		return false
+	}
+	if f.FrameControl.FrameType == frame.FrameTypeGlobal && f.CommandIdentifier == uint8(cluster.ZclCommandDefaultResponse) {
+		if cmd, ok := f.Command.(*cluster.DefaultResponseCommand); ok {
+			return cmd.CommandID == tx.commandId
+		}
+		return false
+	}
+	return tx.responseId != nil && f.FrameControl.FrameType == tx.frameType && f.CommandIdentifier == *tx.responseId
+}
+
+func (tx *Transaction) complete(response *ZclIncomingMessage, err error) {
+	tx.once.Do(func() {
+		tx.response = response
+		tx.err = err
+		close(tx.done)
+	})
+}
+
+func (tx *Transaction) Done() <-chan struct{} {
+	return tx.done
+}
+
+// Result returns the response once the transaction is done. A DefaultResponse carrying a failure
+// status is returned together with an error.
+func (tx *Transaction) Result() (*ZclIncomingMessage, error) {
+	select {
+	case <-tx.done:
+	default:
+		return nil, errors.New("transaction is not complete")
+	}
+	if tx.err != nil {
+		return nil, tx.err
+	}
+	if tx.response != nil {
+		if cmd, ok := tx.response.Data.Command.(*cluster.DefaultResponseCommand); ok && cmd.Status != cluster.ZclStatusSuccess {
+			return tx.response, fmt.Errorf("command %d failed: %w", cmd.CommandID, cmd.Status)
+		}
+	}
+	return tx.response, nil
+}
+
+func normalizeAddr(addr string) string {
+	return strings.ToLower(addr)
+}
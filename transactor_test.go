@@ -0,0 +1,183 @@
+package zcl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	. "gopkg.in/check.v1"
+)
+
+func TestZcl(t *testing.T) { TestingT(t) }
+
+type loopbackTransport struct {
+	zcl        *Zcl
+	transactor *Transactor
+	responder  func(request *frame.Frame) *frame.Frame
+	sent       []*ApplicationMessage
+}
+
+func (l *loopbackTransport) Send(ctx context.Context, m *ApplicationMessage) error {
+	l.sent = append(l.sent, m)
+	if l.responder == nil {
+		return nil
+	}
+	if reply := l.responder(frame.Decode(m.Data)); reply != nil {
+		im, err := l.zcl.ToZclIncomingMessage(&ApplicationMessage{
+			SrcAddr:     m.DstAddr,
+			SrcEndpoint: m.DstEndpoint,
+			DstEndpoint: m.SrcEndpoint,
+			ClusterID:   m.ClusterID,
+			ProfileID:   m.ProfileID,
+			Data:        frame.Encode(reply),
+		})
+		if err != nil {
+			return err
+		}
+		go l.transactor.Dispatch(im)
+	}
+	return nil
+}
+
+type TransactorSuite struct {
+	transport  *loopbackTransport
+	transactor *Transactor
+}
+
+var _ = Suite(&TransactorSuite{})
+
+func (s *TransactorSuite) SetUpTest(c *C) {
+	z := New()
+	s.transport = &loopbackTransport{zcl: z}
+	s.transactor = NewTransactor(s.transport, z.ClusterLibrary(), time.Second)
+	s.transport.transactor = s.transactor
+}
+
+func viewGroupRequest(c *C, tsn uint8) *ZclOutgoingMessage {
+	return viewGroupRequestWithIds(c, func() uint8 { return tsn })
+}
+
+func viewGroupRequestWithIds(c *C, provider func() uint8) *ZclOutgoingMessage {
+	f, err := frame.New().
+		IdGenerator(provider).
+		FrameType(frame.FrameTypeLocal).
+		Direction(frame.DirectionClientServer).
+		CommandId(0x01).
+		Command(&cluster.ViewGroupCommand{GroupID: 5}).
+		Build()
+	c.Assert(err, IsNil)
+	return &ZclOutgoingMessage{DstAddr: "0x1234", DstEndpoint: 1, SrcEndpoint: 1, ClusterID: uint16(cluster.Groups), Frame: f}
+}
+
+func reply(request *frame.Frame, frameType frame.FrameType, commandId uint8, command interface{}) *frame.Frame {
+	f, _ := frame.New().
+		IdGenerator(func() uint8 { return request.TransactionSequenceNumber }).
+		FrameType(frameType).
+		Direction(frame.DirectionServerClient).
+		CommandId(commandId).
+		Command(command).
+		Build()
+	return f
+}
+
+func (s *TransactorSuite) TestSpecificResponse(c *C) {
+	s.transport.responder = func(request *frame.Frame) *frame.Frame {
+		return reply(request, frame.FrameTypeLocal, 0x01, &cluster.ViewGroupResponse{Status: 0, GroupID: 5, GroupName: "kitchen"})
+	}
+	im, err := s.transactor.Do(context.Background(), viewGroupRequest(c, 10))
+	c.Assert(err, IsNil)
+	c.Assert(im.Data.CommandName, Equals, "ViewGroupResponse")
+	c.Assert(im.Data.Command, DeepEquals, &cluster.ViewGroupResponse{Status: 0, GroupID: 5, GroupName: "kitchen"})
+	c.Assert(s.transactor.Pending(), Equals, 0)
+}
+
+func (s *TransactorSuite) TestDefaultResponse(c *C) {
+	s.transport.responder = func(request *frame.Frame) *frame.Frame {
+		return reply(request, frame.FrameTypeGlobal, 0x0b, &cluster.DefaultResponseCommand{CommandID: 0x01, Status: cluster.ZclStatusUnsupClusterCommand})
+	}
+	im, err := s.transactor.Do(context.Background(), viewGroupRequest(c, 11))
+	c.Assert(errors.Is(err, cluster.ZclStatusUnsupClusterCommand), Equals, true)
+	c.Assert(err, ErrorMatches, "command 1 failed: UnsupClusterCommand")
+	c.Assert(im.Data.CommandName, Equals, "DefaultResponse")
+}
+
+func (s *TransactorSuite) TestUnrelatedReplyIgnored(c *C) {
+	s.transport.responder = func(request *frame.Frame) *frame.Frame {
+		return reply(request, frame.FrameTypeLocal, 0x00, &cluster.AddGroupResponse{})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := s.transactor.Do(ctx, viewGroupRequest(c, 12))
+	c.Assert(err, Equals, context.DeadlineExceeded)
+	c.Assert(s.transactor.Pending(), Equals, 0)
+}
+
+func (s *TransactorSuite) TestCancel(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err := s.transactor.Request(ctx, viewGroupRequest(c, 13))
+	c.Assert(err, IsNil)
+	_, err = s.transactor.Request(ctx, viewGroupRequest(c, 13))
+	c.Assert(err, Equals, ErrTransactionInFlight)
+	cancel()
+	<-tx.Done()
+	_, err = tx.Result()
+	c.Assert(err, Equals, context.Canceled)
+}
+
+func (s *TransactorSuite) TestNoResponseExpected(c *C) {
+	f, _ := frame.New().
+		FrameType(frame.FrameTypeLocal).
+		Direction(frame.DirectionClientServer).
+		DisableDefaultResponse(true).
+		CommandId(0x01).
+		Command(&cluster.OnCommand{}).
+		Build()
+	tx, err := s.transactor.Request(context.Background(), &ZclOutgoingMessage{DstAddr: "0x1234", DstEndpoint: 1, ClusterID: uint16(cluster.OnOff), Frame: f})
+	c.Assert(err, IsNil)
+	<-tx.Done()
+	im, err := tx.Result()
+	c.Assert(im, IsNil)
+	c.Assert(err, IsNil)
+	c.Assert(s.transport.sent, HasLen, 1)
+}
+
+func (s *TransactorSuite) TestReleasesAllocatedIds(c *C) {
+	allocator := frame.NewTransactionIdAllocator()
+	s.transactor.IdAllocator(allocator)
+	s.transport.responder = func(request *frame.Frame) *frame.Frame {
+		return reply(request, frame.FrameTypeLocal, 0x01, &cluster.ViewGroupResponse{GroupID: 5})
+	}
+	for i := 0; i < 300; i++ {
+		_, err := s.transactor.Do(context.Background(), viewGroupRequest(c, 10))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(allocator.InFlight("0x1234"), Equals, 0)
+}
+
+func (s *TransactorSuite) TestNoFreeIds(c *C) {
+	allocator := frame.NewTransactionIdAllocator()
+	s.transactor.IdAllocator(allocator)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	seen := map[uint8]bool{}
+	txs := []*Transaction{}
+	for i := 0; i < 256; i++ {
+		tx, err := s.transactor.Request(ctx, viewGroupRequest(c, 10))
+		c.Assert(err, IsNil)
+		c.Assert(seen[tx.key.tsn], Equals, false)
+		seen[tx.key.tsn] = true
+		txs = append(txs, tx)
+	}
+	_, err := s.transactor.Request(ctx, viewGroupRequest(c, 10))
+	c.Assert(err, Equals, frame.ErrNoFreeTransactionId)
+	c.Assert(s.transactor.Pending(), Equals, 256)
+
+	cancel()
+	for _, tx := range txs {
+		<-tx.Done()
+	}
+	c.Assert(allocator.InFlight("0x1234"), Equals, 0)
+}
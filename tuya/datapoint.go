@@ -0,0 +1,98 @@
+package tuya
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/dyrkin/composer"
+)
+
+type DatapointType uint8
+
+const (
+	DatapointTypeRaw    DatapointType = 0x00
+	DatapointTypeBool   DatapointType = 0x01
+	DatapointTypeValue  DatapointType = 0x02
+	DatapointTypeString DatapointType = 0x03
+	DatapointTypeEnum   DatapointType = 0x04
+	DatapointTypeBitmap DatapointType = 0x05
+)
+
+// Datapoint is a Tuya datapoint record. Its Value is []byte for raw datapoints, bool, int32 for
+// values, string, uint8 for enums and uint8, uint16 or uint32 for bitmaps depending on their
+// length. Datapoints of an unknown type or with an unexpected length keep their value as []byte.
+type Datapoint struct {
+	Id    uint8
+	Type  DatapointType
+	Value interface{}
+}
+
+// Serialize writes the datapoint id, type, length and value. Multi-byte fields are big endian.
+func (d *Datapoint) Serialize(w io.Writer) {
+	c := composer.NewWithW(w)
+	data := d.data()
+	c.Uint8(d.Id)
+	c.Uint8(uint8(d.Type))
+	c.Uint16be(uint16(len(data)))
+	c.Bytes(data)
+	c.Flush()
+}
+
+func (d *Datapoint) data() []byte {
+	switch v := d.Value.(type) {
+	case []byte:
+		return v
+	case bool:
+		if v {
+			return []byte{1}
+		}
+		return []byte{0}
+	case int32:
+		data := make([]byte, 4)
+		binary.BigEndian.PutUint32(data, uint32(v))
+		return data
+	case string:
+		return []byte(v)
+	case uint8:
+		return []byte{v}
+	case uint16:
+		data := make([]byte, 2)
+		binary.BigEndian.PutUint16(data, v)
+		return data
+	case uint32:
+		data := make([]byte, 4)
+		binary.BigEndian.PutUint32(data, v)
+		return data
+	}
+	return nil
+}
+
+func (d *Datapoint) Deserialize(r io.Reader) {
+	c := composer.NewWithR(r)
+	d.Id, _ = c.ReadUint8()
+	t, _ := c.ReadUint8()
+	d.Type = DatapointType(t)
+	length, _ := c.ReadUint16be()
+	data, _ := c.ReadBytes(int(length))
+	d.Value = datapointValue(d.Type, data)
+}
+
+func datapointValue(t DatapointType, data []byte) interface{} {
+	switch {
+	case t == DatapointTypeBool && len(data) == 1:
+		return data[0] != 0
+	case t == DatapointTypeValue && len(data) == 4:
+		return int32(binary.BigEndian.Uint32(data))
+	case t == DatapointTypeString:
+		return string(data)
+	case t == DatapointTypeEnum && len(data) == 1:
+		return data[0]
+	case t == DatapointTypeBitmap && len(data) == 1:
+		return data[0]
+	case t == DatapointTypeBitmap && len(data) == 2:
+		return binary.BigEndian.Uint16(data)
+	case t == DatapointTypeBitmap && len(data) == 4:
+		return binary.BigEndian.Uint32(data)
+	}
+	return data
+}
@@ -0,0 +1,77 @@
+package tuya
+
+import "github.com/dyrkin/zcl-go/cluster"
+
+// ManufacturerSpecific is the private cluster Tuya devices use to exchange datapoints with their
+// MCU.
+const ManufacturerSpecific cluster.ClusterId = 0xef00
+
+// DataRequestCommand sets datapoints of the device. The device answers with a DataResponse
+// carrying the same sequence number.
+type DataRequestCommand struct {
+	Sequence   uint16 `endianness:"be"`
+	Datapoints []*Datapoint
+}
+
+// DataQueryCommand asks the device to report all its datapoints.
+type DataQueryCommand struct{}
+
+type McuVersionRequestCommand struct {
+	Sequence uint16 `endianness:"be"`
+}
+
+// TimeSyncResponseCommand answers a TimeSyncRequest with seconds since the epoch. PayloadSize
+// is always 8.
+type TimeSyncResponseCommand struct {
+	PayloadSize uint16
+	UtcTime     uint32 `endianness:"be"`
+	LocalTime   uint32 `endianness:"be"`
+}
+
+type DataResponseCommand struct {
+	Sequence   uint16 `endianness:"be"`
+	Datapoints []*Datapoint
+}
+
+type DataReportCommand struct {
+	Sequence   uint16 `endianness:"be"`
+	Datapoints []*Datapoint
+}
+
+type ActiveStatusReportCommand struct {
+	Sequence   uint16 `endianness:"be"`
+	Datapoints []*Datapoint
+}
+
+type McuVersionResponseCommand struct {
+	Sequence uint16 `endianness:"be"`
+	Version  uint8
+}
+
+type TimeSyncRequestCommand struct {
+	Sequence uint16 `endianness:"be"`
+}
+
+// PatchLibrary adds the 0xEF00 cluster to the library so its frames decode to the commands of
+// this package.
+func PatchLibrary(library *cluster.ClusterLibrary) {
+	library.Clusters()[ManufacturerSpecific] = &cluster.Cluster{
+		Name:                 "TuyaManufacturerSpecific",
+		AttributeDescriptors: map[uint16]*cluster.AttributeDescriptor{},
+		CommandDescriptors: &cluster.CommandDescriptors{
+			Received: map[uint8]*cluster.CommandDescriptor{
+				0x00: {Name: "DataRequest", Command: &DataRequestCommand{}, Direction: cluster.ClientToServer, Requirement: cluster.Mandatory, Response: cluster.RespondsWith(0x01)},
+				0x03: {Name: "DataQuery", Command: &DataQueryCommand{}, Direction: cluster.ClientToServer, Requirement: cluster.Optional},
+				0x10: {Name: "McuVersionRequest", Command: &McuVersionRequestCommand{}, Direction: cluster.ClientToServer, Requirement: cluster.Optional, Response: cluster.RespondsWith(0x11)},
+				0x24: {Name: "TimeSyncResponse", Command: &TimeSyncResponseCommand{}, Direction: cluster.ClientToServer, Requirement: cluster.Optional},
+			},
+			Generated: map[uint8]*cluster.CommandDescriptor{
+				0x01: {Name: "DataResponse", Command: &DataResponseCommand{}, Direction: cluster.ServerToClient, Requirement: cluster.Mandatory},
+				0x02: {Name: "DataReport", Command: &DataReportCommand{}, Direction: cluster.ServerToClient, Requirement: cluster.Mandatory},
+				0x06: {Name: "ActiveStatusReport", Command: &ActiveStatusReportCommand{}, Direction: cluster.ServerToClient, Requirement: cluster.Optional},
+				0x11: {Name: "McuVersionResponse", Command: &McuVersionResponseCommand{}, Direction: cluster.ServerToClient, Requirement: cluster.Optional},
+				0x24: {Name: "TimeSyncRequest", Command: &TimeSyncRequestCommand{}, Direction: cluster.ServerToClient, Requirement: cluster.Optional, Response: cluster.RespondsWith(0x24)},
+			},
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package tuya
+
+import (
+	"testing"
+
+	"github.com/dyrkin/bin"
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	. "gopkg.in/check.v1"
+)
+
+func TestTuya(t *testing.T) { TestingT(t) }
+
+type TuyaSuite struct {
+	zcl *zcl.Zcl
+}
+
+var _ = Suite(&TuyaSuite{})
+
+func (s *TuyaSuite) SetUpTest(c *C) {
+	library := cluster.New()
+	PatchLibrary(library)
+	s.zcl = zcl.NewWithLibrary(library)
+}
+
+func (s *TuyaSuite) TestDecodeDataReport(c *C) {
+	m := &zcl.ApplicationMessage{ClusterID: uint16(ManufacturerSpecific), Data: []uint8{
+		0x09, 0x2a, 0x02, 0x00, 0x05,
+		0x02, 0x02, 0x00, 0x04, 0xff, 0xff, 0xff, 0x38,
+		0x04, 0x04, 0x00, 0x01, 0x01,
+		0x07, 0x01, 0x00, 0x01, 0x01,
+		0x10, 0x05, 0x00, 0x02, 0x01, 0x02,
+		0x11, 0x03, 0x00, 0x02, 'o', 'k',
+		0x12, 0x00, 0x00, 0x03, 0x01, 0x02, 0x03,
+		0x13, 0x02, 0x00, 0x02, 0xab, 0xcd,
+	}}
+	_, err := zcl.New().ToZclIncomingMessage(m)
+	c.Assert(err, NotNil)
+
+	im, err := s.zcl.ToZclIncomingMessage(m)
+	c.Assert(err, IsNil)
+	c.Assert(im.Data.CommandName, Equals, "DataReport")
+	c.Assert(im.Data.Command, DeepEquals, &DataReportCommand{
+		Sequence: 5,
+		Datapoints: []*Datapoint{
+			{Id: 0x02, Type: DatapointTypeValue, Value: int32(-200)},
+			{Id: 0x04, Type: DatapointTypeEnum, Value: uint8(1)},
+			{Id: 0x07, Type: DatapointTypeBool, Value: true},
+			{Id: 0x10, Type: DatapointTypeBitmap, Value: uint16(0x0102)},
+			{Id: 0x11, Type: DatapointTypeString, Value: "ok"},
+			{Id: 0x12, Type: DatapointTypeRaw, Value: []byte{0x01, 0x02, 0x03}},
+			// a value of unexpected length is kept raw
+			{Id: 0x13, Type: DatapointTypeValue, Value: []byte{0xab, 0xcd}},
+		},
+	})
+}
+
+func (s *TuyaSuite) TestEncodeDataRequest(c *C) {
+	request := &DataRequestCommand{Sequence: 0x0102, Datapoints: []*Datapoint{
+		{Id: 0x01, Type: DatapointTypeBool, Value: false},
+		{Id: 0x02, Type: DatapointTypeValue, Value: int32(215)},
+		{Id: 0x03, Type: DatapointTypeBitmap, Value: uint32(0x80)},
+	}}
+	f, err := frame.New().
+		IdGenerator(func() uint8 { return 0x10 }).
+		FrameType(frame.FrameTypeLocal).
+		Direction(frame.DirectionClientServer).
+		DisableDefaultResponse(true).
+		CommandId(0x00).
+		Command(request).
+		Build()
+	c.Assert(err, IsNil)
+	data := frame.Encode(f)
+	c.Assert(data, DeepEquals, []uint8{
+		0x11, 0x10, 0x00, 0x01, 0x02,
+		0x01, 0x01, 0x00, 0x01, 0x00,
+		0x02, 0x02, 0x00, 0x04, 0x00, 0x00, 0x00, 0xd7,
+		0x03, 0x05, 0x00, 0x04, 0x00, 0x00, 0x00, 0x80,
+	})
+
+	im, err := s.zcl.ToZclIncomingMessage(&zcl.ApplicationMessage{ClusterID: uint16(ManufacturerSpecific), Data: data})
+	c.Assert(err, IsNil)
+	c.Assert(im.Data.CommandName, Equals, "DataRequest")
+	c.Assert(im.Data.Command, DeepEquals, request)
+}
+
+func (s *TuyaSuite) TestTimeSyncAndMcuVersion(c *C) {
+	im, err := s.zcl.ToZclIncomingMessage(&zcl.ApplicationMessage{ClusterID: uint16(ManufacturerSpecific), Data: []uint8{0x09, 0x01, 0x24, 0x00, 0x07}})
+	c.Assert(err, IsNil)
+	c.Assert(im.Data.Command, DeepEquals, &TimeSyncRequestCommand{Sequence: 7})
+
+	response := bin.Encode(&TimeSyncResponseCommand{PayloadSize: 8, UtcTime: 0x5f5e1000, LocalTime: 0x5f5e2e10})
+	c.Assert(response, DeepEquals, []uint8{0x08, 0x00, 0x5f, 0x5e, 0x10, 0x00, 0x5f, 0x5e, 0x2e, 0x10})
+
+	im, err = s.zcl.ToZclIncomingMessage(&zcl.ApplicationMessage{ClusterID: uint16(ManufacturerSpecific), Data: []uint8{0x09, 0x02, 0x11, 0x00, 0x01, 0x40}})
+	c.Assert(err, IsNil)
+	c.Assert(im.Data.CommandName, Equals, "McuVersionResponse")
+	c.Assert(im.Data.Command, DeepEquals, &McuVersionResponseCommand{Sequence: 1, Version: 0x40})
+}
@@ -0,0 +1,101 @@
+package xiaomi
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+// ManufacturerCode is carried by the manufacturer specific frames of Xiaomi and Aqara devices.
+const ManufacturerCode uint16 = 0x115f
+
+const (
+	// AttributeTlv is a Basic attribute typed as a character string which holds a sequence of
+	// tagged ZCL values.
+	AttributeTlv uint16 = 0xff01
+	// AttributeStruct is a Basic attribute holding the same measurements as a ZCL struct.
+	AttributeStruct uint16 = 0xff02
+)
+
+// Value is a decoded element of a proprietary attribute. Tag is the tag of a tagged value or the
+// index of a struct element. Name is empty for tags of unknown meaning.
+type Value struct {
+	Tag       uint8              `json:"tag"`
+	Name      string             `json:"name,omitempty"`
+	Attribute *cluster.Attribute `json:"attribute"`
+}
+
+var tlvNames = map[uint8]string{
+	0x01: "BatteryVoltage",
+	0x03: "DeviceTemperature",
+	0x05: "Rssi",
+	0x0a: "ParentAddress",
+}
+
+var structNames = map[uint8]string{
+	0x01: "BatteryVoltage",
+}
+
+// PatchLibrary names the proprietary attributes in the Basic cluster of the library.
+func PatchLibrary(library *cluster.ClusterLibrary) {
+	attributes := library.Clusters()[cluster.Basic].AttributeDescriptors
+	attributes[AttributeTlv] = &cluster.AttributeDescriptor{Name: "XiaomiTlv", Type: cluster.ZclDataTypeCharStr, Access: cluster.Read | cluster.Reportable}
+	attributes[AttributeStruct] = &cluster.AttributeDescriptor{Name: "XiaomiStruct", Type: cluster.ZclDataTypeStruct, Access: cluster.Read | cluster.Reportable}
+}
+
+// Translate decodes the value of a proprietary Basic attribute. It doesn't see the frame, so the
+// caller checks that it carried ManufacturerCode, as the quirks package does. The attribute itself
+// is left as it was received, so it can still be encoded. It returns nil for other attributes and
+// for values which don't decode.
+func Translate(clusterId cluster.ClusterId, attributeId uint16, attribute *cluster.Attribute) []*Value {
+	if clusterId != cluster.Basic || attribute == nil {
+		return nil
+	}
+	switch v := attribute.Value.(type) {
+	case string:
+		if attributeId != AttributeTlv {
+			return nil
+		}
+		values, err := DecodeTlv(v)
+		if err != nil {
+			return nil
+		}
+		return values
+	case []*cluster.Attribute:
+		if attributeId != AttributeStruct {
+			return nil
+		}
+		return DecodeStruct(v)
+	}
+	return nil
+}
+
+// DecodeTlv decodes the tagged values of AttributeTlv. Every value is a tag followed by a data
+// type and a value encoded like an attribute.
+func DecodeTlv(data string) ([]*Value, error) {
+	r := bytes.NewReader([]byte(data))
+	values := []*Value{}
+	for r.Len() > 0 {
+		if r.Len() < 2 {
+			return nil, fmt.Errorf("truncated value at offset %d", int(r.Size())-r.Len())
+		}
+		tag, _ := r.ReadByte()
+		attribute := &cluster.Attribute{}
+		attribute.Deserialize(r)
+		if attribute.Value == nil && attribute.DataType != cluster.ZclDataTypeNoData {
+			return nil, fmt.Errorf("unsupported data type %d of tag %d", attribute.DataType, tag)
+		}
+		values = append(values, &Value{Tag: tag, Name: tlvNames[tag], Attribute: attribute})
+	}
+	return values, nil
+}
+
+// DecodeStruct names the elements of AttributeStruct.
+func DecodeStruct(elements []*cluster.Attribute) []*Value {
+	values := make([]*Value, len(elements))
+	for i, element := range elements {
+		values[i] = &Value{Tag: uint8(i), Name: structNames[uint8(i)], Attribute: element}
+	}
+	return values
+}
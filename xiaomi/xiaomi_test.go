@@ -0,0 +1,73 @@
+package xiaomi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	. "gopkg.in/check.v1"
+)
+
+func TestXiaomi(t *testing.T) { TestingT(t) }
+
+type XiaomiSuite struct{}
+
+var _ = Suite(&XiaomiSuite{})
+
+var weatherTlv = string([]byte{
+	0x01, 0x21, 0xd1, 0x0b,
+	0x05, 0x21, 0x08, 0x00,
+	0x06, 0x24, 0x01, 0x00, 0x00, 0x00, 0x00,
+	0x64, 0x29, 0xee, 0x08,
+	0x0a, 0x21, 0x00, 0x00,
+})
+
+func (s *XiaomiSuite) TestDecodeTlv(c *C) {
+	values, err := DecodeTlv(weatherTlv)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, []*Value{
+		{Tag: 0x01, Name: "BatteryVoltage", Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeUint16, Value: uint64(3025)}},
+		{Tag: 0x05, Name: "Rssi", Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeUint16, Value: uint64(8)}},
+		{Tag: 0x06, Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeUint40, Value: uint64(1)}},
+		{Tag: 0x64, Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeInt16, Value: int64(2286)}},
+		{Tag: 0x0a, Name: "ParentAddress", Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeUint16, Value: uint64(0)}},
+	})
+
+	_, err = DecodeTlv(weatherTlv + "\x01")
+	c.Assert(err, NotNil)
+	_, err = DecodeTlv("\x01\xff\x00")
+	c.Assert(err, NotNil)
+}
+
+func (s *XiaomiSuite) TestDecodeStruct(c *C) {
+	library := cluster.New()
+	PatchLibrary(library)
+	m := &zcl.ApplicationMessage{ClusterID: uint16(cluster.Basic), Data: []uint8{
+		0x1c, 0x5f, 0x11, 0x01, 0x0a,
+		0x02, 0xff, 0x4c, 0x03, 0x00, 0x10, 0x01, 0x21, 0xb3, 0x0b, 0x20, 0x50,
+	}}
+	im, err := zcl.NewWithLibrary(library).ToZclIncomingMessage(m)
+	c.Assert(err, IsNil)
+	report := im.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports[0]
+	c.Assert(report.AttributeName, Equals, "XiaomiStruct")
+
+	c.Assert(Translate(cluster.Basic, report.AttributeID, report.Attribute), DeepEquals, []*Value{
+		{Tag: 0, Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeBoolean, Value: true}},
+		{Tag: 1, Name: "BatteryVoltage", Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeUint16, Value: uint64(2995)}},
+		{Tag: 2, Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeUint8, Value: uint64(0x50)}},
+	})
+	c.Assert(report.Attribute.DataType, Equals, cluster.ZclDataTypeStruct)
+	c.Assert(Translate(cluster.OnOff, report.AttributeID, report.Attribute), IsNil)
+}
+
+func (s *XiaomiSuite) TestJson(c *C) {
+	values, err := DecodeTlv(weatherTlv)
+	c.Assert(err, IsNil)
+	b, err := json.Marshal(values[0])
+	c.Assert(err, IsNil)
+	c.Assert(string(b), Equals, `{"tag":1,"name":"BatteryVoltage","attribute":{"dataType":"Uint16","value":3025}}`)
+	decoded := &Value{}
+	c.Assert(json.Unmarshal(b, decoded), IsNil)
+	c.Assert(decoded, DeepEquals, values[0])
+}
@@ -6,7 +6,6 @@ import (
 	"github.com/dyrkin/zcl-go/cluster"
 	"github.com/dyrkin/zcl-go/frame"
 	"github.com/dyrkin/zcl-go/reflection"
-	"github.com/dyrkin/znp-go"
 )
 
 type CommandExtractor func(commandDescriptors map[uint8]*cluster.CommandDescriptor) (uint8, *cluster.CommandDescriptor, error)
@@ -31,40 +30,89 @@ type ZclFrame struct {
 	Command                   interface{}
 }
 
+type AddressMode uint8
+
+const (
+	AddressModeNwk       AddressMode = 0x00
+	AddressModeGroup     AddressMode = 0x01
+	AddressModeIeee      AddressMode = 0x02
+	AddressModeBroadcast AddressMode = 0x03
+)
+
+// ApplicationMessage is an APS data frame as delivered or accepted by a radio stack,
+// independent of the coordinator protocol it was carried by.
+type ApplicationMessage struct {
+	SrcAddrMode          AddressMode
+	SrcAddr              string
+	DstAddrMode          AddressMode
+	DstAddr              string
+	GroupID              uint16
+	SrcEndpoint          uint8
+	DstEndpoint          uint8
+	ClusterID            uint16
+	ProfileID            uint16
+	WasBroadcast         bool
+	LinkQuality          uint8
+	Rssi                 int8
+	SecurityUse          bool
+	Timestamp            uint32
+	TransactionSeqNumber uint8
+	Data                 []uint8
+}
+
 type ZclIncomingMessage struct {
 	GroupID              uint16
 	ClusterID            uint16
+	ProfileID            uint16
 	SrcAddr              string
+	DstAddr              string
 	SrcEndpoint          uint8
 	DstEndpoint          uint8
 	WasBroadcast         bool
 	LinkQuality          uint8
+	Rssi                 int8
 	SecurityUse          bool
 	Timestamp            uint32
 	TransactionSeqNumber uint8
 	Data                 *ZclFrame
+	// DecodedAttributes holds the values which a quirks.Decoder decoded from proprietary attributes
+	// of the command, keyed by attribute id. It isn't part of the JSON form.
+	DecodedAttributes map[uint16]interface{}
 }
 
 type Zcl struct {
 	library *cluster.ClusterLibrary
 }
 
+// Decoder turns application messages into ZCL messages, e.g. Zcl or quirks.Decoder.
+type Decoder interface {
+	ToZclIncomingMessage(m *ApplicationMessage) (*ZclIncomingMessage, error)
+}
+
 func New() *Zcl {
 	return &Zcl{cluster.New()}
 }
 
-func (z *Zcl) ToZclIncomingMessage(m *znp.AfIncomingMessage) (*ZclIncomingMessage, error) {
+// NewWithLibrary decodes frames using the given cluster library, e.g. one patched for a device.
+func NewWithLibrary(library *cluster.ClusterLibrary) *Zcl {
+	return &Zcl{library}
+}
+
+func (z *Zcl) ToZclIncomingMessage(m *ApplicationMessage) (*ZclIncomingMessage, error) {
 	im := &ZclIncomingMessage{}
 	im.GroupID = m.GroupID
 	im.ClusterID = m.ClusterID
+	im.ProfileID = m.ProfileID
 	im.SrcAddr = m.SrcAddr
+	im.DstAddr = m.DstAddr
 	im.SrcEndpoint = m.SrcEndpoint
 	im.DstEndpoint = m.DstEndpoint
-	im.WasBroadcast = m.WasBroadcast > 0
+	im.WasBroadcast = m.WasBroadcast
 	im.LinkQuality = m.LinkQuality
-	im.SecurityUse = m.SecurityUse > 0
+	im.Rssi = m.Rssi
+	im.SecurityUse = m.SecurityUse
 	im.Timestamp = m.Timestamp
-	im.TransactionSeqNumber = m.TransSeqNumber
+	im.TransactionSeqNumber = m.TransactionSeqNumber
 	data, err := z.toZclFrame(m.Data, m.ClusterID)
 	im.Data = data
 	return im, err
@@ -84,39 +132,48 @@ func (z *Zcl) toZclFrame(data []uint8, clusterId uint16) (*ZclFrame, error) {
 }
 
 func (z *Zcl) toZclCommand(clusterId uint16, f *frame.Frame) (interface{}, string, error) {
+	cd, err := z.commandDescriptor(clusterId, f.FrameControl.FrameType, f.FrameControl.Direction, f.CommandIdentifier)
+	if err != nil {
+		return nil, "", err
+	}
+	copy := reflection.Copy(cd.Command)
+	bin.Decode(f.Payload, copy)
+	if f.FrameControl.FrameType == frame.FrameTypeGlobal {
+		z.patchName(copy, clusterId, f.CommandIdentifier)
+	}
+	return copy, cd.Name, nil
+}
+
+func (z *Zcl) commandDescriptor(clusterId uint16, frameType frame.FrameType, direction frame.Direction, commandId uint8) (*cluster.CommandDescriptor, error) {
 	var cd *cluster.CommandDescriptor
 	var ok bool
-	switch f.FrameControl.FrameType {
+	switch frameType {
 	case frame.FrameTypeGlobal:
-		if cd, ok = z.library.Global()[f.CommandIdentifier]; !ok {
-			return nil, "", fmt.Errorf("unsupported global cmd identifier %d", f.CommandIdentifier)
+		if cd, ok = z.library.Global()[commandId]; !ok {
+			return nil, fmt.Errorf("unsupported global cmd identifier %d", commandId)
 		}
-		cmd := cd.Command
-		copy := reflection.Copy(cmd)
-		bin.Decode(f.Payload, copy)
-		z.patchName(copy, clusterId, f.CommandIdentifier)
-		return copy, cd.Name, nil
+		return cd, nil
 	case frame.FrameTypeLocal:
 		var c *cluster.Cluster
 		if c, ok = z.library.Clusters()[cluster.ClusterId(clusterId)]; !ok {
-			return nil, "", fmt.Errorf("unknown cluster %d", clusterId)
+			return nil, fmt.Errorf("unknown cluster %d", clusterId)
+		}
+		if c.CommandDescriptors == nil {
+			return nil, fmt.Errorf("cluster %d doesn't support this cmd %d", clusterId, commandId)
 		}
 		var commandDescriptors map[uint8]*cluster.CommandDescriptor
-		switch f.FrameControl.Direction {
+		switch direction {
 		case frame.DirectionClientServer:
 			commandDescriptors = c.CommandDescriptors.Received
 		case frame.DirectionServerClient:
 			commandDescriptors = c.CommandDescriptors.Generated
 		}
-		if cd, ok = commandDescriptors[f.CommandIdentifier]; !ok {
-			return nil, "", fmt.Errorf("cluster %d doesn't support this cmd %d", clusterId, f.CommandIdentifier)
+		if cd, ok = commandDescriptors[commandId]; !ok {
+			return nil, fmt.Errorf("cluster %d doesn't support this cmd %d", clusterId, commandId)
 		}
-		cmd := cd.Command
-		copy := reflection.Copy(cmd)
-		bin.Decode(f.Payload, copy)
-		return copy, cd.Name, nil
+		return cd, nil
 	}
-	return nil, "", fmt.Errorf("unknown frame type")
+	return nil, fmt.Errorf("unknown frame type")
 }
 
 func (z *Zcl) patchName(cmd interface{}, clusterId uint16, commandId uint8) {